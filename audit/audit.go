@@ -0,0 +1,296 @@
+// Package audit writes an append-only audit log of registration actions
+// with Crossref, DataCite and InvenioRDM. The log is a JSON Lines file.
+// Each entry contains the hash of the previous entry, so that changed or
+// removed entries are detected by Verify.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/front-matter/commonmeta/doiutils"
+)
+
+// Entry is an entry of the audit log.
+type Entry struct {
+	Time        time.Time `json:"time"`
+	User        string    `json:"user,omitempty"`
+	Profile     string    `json:"profile,omitempty"`
+	Command     string    `json:"command,omitempty"`
+	Action      string    `json:"action"`
+	Target      string    `json:"target"`
+	ID          string    `json:"id"`
+	PayloadHash string    `json:"payload_hash,omitempty"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	PrevHash    string    `json:"prev_hash"`
+	Hash        string    `json:"hash"`
+}
+
+// Log configures the audit log: the path of the file, and the user,
+// configuration profile and command recorded with each entry. An empty
+// path disables the audit log.
+type Log struct {
+	Path    string
+	User    string
+	Profile string
+	Command string
+}
+
+var (
+	mu      sync.Mutex
+	current Log
+	failed  int
+	failure error
+)
+
+// DefaultPath returns the default audit log: audit.jsonl in
+// $XDG_STATE_HOME/commonmeta, or ~/.local/state/commonmeta.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "commonmeta", "audit.jsonl")
+}
+
+// Configure sets the audit log used by Record.
+func Configure(l Log) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = l
+	failed = 0
+	failure = nil
+}
+
+// Err returns an error if entries could not be appended to the audit log
+// since it was configured, wrapping the first of these errors. Writers
+// don't return audit log errors, so that the outcome of a registration
+// action is reported separately from its audit log entry.
+func Err() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("audit log %s: %d entries not written: %w", current.Path, failed, failure)
+}
+
+// Record appends an entry to the audit log for an action, e.g. upsert or
+// delete, on a record with a target service. The payload sent to the
+// service is stored as hash. The status is failed if err is not nil and
+// no status is given. Record does nothing if the audit log is disabled.
+// The log is locked while the last entry is read and the entry appended,
+// so that processes writing to the same log keep the hash chain intact.
+// Errors are also kept for Err.
+func Record(action string, target string, id string, payload []byte, status string, err error) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if current.Path == "" {
+		return nil
+	}
+	err = record(action, target, id, payload, status, err)
+	if err != nil {
+		if failed == 0 {
+			failure = err
+		}
+		failed++
+	}
+	return err
+}
+
+// record appends an entry to the audit log, mu must be held.
+func record(action string, target string, id string, payload []byte, status string, err error) error {
+	entry := Entry{
+		Time:    time.Now().UTC(),
+		User:    current.User,
+		Profile: current.Profile,
+		Command: current.Command,
+		Action:  action,
+		Target:  target,
+		ID:      NormalizeID(id),
+		Status:  status,
+	}
+	if payload != nil {
+		entry.PayloadHash = hash(payload)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		if entry.Status == "" {
+			entry.Status = "failed"
+		}
+	}
+
+	err = os.MkdirAll(filepath.Dir(current.Path), 0o755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(current.Path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = lock(f)
+	if err != nil {
+		return fmt.Errorf("audit log %s: %w", current.Path, err)
+	}
+	defer unlock(f)
+
+	entry.PrevHash, err = lastHash(f)
+	if err != nil {
+		return fmt.Errorf("audit log %s: %w", current.Path, err)
+	}
+	entry.Hash, err = entryHash(entry)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// lastHash returns the hash of the last entry of an audit log, reading the
+// file backwards from the end, or an empty string for an empty log.
+func lastHash(f *os.File) (string, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := info.Size()
+	for window := int64(4096); ; window *= 2 {
+		start := max(size-window, 0)
+		buf := make([]byte, size-start)
+		_, err = f.ReadAt(buf, start)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		buf = bytes.TrimRight(buf, " \t\r\n")
+		i := bytes.LastIndexByte(buf, '\n')
+		if i < 0 && start > 0 {
+			// the last entry doesn't fit into the window
+			continue
+		}
+		line := buf[i+1:]
+		if len(line) == 0 {
+			return "", nil
+		}
+		var entry Entry
+		err = json.Unmarshal(line, &entry)
+		if err != nil {
+			return "", fmt.Errorf("last entry: %w", err)
+		}
+		return entry.Hash, nil
+	}
+}
+
+// Read reads all entries of an audit log.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadEntries(f)
+}
+
+// ReadEntries reads the entries of an audit log from a reader.
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry Entry
+		err := json.Unmarshal(line, &entry)
+		if err != nil {
+			return entries, fmt.Errorf("line %d: %w", n, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Verify checks the hash chain of the entries of an audit log, and returns
+// an error for the first entry that was changed, or follows an entry that
+// was removed.
+func Verify(entries []Entry) error {
+	var prev string
+	for i, entry := range entries {
+		if entry.PrevHash != prev {
+			return fmt.Errorf("entry %d: previous entry missing or changed", i+1)
+		}
+		h, err := entryHash(entry)
+		if err != nil {
+			return err
+		}
+		if h != entry.Hash {
+			return fmt.Errorf("entry %d: entry changed", i+1)
+		}
+		prev = entry.Hash
+	}
+	return nil
+}
+
+// Filter returns the entries for a DOI or InvenioRDM id between from and
+// until. An empty id and zero times match all entries.
+func Filter(entries []Entry, id string, from time.Time, until time.Time) []Entry {
+	id = NormalizeID(id)
+	var filtered []Entry
+	for _, entry := range entries {
+		if id != "" && entry.ID != id {
+			continue
+		}
+		if !from.IsZero() && entry.Time.Before(from) {
+			continue
+		}
+		if !until.IsZero() && !entry.Time.Before(until) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// NormalizeID returns DOIs as URL, and other ids unchanged.
+func NormalizeID(id string) string {
+	if doi, ok := doiutils.ValidateDOI(id); ok {
+		return doiutils.NormalizeDOI(doi)
+	}
+	return id
+}
+
+// entryHash returns the hash of an entry without its hash.
+func entryHash(entry Entry) (string, error) {
+	entry.Hash = ""
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	return hash(content), nil
+}
+
+// hash returns the hex encoded sha256 hash of content.
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,196 @@
+package audit_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/front-matter/commonmeta/audit"
+)
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit.Configure(audit.Log{Path: path, User: "test", Profile: "crossref-test", Command: "put"})
+	defer audit.Configure(audit.Log{})
+
+	err := audit.Record("upsert", "crossrefxml", "10.59350/abcd-1234", []byte("<doi_batch/>"), "submitted", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = audit.Record("delete", "datacite", "https://doi.org/10.83132/efgh-5678", nil, "", errors.New("403 Forbidden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// continue the hash chain of an existing log
+	audit.Configure(audit.Log{Path: path, User: "test"})
+	err = audit.Record("delete_draft", "inveniordm", "fh8y2-aef76", nil, "deleted", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := audit.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testCase struct {
+		id     string
+		status string
+		user   string
+	}
+	testCases := []testCase{
+		{id: "https://doi.org/10.59350/abcd-1234", status: "submitted", user: "test"},
+		{id: "https://doi.org/10.83132/efgh-5678", status: "failed", user: "test"},
+		{id: "fh8y2-aef76", status: "deleted", user: "test"},
+	}
+	if len(entries) != len(testCases) {
+		t.Fatalf("Read(%v): want %v entries, got %v", path, len(testCases), len(entries))
+	}
+	for i, tc := range testCases {
+		if tc.id != entries[i].ID || tc.status != entries[i].Status || tc.user != entries[i].User {
+			t.Errorf("Record(%v): want %v %v, got %v %v %v", tc.id, tc.status, tc.user, entries[i].ID, entries[i].Status, entries[i].User)
+		}
+	}
+	if entries[0].PayloadHash == "" || entries[1].PayloadHash != "" {
+		t.Errorf("Record: want payload hash only for payloads, got %q and %q", entries[0].PayloadHash, entries[1].PayloadHash)
+	}
+	err = audit.Verify(entries)
+	if err != nil {
+		t.Errorf("Verify(%v): want no error, got %v", path, err)
+	}
+
+	// tamper with the log
+	content, _ := os.ReadFile(path)
+	tampered := strings.Replace(string(content), `"status":"failed"`, `"status":"findable"`, 1)
+	entries, _ = audit.ReadEntries(strings.NewReader(tampered))
+	err = audit.Verify(entries)
+	if err == nil {
+		t.Errorf("Verify(tampered): want error, got nil")
+	}
+	lines := strings.SplitN(string(content), "\n", 2)
+	entries, _ = audit.ReadEntries(strings.NewReader(lines[1]))
+	err = audit.Verify(entries)
+	if err == nil {
+		t.Errorf("Verify(first entry removed): want error, got nil")
+	}
+}
+
+// TestRecordProcess appends to the audit log given by TestRecordSharedLog
+// from a separate process.
+func TestRecordProcess(t *testing.T) {
+	path := os.Getenv("COMMONMETA_TEST_AUDIT_LOG")
+	if path == "" {
+		t.Skip("run by TestRecordSharedLog")
+	}
+	audit.Configure(audit.Log{Path: path, User: "process"})
+	for range 20 {
+		err := audit.Record("upsert", "datacite", "10.83132/efgh-5678", nil, "findable", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRecordSharedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit.Configure(audit.Log{Path: path, User: "test"})
+	defer audit.Configure(audit.Log{})
+	err := audit.Record("upsert", "datacite", "10.83132/abcd-1234", nil, "findable", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cmds []*exec.Cmd
+	for range 2 {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestRecordProcess$")
+		cmd.Env = append(os.Environ(), "COMMONMETA_TEST_AUDIT_LOG="+path)
+		err = cmd.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	for range 20 {
+		err = audit.Record("delete", "datacite", "10.83132/abcd-1234", nil, "deleted", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, cmd := range cmds {
+		err = cmd.Wait()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := audit.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 61 {
+		t.Errorf("Record: want 61 entries, got %v", len(entries))
+	}
+	err = audit.Verify(entries)
+	if err != nil {
+		t.Errorf("Verify(%v): want no error, got %v", path, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 12, 0, 0, 0, time.UTC) }
+	entries := []audit.Entry{
+		{Time: day(1), ID: "https://doi.org/10.59350/abcd-1234"},
+		{Time: day(2), ID: "fh8y2-aef76"},
+		{Time: day(3), ID: "https://doi.org/10.59350/abcd-1234"},
+	}
+	type testCase struct {
+		id    string
+		from  time.Time
+		until time.Time
+		want  int
+	}
+	testCases := []testCase{
+		{want: 3},
+		{id: "10.59350/ABCD-1234", want: 2},
+		{id: "fh8y2-aef76", want: 1},
+		{from: day(2), want: 2},
+		{until: day(2), want: 1},
+		{id: "10.59350/abcd-1234", from: day(2), until: day(4), want: 1},
+	}
+	for _, tc := range testCases {
+		got := audit.Filter(entries, tc.id, tc.from, tc.until)
+		if tc.want != len(got) {
+			t.Errorf("Filter(%v, %v, %v): want %v, got %v", tc.id, tc.from, tc.until, tc.want, len(got))
+		}
+	}
+}
+
+func TestErr(t *testing.T) {
+	// a directory can't be opened as audit log
+	dir := t.TempDir()
+	audit.Configure(audit.Log{Path: dir})
+	defer audit.Configure(audit.Log{})
+
+	for range 2 {
+		if audit.Record("upsert", "datacite", "10.83132/abcd-1234", nil, "findable", nil) == nil {
+			t.Fatal("Record: want an error, got nil")
+		}
+	}
+	err := audit.Err()
+	if err == nil || !strings.Contains(err.Error(), "2 entries not written") {
+		t.Errorf("Err: want 2 entries not written, got %v", err)
+	}
+
+	audit.Configure(audit.Log{Path: filepath.Join(dir, "audit.jsonl")})
+	err = audit.Record("upsert", "datacite", "10.83132/abcd-1234", nil, "findable", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = audit.Err(); err != nil {
+		t.Errorf("Err: want nil, got %v", err)
+	}
+}
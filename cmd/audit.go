@@ -0,0 +1,101 @@
+/*
+Copyright © 2025 Front Matter <info@front-matter.io>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/front-matter/commonmeta/audit"
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/spf13/cobra"
+)
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Query the audit log of registration actions",
+	Long: `Query the audit log of registration actions by put, push, delete
+and setup. Filter by DOI or InvenioRDM id, and by date range with --since
+and --until (a date or RFC 3339 timestamp, until is exclusive). Use --verify
+to check that no entries were changed or removed. Example usage:
+
+commonmeta audit 10.59350/abcd-1234
+commonmeta audit --since 2025-01-01 --until 2025-02-01
+commonmeta audit --verify`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		var id string
+		if len(args) > 0 {
+			id = args[0]
+		}
+		path, _ := cmd.Flags().GetString("audit-log")
+		since, _ := cmd.Flags().GetString("since")
+		until, _ := cmd.Flags().GetString("until")
+		verify, _ := cmd.Flags().GetBool("verify")
+
+		cmd.SetOut(os.Stdout)
+		cmd.SetErr(os.Stderr)
+
+		if path == "" {
+			exitWithError(cmd, usageError("Please provide an audit log"))
+			return
+		}
+		from, err := parseTime(since)
+		if err != nil {
+			exitWithError(cmd, usageError("Please provide a valid date for --since"))
+			return
+		}
+		to, err := parseTime(until)
+		if err != nil {
+			exitWithError(cmd, usageError("Please provide a valid date for --until"))
+			return
+		}
+
+		entries, err := audit.Read(path)
+		if os.IsNotExist(err) {
+			exitWithError(cmd, fmt.Errorf("%w: audit log %s", commonmeta.ErrNotFound, path))
+			return
+		} else if err != nil {
+			exitWithError(cmd, err)
+			return
+		}
+		if verify {
+			err = audit.Verify(entries)
+			if err != nil {
+				err = fmt.Errorf("%w: audit log %s: %w", commonmeta.ErrValidation, path, err)
+			}
+		}
+
+		entries = audit.Filter(entries, id, from, to)
+		if entries == nil {
+			entries = []audit.Entry{}
+		}
+		output, _ := json.Marshal(entries)
+		printOutput(cmd, output, err)
+	},
+}
+
+// parseTime parses a date or RFC 3339 timestamp. An empty string is the
+// zero time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, s)
+	}
+	return t, err
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringP("since", "", "", "show entries from this date or timestamp")
+	auditCmd.Flags().StringP("until", "", "", "show entries before this date or timestamp")
+	auditCmd.Flags().BoolP("verify", "", false, "verify that no entries were changed or removed")
+}
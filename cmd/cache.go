@@ -0,0 +1,56 @@
+/*
+Copyright © 2025 Front Matter <info@front-matter.io>
+*/
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/front-matter/commonmeta/httputils"
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Show statistics for or clear the HTTP cache.",
+	Long: `Show statistics for or clear the on-disk HTTP cache used for metadata
+lookups when the --cache flag is set. Example usage:
+
+	commonmeta cache stats
+	commonmeta cache clear --cache-dir /tmp/commonmeta`,
+	ValidArgs: []string{"stats", "clear"},
+	Run: func(cmd *cobra.Command, args []string) {
+		cacheDir, _ := cmd.Flags().GetString("cache-dir")
+
+		if len(args) == 0 {
+			exitWithError(cmd, usageError("Please provide an action: stats or clear"))
+			return
+		}
+		c := httputils.NewCache(nil, cacheDir, 0)
+
+		switch args[0] {
+		case "stats":
+			stats, err := c.Stats()
+			if err != nil {
+				exitWithError(cmd, err)
+				return
+			}
+			output, err := json.Marshal(stats)
+			printOutput(cmd, output, err)
+		case "clear":
+			n, err := c.Clear()
+			if err != nil {
+				exitWithError(cmd, err)
+				return
+			}
+			cmd.Printf("Removed %d cached responses from %s\n", n, c.Dir)
+		default:
+			exitWithError(cmd, usageError("Please provide a valid action: stats or clear"))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -4,8 +4,6 @@ Copyright © 2024-2025 Front Matter <info@front-matter.io>
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"os"
 	"slices"
@@ -56,7 +54,7 @@ commonmeta 10.5555/12345678`,
 		cmd.SetErr(os.Stderr)
 
 		if len(args) == 0 {
-			fmt.Println("Please provide an input")
+			exitWithError(cmd, usageError("Please provide an input"))
 			return
 		}
 
@@ -82,7 +80,7 @@ commonmeta 10.5555/12345678`,
 		if id == "" {
 			_, err = os.Stat(input)
 			if err != nil {
-				fmt.Printf("File not found: %s", input)
+				exitWithError(cmd, fmt.Errorf("%w: file %s", commonmeta.ErrNotFound, input))
 				return
 			}
 			str = input
@@ -99,7 +97,7 @@ commonmeta 10.5555/12345678`,
 			} else if from == "datacite" {
 				data, err = datacite.Fetch(id, match)
 			} else if from == "inveniordm" {
-				rl := rate.NewLimiter(rate.Every(10*time.Second), 100)
+				rl := rate.NewLimiter(rate.Every(100*time.Millisecond), 100)
 				client := inveniordm.NewClient(rl, fromHost)
 				data, err = inveniordm.Fetch(id, match, client)
 			} else if from == "jsonfeed" {
@@ -112,15 +110,15 @@ commonmeta 10.5555/12345678`,
 			} else if slices.Contains(commonmeta.OrganizationTypes, identifierType) && from == "ror" {
 				orgdata, err = ror.Search(id)
 				if orgdata.ID == "" {
-					cmd.Println("No match found")
+					exitWithError(cmd, fmt.Errorf("%w: no match found for %s", commonmeta.ErrNotFound, id))
 					return
 				}
 			} else {
-				fmt.Println("Please provide a valid input")
+				exitWithError(cmd, usageError("Please provide a valid input"))
 				return
 			}
 			if err != nil {
-				fmt.Println("An error occurred:", err)
+				exitWithError(cmd, err)
 				return
 			}
 		} else if str != "" {
@@ -142,11 +140,11 @@ commonmeta 10.5555/12345678`,
 			} else if from == "schemaorg" {
 				data, err = schemaorg.Load(str, match)
 			} else {
-				cmd.PrintErr("Please provide a valid input")
+				exitWithError(cmd, usageError("Please provide a valid input"))
 				return
 			}
 			if err != nil {
-				fmt.Println("An error occurred:", err)
+				exitWithError(cmd, err)
 				return
 			}
 		}
@@ -174,21 +172,7 @@ commonmeta 10.5555/12345678`,
 			output, err = ror.Write(orgdata)
 		}
 
-		if err != nil {
-			cmd.PrintErr(err)
-		}
-
-		if to == "crossrefxml" || to == "inveniordm" {
-			cmd.Printf("%s\n", output)
-		} else {
-			var out bytes.Buffer
-			json.Indent(&out, output, "", "  ")
-			cmd.Println(out.String())
-		}
-
-		if err != nil {
-			cmd.PrintErr(err)
-		}
+		printOutput(cmd, output, err)
 	},
 }
 
@@ -4,8 +4,6 @@ Copyright © 2024-2025 Front Matter <info@front-matter.io>
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/front-matter/commonmeta/utils"
 	"github.com/spf13/cobra"
 )
@@ -23,12 +21,12 @@ var decodeCmd = &cobra.Command{
 	commonmeta decode 10.54900/d3ck1-skq19`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
-			fmt.Println("Please provide an input")
+			exitWithError(cmd, usageError("Please provide an input"))
 			return
 		}
 		number, err := utils.DecodeID(args[0])
 		if err != nil {
-			cmd.Println(err)
+			exitWithError(cmd, err)
 			return
 		}
 		cmd.Println(number)
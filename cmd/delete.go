@@ -4,71 +4,202 @@ Copyright © 2025 Front Matter <info@front-matter.io>
 package cmd
 
 import (
-	"bytes"
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/crossrefxml"
+	"github.com/front-matter/commonmeta/datacite"
+	"github.com/front-matter/commonmeta/doiutils"
 	"github.com/front-matter/commonmeta/inveniordm"
 	"github.com/front-matter/commonmeta/utils"
 	"github.com/spf13/cobra"
 	"golang.org/x/time/rate"
 )
 
-// deleteCmd represents the decode command
+// deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
-	Short: "Deletes a InvenioRDM record.",
-	Long: `Deletes a InvenioRDM record by id.
-	
+	Short: "Deletes InvenioRDM records, DataCite draft DOIs or withdraws Crossref DOIs.",
+	Long: `Deletes InvenioRDM records, DataCite draft DOIs or withdraws Crossref DOIs.
+Published InvenioRDM records are replaced by a tombstone with the --reason.
+Use --draft to delete the draft of an InvenioRDM record instead: an
+unpublished record, or the unpublished edits of a published record. Only DataCite draft DOIs can be deleted.
+Crossref DOIs can't be deleted, their registered metadata is deposited
+again with the title prefixed with [Withdrawn] and the --reason as
+abstract, all other metadata are kept. Use --file to read a list of ids, one per line.
+
 	Example usage:
-	
-	commonmeta delete fh8y2-aef76`,
+
+	commonmeta delete fh8y2-aef76
+	commonmeta delete fh8y2-aef76 --reason spam --note "Duplicate upload"
+	commonmeta delete fh8y2-aef76 --draft
+	commonmeta delete 10.83132/abcd-1234 -t datacite
+	commonmeta delete 10.59350/abcd-1234 -t crossrefxml --reason "registered in error"
+	commonmeta delete --file ids.txt -t datacite --yes`,
 	Run: func(cmd *cobra.Command, args []string) {
+		to, _ := cmd.Flags().GetString("to")
+		file, _ := cmd.Flags().GetString("file")
+		reason, _ := cmd.Flags().GetString("reason")
+		note, _ := cmd.Flags().GetString("note")
+		draft, _ := cmd.Flags().GetBool("draft")
+		yes, _ := cmd.Flags().GetBool("yes")
 		host, _ := cmd.Flags().GetString("host")
 		token, _ := cmd.Flags().GetString("token")
+		client_, _ := cmd.Flags().GetString("client")
+		password, _ := cmd.Flags().GetString("password")
+		depositor, _ := cmd.Flags().GetString("depositor")
+		email, _ := cmd.Flags().GetString("email")
+		registrant, _ := cmd.Flags().GetString("registrant")
+		loginID, _ := cmd.Flags().GetString("login_id")
+		loginPasswd, _ := cmd.Flags().GetString("login_passwd")
+		development, _ := cmd.Flags().GetBool("development")
 
-		var record commonmeta.APIResponse
+		cmd.SetOut(os.Stdout)
+		cmd.SetErr(os.Stderr)
 
-		if len(args) == 0 {
-			fmt.Println("Please provide an input")
-			return
+		// InvenioRDM is the default, as the to flag defaults to commonmeta
+		if to == "" || to == "commonmeta" {
+			to = "inveniordm"
 		}
-		input := args[0]
-		rid, ok := utils.ValidateRID(input)
-		if !ok {
-			fmt.Println("Please provide a valid input")
-			return
+
+		var inputs []string
+		if file != "" {
+			f, err := os.Open(file)
+			if err != nil {
+				exitWithError(cmd, fmt.Errorf("%w: file %s", commonmeta.ErrNotFound, file))
+				return
+			}
+			inputs, err = readIDs(f)
+			f.Close()
+			if err != nil {
+				exitWithError(cmd, err)
+				return
+			}
 		}
-		if host == "" || token == "" {
-			fmt.Println("Please provide an inveniordm host and token")
+		inputs = append(inputs, args...)
+		if len(inputs) == 0 {
+			exitWithError(cmd, usageError("Please provide an input"))
 			return
 		}
-		rl := rate.NewLimiter(rate.Every(10*time.Second), 100)
-		client := inveniordm.NewClient(rl, host)
-		record.ID = rid
-		record, err := inveniordm.DeleteDraftRecord(record, token, client)
-		if err != nil {
-			cmd.PrintErr(err)
+
+		var ids []string
+		for _, input := range inputs {
+			var id string
+			var ok bool
+			switch to {
+			case "inveniordm":
+				id, ok = utils.ValidateRID(input)
+			case "datacite", "crossrefxml":
+				id, ok = doiutils.ValidateDOI(input)
+			default:
+				exitWithError(cmd, usageError("Please provide a valid service"))
+				return
+			}
+			if !ok {
+				exitWithError(cmd, usageError("Please provide a valid input: "+input))
+				return
+			}
+			ids = append(ids, id)
 		}
 
-		var output []byte
-		var out bytes.Buffer
-		output, err = json.Marshal(record)
-		if err != nil {
-			cmd.PrintErr(err)
+		if !yes && !confirm(cmd, fmt.Sprintf("Delete %d record(s) from %s?", len(ids), to)) {
+			exitWithError(cmd, errors.New("deletion aborted"))
+			return
+		}
+
+		var deleteRecord func(id string) (commonmeta.APIResponse, error)
+		switch to {
+		case "inveniordm":
+			if host == "" || token == "" {
+				exitWithError(cmd, usageError("Please provide an inveniordm host and token"))
+				return
+			}
+			rl := rate.NewLimiter(rate.Every(100*time.Millisecond), 100)
+			client := inveniordm.NewClient(rl, host)
+			deleteRecord = func(id string) (commonmeta.APIResponse, error) {
+				return inveniordm.Delete(commonmeta.APIResponse{ID: id}, reason, note, draft, token, client)
+			}
+		case "datacite":
+			account := datacite.Account{
+				Client:      client_,
+				Password:    password,
+				Development: development,
+			}
+			deleteRecord = func(id string) (commonmeta.APIResponse, error) {
+				return datacite.Delete(commonmeta.APIResponse{DOI: doiutils.NormalizeDOI(id)}, account)
+			}
+		case "crossrefxml":
+			account := crossrefxml.Account{
+				Depositor:   depositor,
+				Email:       email,
+				Registrant:  registrant,
+				LoginID:     loginID,
+				LoginPasswd: loginPasswd,
+				Development: development,
+			}
+			deleteRecord = func(id string) (commonmeta.APIResponse, error) {
+				return crossrefxml.Withdraw(commonmeta.APIResponse{DOI: doiutils.NormalizeDOI(id)}, account, reason)
+			}
 		}
 
-		json.Indent(&out, output, "", "  ")
-		cmd.Println(out.String())
+		var records []commonmeta.APIResponse
+		var errs []error
+		for _, id := range ids {
+			record, err := deleteRecord(id)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", id, err))
+				if record.Message == "" {
+					record.Message = err.Error()
+				}
+			}
+			records = append(records, record)
+		}
 
-		if err != nil {
-			cmd.PrintErr(err)
+		var output []byte
+		if len(records) == 1 {
+			output, _ = json.Marshal(records[0])
+		} else {
+			output, _ = json.Marshal(records)
 		}
+		printOutput(cmd, output, errors.Join(errs...))
 	},
 }
 
+// readIDs reads a list of ids, one per line. Empty lines and lines
+// starting with # are skipped.
+func readIDs(r io.Reader) ([]string, error) {
+	var ids []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids = append(ids, line)
+	}
+	return ids, scanner.Err()
+}
+
+// confirm asks the user to confirm an action on stdin.
+func confirm(cmd *cobra.Command, question string) bool {
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
 func init() {
 	rootCmd.AddCommand(deleteCmd)
+
+	deleteCmd.Flags().StringP("reason", "", "", "reason for deleting a published record, e.g. spam, misconduct, retracted or other")
+	deleteCmd.Flags().StringP("note", "", "", "note shown on the tombstone page of a deleted InvenioRDM record")
+	deleteCmd.Flags().BoolP("draft", "", false, "delete the draft of an InvenioRDM record instead of the published record")
+	deleteCmd.Flags().BoolP("yes", "y", false, "delete without asking for confirmation")
 }
@@ -0,0 +1,272 @@
+/*
+Copyright © 2025 Front Matter <info@front-matter.io>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/crossref"
+	"github.com/front-matter/commonmeta/crossrefxml"
+	"github.com/front-matter/commonmeta/datacite"
+	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/inveniordm"
+	"github.com/front-matter/commonmeta/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+// Preview is the result of a dry run for a record: the payload that would
+// be sent to the service, the files that would be uploaded, and the
+// changes compared to the metadata currently registered. Status is new,
+// new_version, changed, unchanged, skipped, invalid, or failed if the
+// registered metadata couldn't be fetched.
+type Preview struct {
+	ID      string                 `json:"id"`
+	Target  string                 `json:"target"`
+	Status  string                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Payload any                    `json:"payload,omitempty"`
+	Files   []inveniordm.FileEntry `json:"files,omitempty"`
+	Changes []utils.Change         `json:"changes,omitempty"`
+}
+
+// dryRun builds and validates the payloads for a list of records without
+// registering them, and compares them with the metadata currently
+// registered with the service. Only read requests are sent. Crossref
+// resource-only and reference-only deposits are compared by their
+// resources or references, files to upload to InvenioRDM are read and
+// verified, and a new InvenioRDM version is compared with the previous
+// version.
+func dryRun(cmd *cobra.Command, to string, list []commonmeta.Data) ([]Preview, error) {
+	depositor, _ := cmd.Flags().GetString("depositor")
+	email, _ := cmd.Flags().GetString("email")
+	registrant, _ := cmd.Flags().GetString("registrant")
+	host, _ := cmd.Flags().GetString("host")
+	fromHost, _ := cmd.Flags().GetString("from-host")
+	event, _ := cmd.Flags().GetString("event")
+	depositType, _ := cmd.Flags().GetString("deposit-type")
+	files, _ := cmd.Flags().GetBool("files")
+	newVersion, _ := cmd.Flags().GetBool("new-version")
+
+	var preview func(data commonmeta.Data) (Preview, error)
+	switch to {
+	case "crossrefxml":
+		account := crossrefxml.Account{
+			Depositor:  depositor,
+			Email:      email,
+			Registrant: registrant,
+		}
+		preview = func(data commonmeta.Data) (Preview, error) {
+			p := Preview{ID: data.ID, Target: to}
+			ra, ok := doiutils.GetDOIRA(data.ID)
+			if !ok {
+				return p, fmt.Errorf("%w: DOI is not a valid DOI", commonmeta.ErrValidation)
+			} else if ra != "Crossref" {
+				p.Status = "skipped"
+				return p, nil
+			}
+			fetch := func() (commonmeta.Data, error) {
+				return crossref.Fetch(data.ID, false)
+			}
+			if depositType != "" && depositType != "metadata" {
+				_, err := crossrefxml.ConvertResources(data, depositType)
+				if err != nil {
+					return p, err
+				}
+				var payload []byte
+				if depositType == "resources" {
+					payload, err = crossrefxml.WriteResources([]commonmeta.Data{data}, account)
+				} else {
+					payload, err = crossrefxml.WriteReferences([]commonmeta.Data{data}, account)
+				}
+				if err != nil {
+					return p, err
+				}
+				p.Payload = string(payload)
+				return p, previewChanges(&p, data, fetch, func(data commonmeta.Data) (any, error) {
+					body, err := crossrefxml.ConvertResources(data, depositType)
+					if errors.Is(err, commonmeta.ErrValidation) {
+						// the registered metadata have no resources or references
+						return nil, nil
+					}
+					return body, err
+				})
+			}
+			payload, err := crossrefxml.Write(data, account)
+			if err != nil {
+				return p, fmt.Errorf("%w: %w", commonmeta.ErrValidation, err)
+			}
+			p.Payload = string(payload)
+			return p, previewChanges(&p, data, fetch, func(data commonmeta.Data) (any, error) {
+				return crossrefxml.Convert(data)
+			})
+		}
+	case "datacite":
+		preview = func(data commonmeta.Data) (Preview, error) {
+			p := Preview{ID: data.ID, Target: to}
+			payload, err := datacite.WriteWithEvent(data, event)
+			if err != nil {
+				return p, fmt.Errorf("%w: %w", commonmeta.ErrValidation, err)
+			}
+			p.Payload = json.RawMessage(payload)
+			return p, previewChanges(&p, data, func() (commonmeta.Data, error) {
+				return datacite.Fetch(data.ID, false)
+			}, func(data commonmeta.Data) (any, error) {
+				return datacite.Convert(data)
+			})
+		}
+	case "inveniordm":
+		if host == "" {
+			return nil, usageError("Please provide an inveniordm host")
+		}
+		rl := rate.NewLimiter(rate.Every(100*time.Millisecond), 100)
+		client := inveniordm.NewClient(rl, host)
+		preview = func(data commonmeta.Data) (Preview, error) {
+			p := Preview{ID: data.ID, Target: to}
+			doi, ok := doiutils.ValidateDOI(data.ID)
+			if !ok {
+				return p, fmt.Errorf("%w: missing or invalid DOI", commonmeta.ErrValidation)
+			}
+			if !inveniordm.GetMapping().Registration.Accepts(host, data.ID) {
+				p.Status = "skipped"
+				return p, nil
+			}
+			payload, err := inveniordm.Write(data, fromHost)
+			if err != nil {
+				return p, fmt.Errorf("%w: %w", commonmeta.ErrValidation, err)
+			}
+			p.Payload = json.RawMessage(payload)
+			if files {
+				for _, file := range data.Files {
+					checksum, size, err := inveniordm.FileChecksum(file)
+					if err != nil {
+						return p, fmt.Errorf("file %s: %w", inveniordm.FileKey(file), err)
+					}
+					p.Files = append(p.Files, inveniordm.FileEntry{Key: inveniordm.FileKey(file), Checksum: checksum, Size: int(size)})
+				}
+			}
+			fetch := func(doi string) (commonmeta.Data, error) {
+				id, err := inveniordm.SearchByDOI(doi, client)
+				if err != nil {
+					return commonmeta.Data{}, err
+				} else if id == "" {
+					return commonmeta.Data{}, commonmeta.ErrNotFound
+				}
+				content, err := inveniordm.Get(id, client)
+				if err != nil {
+					return commonmeta.Data{}, err
+				}
+				return inveniordm.Read(content, false)
+			}
+			convert := func(data commonmeta.Data) (any, error) {
+				return inveniordm.Convert(data, fromHost)
+			}
+			if newVersion {
+				// push fails if the record exists, and compares the new
+				// version with the previous version
+				_, err := fetch(doi)
+				if err == nil {
+					return p, fmt.Errorf("%w: can't create a new version, %s already exists", commonmeta.ErrValidation, doi)
+				} else if !errors.Is(err, commonmeta.ErrNotFound) {
+					return p, err
+				}
+				previousDOI := inveniordm.PreviousVersionDOI(data)
+				if previousDOI == "" {
+					return p, fmt.Errorf("%w: a new version requires an IsNewVersionOf or IsVersionOf relation", commonmeta.ErrValidation)
+				}
+				previous, err := fetch(previousDOI)
+				if err != nil {
+					return p, fmt.Errorf("previous version %s: %w", previousDOI, err)
+				}
+				err = previewChanges(&p, data, func() (commonmeta.Data, error) {
+					return previous, nil
+				}, convert)
+				if err == nil {
+					p.Status = "new_version"
+				}
+				return p, err
+			}
+			return p, previewChanges(&p, data, func() (commonmeta.Data, error) {
+				return fetch(doi)
+			}, convert)
+		}
+	default:
+		return nil, usageError("Please provide a valid service")
+	}
+
+	var previews []Preview
+	var errs []error
+	for _, data := range list {
+		p, err := preview(data)
+		if err != nil {
+			p.Status = "failed"
+			if errors.Is(err, commonmeta.ErrValidation) {
+				p.Status = "invalid"
+			}
+			p.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", data.ID, err))
+		}
+		previews = append(previews, p)
+	}
+	return previews, errors.Join(errs...)
+}
+
+// previewChanges fetches the metadata currently registered for a record
+// and sets the status and field-level changes of the preview. Both the
+// registered and the new metadata are converted to the format of the
+// service, so that only changes the service would see are reported.
+func previewChanges(p *Preview, data commonmeta.Data, fetch func() (commonmeta.Data, error), convert func(commonmeta.Data) (any, error)) error {
+	var old []byte
+	registered, err := fetch()
+	if err != nil && !errors.Is(err, commonmeta.ErrNotFound) {
+		return err
+	}
+	if err == nil {
+		v, err := convert(registered)
+		if err != nil {
+			return err
+		}
+		old, err = json.Marshal(v)
+		if err != nil {
+			return err
+		}
+	}
+	v, err := convert(data)
+	if err != nil {
+		return err
+	}
+	updated, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	p.Changes, err = utils.DiffJSON(old, updated)
+	if err != nil {
+		return err
+	}
+	switch {
+	case old == nil:
+		p.Status = "new"
+	case len(p.Changes) > 0:
+		p.Status = "changed"
+	default:
+		p.Status = "unchanged"
+	}
+	return nil
+}
+
+// printPreviews prints the previews of a dry run and exits with the exit
+// code for err.
+func printPreviews(cmd *cobra.Command, previews []Preview, err error) {
+	var output []byte
+	if len(previews) == 1 {
+		output, _ = json.Marshal(previews[0])
+	} else {
+		output, _ = json.Marshal(previews)
+	}
+	printOutput(cmd, output, err)
+}
@@ -4,8 +4,6 @@ Copyright © 2024-2025 Front Matter <info@front-matter.io>
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/front-matter/commonmeta/doiutils"
 	"github.com/spf13/cobra"
 )
@@ -19,13 +17,13 @@ var encodeCmd = &cobra.Command{
 	commonmeta encode 10.5555`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
-			fmt.Println("Please provide an input")
+			exitWithError(cmd, usageError("Please provide an input"))
 			return
 		}
 		input := args[0]
 		prefix, ok := doiutils.ValidatePrefix(input)
 		if !ok {
-			cmd.PrintErr("Invalid prefix")
+			exitWithError(cmd, usageError("Invalid prefix"))
 			return
 		}
 		doi := doiutils.EncodeDOI(prefix)
@@ -0,0 +1,231 @@
+/*
+Copyright © 2025 Front Matter <info@front-matter.io>
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/crossref"
+	"github.com/front-matter/commonmeta/crossrefxml"
+	"github.com/front-matter/commonmeta/csl"
+	"github.com/front-matter/commonmeta/datacite"
+	"github.com/front-matter/commonmeta/enrich"
+	"github.com/front-matter/commonmeta/fileutils"
+	"github.com/front-matter/commonmeta/inveniordm"
+	"github.com/front-matter/commonmeta/jsonfeed"
+	"github.com/front-matter/commonmeta/schemaorg"
+	"github.com/spf13/cobra"
+)
+
+// enrichCmd represents the enrich command
+var enrichCmd = &cobra.Command{
+	Use:   "enrich",
+	Short: "Fill missing metadata from other sources",
+	Long: `Fill missing abstracts, licenses, references, funding, ORCID
+and ROR IDs of a record or list of records from the metadata registered
+for the same DOI with Crossref, DataCite or OpenAlex. Affiliations without
+ROR ID are matched via ROR, and licenses are normalized to SPDX IDs.
+Input is a file, or a URL for schemaorg and jsonfeed. Example usage:
+
+commonmeta enrich posts.json -f jsonfeed --file enriched.json
+commonmeta enrich https://blog.front-matter.io/posts/editorial -f schemaorg
+commonmeta enrich records.jsonl --rules enrich.yaml --provenance provenance.json
+
+The rules file lists the sources of every field in order of preference,
+fields without sources are not enriched:
+
+abstract: [crossref, datacite, openalex]
+license: [crossref, spdx]
+references: []
+ror: [ror]
+
+The fields added and their source are written to the --provenance file,
+or printed to stderr. If a source fails, the records are still written,
+and the command exits with the exit code of the error.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		var data []commonmeta.Data
+		var err error
+		var output []byte
+
+		from, _ := cmd.Flags().GetString("from")
+		file, _ := cmd.Flags().GetString("file")
+		match, _ := cmd.Flags().GetBool("match")
+		email, _ := cmd.Flags().GetString("email")
+		rulesFile, _ := cmd.Flags().GetString("rules")
+		provenance, _ := cmd.Flags().GetString("provenance")
+
+		cmd.SetOut(os.Stdout)
+		cmd.SetErr(os.Stderr)
+
+		if len(args) == 0 {
+			exitWithError(cmd, usageError("Please provide an input"))
+			return
+		}
+		input := args[0]
+		if from == "" {
+			from = "commonmeta"
+		}
+
+		rules := enrich.DefaultRules()
+		if rulesFile != "" {
+			rules, err = enrich.LoadRules(rulesFile)
+			if err != nil {
+				exitWithError(cmd, fmt.Errorf("%w: rules file %s: %w", commonmeta.ErrValidation, rulesFile, err))
+				return
+			}
+		}
+
+		var list bool
+		if _, err = os.Stat(input); err == nil {
+			list = isList(input)
+			data, err = loadRecords(from, input, list, match)
+		} else if from == "schemaorg" {
+			var d commonmeta.Data
+			d, err = schemaorg.Fetch(input, match)
+			data = []commonmeta.Data{d}
+		} else if from == "jsonfeed" {
+			var d commonmeta.Data
+			d, err = jsonfeed.Fetch(input)
+			data = []commonmeta.Data{d}
+		} else {
+			err = fmt.Errorf("%w: file %s", commonmeta.ErrNotFound, input)
+		}
+		if err != nil {
+			exitWithError(cmd, err)
+			return
+		}
+
+		// the records are written also if some fields couldn't be enriched,
+		// the error is reported afterwards
+		data, changes, enrichErr := enrich.New(rules, email).EnrichAll(data)
+
+		if provenance != "" {
+			output, err = json.MarshalIndent(changes, "", "  ")
+			if err == nil {
+				err = fileutils.WriteFile(provenance, output)
+			}
+			if err != nil {
+				exitWithError(cmd, err)
+				return
+			}
+		} else {
+			for _, c := range changes {
+				cmd.PrintErrf("%s: %s from %s %s\n", c.ID, c.Field, c.Source, c.Value)
+			}
+		}
+
+		file, extension, compress := fileutils.GetExtension(file, ".json")
+		if list {
+			output, err = commonmeta.WriteAll(data, extension)
+		} else {
+			output, err = commonmeta.Write(data[0])
+		}
+		if err != nil {
+			exitWithError(cmd, err)
+			return
+		}
+		if file == "" {
+			printOutput(cmd, output, enrichErr)
+			return
+		}
+		switch compress {
+		case "gz":
+			err = fileutils.WriteGZFile(file, output)
+		case "zip":
+			err = fileutils.WriteZIPFile(file, output)
+		default:
+			err = fileutils.WriteFile(file, output)
+		}
+		if err = errors.Join(err, enrichErr); err != nil {
+			exitWithError(cmd, err)
+		}
+	},
+}
+
+// isList reports whether a file contains a list of records: a JSON array,
+// JSON lines or XML, which can contain several records.
+func isList(filename string) bool {
+	switch filepath.Ext(filename) {
+	case ".jsonl", ".jsonlines", ".xml":
+		return true
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b == '['
+	}
+}
+
+// loadRecords loads a record or a list of records from a file.
+func loadRecords(from string, filename string, list bool, match bool) ([]commonmeta.Data, error) {
+	var d commonmeta.Data
+	var err error
+	if list {
+		switch from {
+		case "commonmeta":
+			return commonmeta.LoadAll(filename)
+		case "crossref":
+			return crossref.LoadAll(filename, match)
+		case "crossrefxml":
+			return crossrefxml.LoadAll(filename)
+		case "datacite":
+			return datacite.LoadAll(filename, match)
+		case "inveniordm":
+			return inveniordm.LoadAll(filename, match)
+		case "jsonfeed":
+			return jsonfeed.LoadAll(filename)
+		case "csl":
+			return csl.LoadAll(filename)
+		}
+		return nil, usageError("Please provide a valid input format")
+	}
+	switch from {
+	case "commonmeta":
+		d, err = commonmeta.Load(filename)
+	case "crossref":
+		d, err = crossref.Load(filename, match)
+	case "datacite":
+		d, err = datacite.Load(filename, match)
+	case "inveniordm":
+		d, err = inveniordm.Load(filename, match)
+	case "jsonfeed":
+		d, err = jsonfeed.Load(filename)
+	case "csl":
+		d, err = csl.Load(filename)
+	case "schemaorg":
+		d, err = schemaorg.Load(filename, match)
+	default:
+		return nil, usageError("Please provide a valid input format")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []commonmeta.Data{d}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(enrichCmd)
+
+	enrichCmd.Flags().StringP("rules", "", "", "enrichment rules file, default all fields from all sources")
+	enrichCmd.Flags().StringP("provenance", "", "", "file to write the fields added and their source to")
+}
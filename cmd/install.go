@@ -4,8 +4,6 @@ Copyright © 2024-2025 Front Matter <info@front-matter.io>
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/front-matter/commonmeta/ror"
 	"github.com/front-matter/commonmeta/spdx"
 	"github.com/spf13/cobra"
@@ -25,7 +23,7 @@ var installCmd = &cobra.Command{
 		dataVersion, _ := cmd.Flags().GetString("data-version")
 
 		if len(args) == 0 {
-			fmt.Println("Please provide an input")
+			exitWithError(cmd, usageError("Please provide an input"))
 			return
 		}
 		input := args[0]
@@ -34,7 +32,7 @@ var installCmd = &cobra.Command{
 		case "spdx":
 			_, err = spdx.FetchAll()
 			if err != nil {
-				cmd.Println(err)
+				exitWithError(cmd, err)
 				return
 			}
 			fileName = spdx.SPDXFilename
@@ -44,12 +42,12 @@ var installCmd = &cobra.Command{
 			}
 			_, err = ror.FetchAll(dataVersion)
 			if err != nil {
-				cmd.Println(err)
+				exitWithError(cmd, err)
 				return
 			}
 			fileName = ror.ArchivedFilename
 		default:
-			cmd.Println("Unsupported vocabulary. Supported vocabularies are: spdx, ror")
+			exitWithError(cmd, usageError("Unsupported vocabulary. Supported vocabularies are: spdx, ror"))
 			return
 		}
 		cmd.Printf("Saved %s file: %s\n", input, fileName)
@@ -18,8 +18,10 @@ import (
 	"github.com/front-matter/commonmeta/csl"
 	"github.com/front-matter/commonmeta/datacite"
 	"github.com/front-matter/commonmeta/fileutils"
+	"github.com/front-matter/commonmeta/harvest"
 	"github.com/front-matter/commonmeta/inveniordm"
 	"github.com/front-matter/commonmeta/jsonfeed"
+	"github.com/front-matter/commonmeta/oaipmh"
 	"github.com/front-matter/commonmeta/openalex"
 	"github.com/front-matter/commonmeta/ror"
 	"github.com/front-matter/commonmeta/schemaorg"
@@ -36,7 +38,16 @@ var listCmd = &cobra.Command{
 
 	commonmeta list --number 10 --member 78 --type journal-article - f crossref,
 	commonmeta list --number 10 --client cern.zenodo --type dataset -f datacite,
-	commonmeta list --number 10 --from inveniordm --from-host rogue-scholar.org --community front_matter`,
+	commonmeta list --number 10 --from inveniordm --from-host rogue-scholar.org --community front_matter,
+	commonmeta list --from oaipmh --from-host https://repo.example.org/oai --set xyz --since 2025-01-01,
+	commonmeta list --number 1000 --member 78 -f crossref --since-last-run --state harvest.json
+
+With --since-last-run, Crossref, DataCite, OpenAlex and InvenioRDM queries
+only return records indexed or updated since the last complete run of the
+same query, starting from --date-updated on the first run. --number limits
+the records fetched per run, the next run resumes where it stopped. The
+state is updated once the records are written, records that can't be read
+are skipped and reported.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		var input string // an identifier, content fetched via API
 		var str string   // a string, content loaded from a file
@@ -45,6 +56,8 @@ var listCmd = &cobra.Command{
 		var orgdata []ror.ROR
 		var extension string
 		var output []byte
+		var state *harvest.State // harvest state committed after the output is written
+		var harvested harvest.Result
 
 		number, _ := cmd.Flags().GetInt("number")
 		page, _ := cmd.Flags().GetInt("page")
@@ -65,6 +78,10 @@ var listCmd = &cobra.Command{
 		fromToken, _ := cmd.Flags().GetString("from-token")
 		community, _ := cmd.Flags().GetString("community")
 		subject, _ := cmd.Flags().GetString("subject")
+		set, _ := cmd.Flags().GetString("set")
+		since, _ := cmd.Flags().GetString("since")
+		until, _ := cmd.Flags().GetString("until")
+		metadataPrefix, _ := cmd.Flags().GetString("metadata-prefix")
 		dataVersion, _ := cmd.Flags().GetString("data-version")
 		vocabulary, _ := cmd.Flags().GetBool("vocabulary")
 		hasORCID, _ := cmd.Flags().GetBool("has-orcid")
@@ -79,6 +96,8 @@ var listCmd = &cobra.Command{
 		sample, _ := cmd.Flags().GetBool("sample")
 		file, _ := cmd.Flags().GetString("file")
 		match, _ := cmd.Flags().GetBool("match")
+		sinceLastRun, _ := cmd.Flags().GetBool("since-last-run")
+		statePath, _ := cmd.Flags().GetString("state")
 
 		depositor, _ := cmd.Flags().GetString("depositor")
 		email, _ := cmd.Flags().GetString("email")
@@ -98,7 +117,7 @@ var listCmd = &cobra.Command{
 		if input != "" && !strings.HasPrefix(input, "--") {
 			_, err = os.Stat(input)
 			if err != nil {
-				cmd.PrintErrf("File not found: %s", input)
+				exitWithError(cmd, fmt.Errorf("%w: file %s", commonmeta.ErrNotFound, input))
 				return
 			}
 			str = input
@@ -120,6 +139,45 @@ var listCmd = &cobra.Command{
 			data, err = jsonfeed.LoadAll(str)
 		} else if str != "" && from == "csl" {
 			data, err = csl.LoadAll(str)
+		} else if sinceLastRun && slices.Contains(harvest.Sources, from) {
+			var query string
+			options := harvest.Options{Number: number, Match: match, Email: email, Token: fromToken}
+			if dateUpdated != "" {
+				options.Since, err = time.Parse(time.DateOnly, dateUpdated)
+				if err != nil {
+					exitWithError(cmd, usageError("Please provide --date-updated as YYYY-MM-DD"))
+					return
+				}
+			}
+			switch from {
+			case "crossref":
+				query = crossref.QueryURL(number, page, member, type_, sample, year, orcid, ror_, hasORCID, hasROR, hasReferences, hasRelation, hasAbstract, hasAward, hasLicense, hasArchive)
+			case "datacite":
+				query = datacite.QueryURL(number, page, client_, type_, sample, year, language, orcid, ror_, hasORCID, hasROR, hasReferences, hasRelation, hasAbstract, hasAward, hasLicense)
+			case "openalex":
+				query = openalex.NewReader(email).QueryURL(number, page, member, type_, sample, "", year, orcid, ror_, hasORCID, hasROR, hasReferences, hasRelation, hasAbstract, hasAward, hasLicense, hasArchive)
+			case "inveniordm":
+				rl := rate.NewLimiter(rate.Every(100*time.Millisecond), 100)
+				options.Client = inveniordm.NewClient(rl, fromHost)
+				query = inveniordm.QueryURL(number, page, fromHost, community, subject, type_, year, language, orcid, affiliation, ror_, hasORCID, hasROR)
+			}
+			state, err = harvest.Open(statePath)
+			if err == nil {
+				harvested, err = harvest.Run(state, from, query, options)
+				data = harvested.Data
+			}
+			if len(harvested.Skipped) > 0 {
+				cmd.PrintErrf("Skipped %d records that could not be read:\n", len(harvested.Skipped))
+				for _, e := range harvested.Skipped {
+					cmd.PrintErrln(e)
+				}
+			}
+			if err != nil && len(data) > 0 {
+				// the checkpoint includes the records fetched before the
+				// error, so write them and resume in the next run
+				cmd.PrintErrf("Harvest interrupted, resume with the next run: %v\n", err)
+				err = nil
+			}
 		} else if from == "crossref" {
 			data, err = crossref.FetchAll(number, page, member, type_, sample, year, orcid, ror_, hasORCID, hasROR, hasReferences, hasRelation, hasAbstract, hasAward, hasLicense, hasArchive, match)
 		} else if from == "datacite" {
@@ -128,14 +186,16 @@ var listCmd = &cobra.Command{
 			r := openalex.NewReader(email)
 			data, err = r.FetchAll(number, page, member, type_, sample, "", year, orcid, ror_, hasORCID, hasROR, hasReferences, hasRelation, hasAbstract, hasAward, hasLicense, hasArchive)
 		} else if from == "inveniordm" {
-			rl := rate.NewLimiter(rate.Every(10*time.Second), 100)
+			rl := rate.NewLimiter(rate.Every(100*time.Millisecond), 100)
 			client := inveniordm.NewClient(rl, fromHost)
 			data, err = inveniordm.FetchAll(number, page, fromToken, community, subject, type_, year, language, orcid, affiliation, ror_, hasORCID, hasROR, match, client)
 		} else if from == "jsonfeed" {
 			data, err = jsonfeed.FetchAll(number, page, community, isArchived)
+		} else if from == "oaipmh" {
+			data, err = oaipmh.FetchAll(fromHost, metadataPrefix, set, since, until, number)
 		} else if str != "" && from == "ror" {
 			if type_ != "" && !slices.Contains(ror.RORTypes, type_) {
-				cmd.PrintErr("Please provide a valid type")
+				exitWithError(cmd, usageError("Please provide a valid type"))
 				return
 			}
 			orgdata, err = ror.LoadAll(str)
@@ -148,11 +208,11 @@ var listCmd = &cobra.Command{
 			orgdata, err = ror.LoadBuiltin()
 			input = ror.Basename("v1.71")
 		} else {
-			fmt.Println("Please provide a valid input format")
+			exitWithError(cmd, usageError("Please provide a valid input format"))
 			return
 		}
 		if err != nil {
-			fmt.Println("An error occurred:", err)
+			exitWithError(cmd, err)
 			return
 		}
 
@@ -161,7 +221,7 @@ var listCmd = &cobra.Command{
 			orgdata, err = ror.FilterList(orgdata, type_, country, dateUpdated, file, number, page)
 		}
 		if err != nil {
-			fmt.Println("An error occurred:", err)
+			exitWithError(cmd, err)
 			return
 		}
 
@@ -187,7 +247,12 @@ var listCmd = &cobra.Command{
 		} else if len(orgdata) > 0 && to == "inveniordm" {
 			output, err = ror.WriteAllInvenioRDM(orgdata, extension)
 		} else {
-			fmt.Println("Please provide a valid output format")
+			exitWithError(cmd, usageError("Please provide a valid output format"))
+			return
+		}
+
+		if err != nil {
+			exitWithError(cmd, err)
 			return
 		}
 
@@ -209,9 +274,13 @@ var listCmd = &cobra.Command{
 			default:
 				err = fileutils.WriteFile(file, output)
 			}
-		} else {
+		} else if !isJSONOutput(cmd) {
 			fmt.Printf("%s\n", output)
 		}
+		if err == nil && state != nil {
+			// the next run starts after the records written
+			err = state.Commit(harvested.Checkpoint)
+		}
 
 		if to == "inveniordm" && vocabulary {
 			file = "affiliations_ror.yaml"
@@ -225,15 +294,25 @@ var listCmd = &cobra.Command{
 			if err != nil {
 				cmd.PrintErr(err)
 			}
-			fmt.Printf("Found ROR IDs written to %s\n", file)
+			cmd.PrintErrf("Found ROR IDs written to %s\n", file)
 		}
 
-		if err != nil {
-			cmd.PrintErr(err)
+		if isJSONOutput(cmd) && file == "" {
+			printOutput(cmd, output, err)
+		} else if err != nil {
+			exitWithError(cmd, err)
+			return
 		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringP("set", "", "", "OAI-PMH set")
+	listCmd.Flags().StringP("since", "", "", "OAI-PMH records changed on or after this date")
+	listCmd.Flags().StringP("until", "", "", "OAI-PMH records changed on or before this date")
+	listCmd.Flags().BoolP("since-last-run", "", false, "only fetch records indexed or updated since the last run of the query")
+	listCmd.Flags().StringP("state", "", harvest.DefaultPath(), "harvest state recording the last run of each query")
+	listCmd.Flags().StringP("metadata-prefix", "", oaipmh.DefaultMetadataPrefix, "OAI-PMH metadata format, oai_dc, oai_datacite or datacite")
 }
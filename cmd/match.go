@@ -4,10 +4,9 @@ Copyright © 2024-2025 Front Matter <info@front-matter.io>
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 
+	"github.com/front-matter/commonmeta/commonmeta"
 	"github.com/front-matter/commonmeta/ror"
 	"github.com/spf13/cobra"
 )
@@ -32,7 +31,7 @@ var matchCmd = &cobra.Command{
 		to, _ := cmd.Flags().GetString("to")
 
 		if len(args) == 0 {
-			fmt.Println("Please provide an input")
+			exitWithError(cmd, usageError("Please provide an input"))
 			return
 		}
 
@@ -45,30 +44,24 @@ var matchCmd = &cobra.Command{
 		if from == "ror" {
 			orgdata, err = ror.MatchOrganization(input)
 			if err != nil {
-				cmd.Println(err)
+				exitWithError(cmd, err)
 				return
 			}
 		} else {
-			cmd.Println("No valid input format. Currently only 'ror' is supported.")
+			exitWithError(cmd, usageError("No valid input format. Currently only 'ror' is supported."))
 			return
 		}
 
 		if orgdata.ID == "" {
-			cmd.Println("No match found")
+			exitWithError(cmd, fmt.Errorf("%w: no match found for %s", commonmeta.ErrNotFound, input))
 			return
 		} else if to == "inveniordm" {
 			output, err = ror.WriteInvenioRDM(orgdata)
-			cmd.Println(string(output))
 		} else if to == "ror" {
 			output, err = ror.Write(orgdata)
-			var out bytes.Buffer
-			json.Indent(&out, output, "", "  ")
-			cmd.Println(out.String())
 		}
 
-		if err != nil {
-			cmd.PrintErr(err)
-		}
+		printOutput(cmd, output, err)
 	},
 }
 
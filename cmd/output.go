@@ -0,0 +1,171 @@
+/*
+Copyright © 2025 Front Matter <info@front-matter.io>
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/front-matter/commonmeta/audit"
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the commonmeta command, so that shell scripts can tell
+// failures apart.
+const (
+	ExitOK           = 0
+	ExitError        = 1
+	ExitUsage        = 2
+	ExitNotFound     = 3
+	ExitValidation   = 4
+	ExitUnauthorized = 5
+	ExitRateLimited  = 6
+	ExitAudit        = 7
+)
+
+// Result is the output of a command with --output json.
+type Result struct {
+	Status  string   `json:"status"`
+	Error   string   `json:"error,omitempty"`
+	Summary *Summary `json:"summary,omitempty"`
+	Records any      `json:"records"`
+	Audit   string   `json:"audit,omitempty"`
+}
+
+// Summary counts the records of a command that succeeded, failed or were
+// skipped.
+type Summary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped,omitempty"`
+}
+
+// summarize counts the records that failed, i.e. have an error or a
+// failed status, and the records that were skipped.
+func summarize(records []commonmeta.APIResponse) Summary {
+	summary := Summary{Total: len(records)}
+	for _, record := range records {
+		if record.Error != "" || strings.HasPrefix(record.Status, "failed") {
+			summary.Failed++
+		} else if record.Status == "skipped" {
+			summary.Skipped++
+		}
+	}
+	summary.Succeeded = summary.Total - summary.Failed - summary.Skipped
+	return summary
+}
+
+// usageError is returned for missing or invalid command line arguments.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// exitCode returns the exit code for an error.
+func exitCode(err error) int {
+	var usage usageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usage):
+		return ExitUsage
+	case errors.Is(err, commonmeta.ErrNotFound):
+		return ExitNotFound
+	case errors.Is(err, commonmeta.ErrValidation):
+		return ExitValidation
+	case errors.Is(err, commonmeta.ErrUnauthorized):
+		return ExitUnauthorized
+	case errors.Is(err, commonmeta.ErrRateLimited):
+		return ExitRateLimited
+	default:
+		return ExitError
+	}
+}
+
+// isJSONOutput reports whether the --output json flag is set.
+func isJSONOutput(cmd *cobra.Command) bool {
+	format, _ := cmd.Flags().GetString("output")
+	return format == "json"
+}
+
+// printOutput prints the output of a command and exits with the exit code for err.
+// With --output json, output and err are wrapped in a Result. Otherwise JSON
+// output is indented, and err is printed to stderr.
+func printOutput(cmd *cobra.Command, output []byte, err error) {
+	printResult(cmd, output, nil, err)
+}
+
+// printRecords prints the records of a command together with a summary of
+// the records that succeeded and failed, and exits with the exit code for err.
+func printRecords(cmd *cobra.Command, records []commonmeta.APIResponse, err error) {
+	output, _ := json.Marshal(records)
+	summary := summarize(records)
+	printResult(cmd, output, &summary, err)
+}
+
+// printResult prints the output of a command and an optional summary, and
+// exits with the exit code for err. Errors writing the audit log are
+// reported after the outcome, with ExitAudit if the command succeeded.
+func printResult(cmd *cobra.Command, output []byte, summary *Summary, err error) {
+	auditErr := audit.Err()
+	if isJSONOutput(cmd) {
+		result := Result{Status: "ok", Summary: summary}
+		if err != nil {
+			result.Status = "error"
+			result.Error = err.Error()
+		}
+		if len(output) > 0 {
+			if json.Valid(output) {
+				result.Records = json.RawMessage(output)
+			} else {
+				result.Records = string(output)
+			}
+		}
+		if auditErr != nil {
+			result.Audit = auditErr.Error()
+		}
+		b, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Fprintln(cmd.OutOrStdout(), string(b))
+	} else {
+		if len(output) > 0 {
+			var out bytes.Buffer
+			if json.Indent(&out, output, "", "  ") == nil {
+				output = out.Bytes()
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(output))
+		}
+		if summary != nil {
+			if summary.Skipped > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%d records: %d succeeded, %d skipped, %d failed\n", summary.Total, summary.Succeeded, summary.Skipped, summary.Failed)
+			} else {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%d records: %d succeeded, %d failed\n", summary.Total, summary.Succeeded, summary.Failed)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(cmd.ErrOrStderr(), "An error occurred:", err)
+		}
+		if auditErr != nil {
+			fmt.Fprintln(cmd.ErrOrStderr(), "The audit log was not written:", auditErr)
+		}
+	}
+	code := exitCode(err)
+	if code == ExitOK && auditErr != nil {
+		code = ExitAudit
+	}
+	if code != ExitOK {
+		os.Exit(code)
+	}
+}
+
+// exitWithError prints err and exits with the exit code for err.
+func exitWithError(cmd *cobra.Command, err error) {
+	printOutput(cmd, nil, err)
+}
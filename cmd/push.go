@@ -4,18 +4,24 @@ Copyright © 2024-2025 Front Matter <info@front-matter.io>
 package cmd
 
 import (
-	"bytes"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/crossrefxml"
 	"github.com/front-matter/commonmeta/csl"
 	"github.com/front-matter/commonmeta/datacite"
+	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/inveniordm"
+	"github.com/front-matter/commonmeta/jobs"
 	"github.com/front-matter/commonmeta/jsonfeed"
 	"golang.org/x/time/rate"
 
@@ -31,7 +37,15 @@ var pushCmd = &cobra.Command{
 a service. Multiple formats are supported, registration is currently
 only supported with InvenioRDM. Example usage:
 
-commonmeta push --sample -f crossref -t inveniordm -h rogue-scholar.org --token mytoken`,
+commonmeta push --sample -f crossref -t inveniordm -h rogue-scholar.org --token mytoken
+
+Records whose metadata didn't change since their last registration
+recorded in the job store are skipped, use --force to push them anyway.
+Crossref deposits stay pending in the job store until --wait or the
+submission logs checked by the next push or retry confirm their
+registration, and are not deposited again while they are processed.
+Use --dry-run to show the payloads and the changes to the registered
+metadata without registering, for the given --deposit-type and --files.`,
 
 	Run: func(cmd *cobra.Command, args []string) {
 		var input string
@@ -69,18 +83,10 @@ commonmeta push --sample -f crossref -t inveniordm -h rogue-scholar.org --token
 		isArchived, _ := cmd.Flags().GetBool("is-archived")
 		sample, _ := cmd.Flags().GetBool("sample")
 
-		depositor, _ := cmd.Flags().GetString("depositor")
-		email, _ := cmd.Flags().GetString("email")
-		registrant, _ := cmd.Flags().GetString("registrant")
-		loginID, _ := cmd.Flags().GetString("login_id")
-		loginPasswd, _ := cmd.Flags().GetString("login_passwd")
 		to, _ := cmd.Flags().GetString("to")
-		host, _ := cmd.Flags().GetString("host")
-		token, _ := cmd.Flags().GetString("token")
-		legacyKey, _ := cmd.Flags().GetString("legacyKey")
-		password, _ := cmd.Flags().GetString("password")
-		development, _ := cmd.Flags().GetBool("development")
 		match, _ := cmd.Flags().GetBool("match")
+		force, _ := cmd.Flags().GetBool("force")
+		dryRun_, _ := cmd.Flags().GetBool("dry-run")
 
 		cmd.SetOut(os.Stdout)
 		cmd.SetErr(os.Stderr)
@@ -88,7 +94,7 @@ commonmeta push --sample -f crossref -t inveniordm -h rogue-scholar.org --token
 		if input != "" && !strings.HasPrefix(input, "--") {
 			_, err = os.Stat(input)
 			if err != nil {
-				cmd.PrintErrf("File not found: %s", input)
+				exitWithError(cmd, fmt.Errorf("%w: file %s", commonmeta.ErrNotFound, input))
 				return
 			}
 			str = input
@@ -99,7 +105,7 @@ commonmeta push --sample -f crossref -t inveniordm -h rogue-scholar.org --token
 		} else if from == "datacite" {
 			data, err = datacite.FetchAll(number, page, client_, type_, sample, year, language, orcid, ror, hasORCID, hasROR, hasReferences, hasRelation, hasAbstract, hasAward, hasLicense, match)
 		} else if from == "inveniordm" {
-			rl := rate.NewLimiter(rate.Every(10*time.Second), 100)
+			rl := rate.NewLimiter(rate.Every(100*time.Millisecond), 100)
 			client := inveniordm.NewClient(rl, fromHost)
 			data, err = inveniordm.FetchAll(number, page, fromToken, community, subject, type_, year, language, orcid, affiliation, ror, hasORCID, hasROR, match, client)
 		} else if from == "jsonfeed" {
@@ -119,65 +125,291 @@ commonmeta push --sample -f crossref -t inveniordm -h rogue-scholar.org --token
 		} else if str != "" && from == "csl" {
 			data, err = csl.LoadAll(str)
 		} else {
-			fmt.Println("Please provide a valid input format")
+			exitWithError(cmd, usageError("Please provide a valid input format"))
 			return
 		}
 
 		if err != nil {
-			cmd.PrintErr(err)
+			exitWithError(cmd, err)
+			return
 		}
 
-		var records []commonmeta.APIResponse
-		switch to {
-		case "crossrefxml":
-			account := crossrefxml.Account{
-				Depositor:   depositor,
-				Email:       email,
-				Registrant:  registrant,
-				LoginID:     loginID,
-				LoginPasswd: loginPasswd,
-			}
-			records, err = crossrefxml.UpsertAll(data, account, legacyKey)
-		case "datacite":
-			account := datacite.Account{
-				Client:      client_,
-				Password:    password,
-				Development: development,
+		if dryRun_ {
+			previews, err := dryRun(cmd, to, data)
+			printPreviews(cmd, previews, err)
+			return
+		}
+
+		store, err := openJobs(cmd)
+		if err != nil {
+			exitWithError(cmd, err)
+			return
+		}
+		options := pushOptions(cmd)
+		var skipped []commonmeta.APIResponse
+		var resolveErr error
+		if store != nil {
+			resolveErr = resolveDeposits(cmd, store, to)
+			hash := contentHash(cmd, to, options)
+			if !force && hash != nil {
+				var unchanged []commonmeta.Data
+				data, unchanged, err = store.Changed(to, options, data, hash)
+				if err != nil {
+					exitWithError(cmd, err)
+					return
+				}
+				skipped = skippedRecords(unchanged)
 			}
-			records, err = datacite.UpsertAll(data, account)
-		case "inveniordm":
-			if host == "" || token == "" {
-				fmt.Println("Please provide an inveniordm host and token")
+			err = store.Start(to, options, data, hash)
+			if err != nil {
+				exitWithError(cmd, err)
 				return
 			}
-			rl := rate.NewLimiter(rate.Every(10*time.Second), 100) // 100 request every 10 seconds
-			client := inveniordm.NewClient(rl, host)
-			records, err = inveniordm.UpsertAll(data, fromHost, token, legacyKey, client)
-		default:
-			fmt.Println("Please provide a valid service")
-			return
 		}
+		var records []commonmeta.APIResponse
+		if len(data) > 0 {
+			records, err = pushRecords(cmd, to, data, options)
+		}
+		if store != nil {
+			store.Finish(to, options, data, records, err)
+			err = errors.Join(err, store.Save())
+		}
+		printRecords(cmd, append(records, skipped...), errors.Join(err, resolveErr))
+	},
+}
 
-		if err != nil {
-			cmd.PrintErr(err)
+// pushRecords registers a list of records with a service with options,
+// using the credentials and settings given as flags.
+func pushRecords(cmd *cobra.Command, to string, data []commonmeta.Data, options jobs.Options) ([]commonmeta.APIResponse, error) {
+	var records []commonmeta.APIResponse
+	var err error
+
+	depositor, _ := cmd.Flags().GetString("depositor")
+	email, _ := cmd.Flags().GetString("email")
+	registrant, _ := cmd.Flags().GetString("registrant")
+	loginID, _ := cmd.Flags().GetString("login_id")
+	loginPasswd, _ := cmd.Flags().GetString("login_passwd")
+	host, _ := cmd.Flags().GetString("host")
+	fromHost, _ := cmd.Flags().GetString("from-host")
+	token, _ := cmd.Flags().GetString("token")
+	legacyKey, _ := cmd.Flags().GetString("legacyKey")
+	client_, _ := cmd.Flags().GetString("client")
+	password, _ := cmd.Flags().GetString("password")
+	development, _ := cmd.Flags().GetBool("development")
+	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	batchBytes, _ := cmd.Flags().GetInt("batch-bytes")
+	wait, _ := cmd.Flags().GetDuration("wait")
+	workers, _ := cmd.Flags().GetInt("workers")
+	rateLimit, _ := cmd.Flags().GetFloat64("rate-limit")
+
+	switch to {
+	case "crossrefxml":
+		account := crossrefxml.Account{
+			Depositor:   depositor,
+			Email:       email,
+			Registrant:  registrant,
+			LoginID:     loginID,
+			LoginPasswd: loginPasswd,
+			Development: development,
+		}
+		if options.DepositType == "" || options.DepositType == "metadata" {
+			setRateLimit(config.CrossrefDepositURL(development), rateLimit)
+			records, err = crossrefxml.UpsertBatches(data, account, legacyKey, batchSize, batchBytes, workers)
+		} else {
+			records, err = crossrefxml.UpsertResources(data, account, options.DepositType)
+		}
+		if wait > 0 {
+			var pollErr error
+			records, pollErr = crossrefxml.PollStatus(records, account, 30*time.Second, wait)
+			err = errors.Join(err, pollErr)
+		}
+	case "datacite":
+		account := datacite.Account{
+			Client:      client_,
+			Password:    password,
+			Development: development,
+			Event:       options.Event,
+		}
+		setRateLimit(config.DataCiteURL(development), rateLimit)
+		records, err = datacite.UpsertAll(data, account, workers)
+	case "inveniordm":
+		if host == "" || token == "" {
+			return records, usageError("Please provide an inveniordm host and token")
 		}
+		rl := rate.NewLimiter(rate.Every(100*time.Millisecond), 100) // 10 requests per second, bursts of 100
+		if rateLimit > 0 {
+			rl = rate.NewLimiter(rate.Limit(rateLimit), max(1, int(rateLimit)))
+		}
+		client := inveniordm.NewClient(rl, host)
+		records, err = inveniordm.UpsertAll(data, token, inveniordm.UpsertOptions{
+			FromHost:  fromHost,
+			LegacyKey: legacyKey,
+			Files:     options.Files,
+			Workers:   workers,
+		}, client)
+	default:
+		return records, usageError("Please provide a valid service")
+	}
+	return records, err
+}
 
-		var output []byte
-		var out bytes.Buffer
-		output, err = json.Marshal(records)
-		if err != nil {
-			cmd.PrintErr(err)
+// contentHash returns the function computing the content hash of a record
+// after conversion for a service, or nil for an invalid service. For
+// Crossref resource-only and reference-only deposits only the deposited
+// resources or references are hashed.
+func contentHash(cmd *cobra.Command, to string, options jobs.Options) jobs.HashFunc {
+	fromHost, _ := cmd.Flags().GetString("from-host")
+
+	switch to {
+	case "crossrefxml":
+		if options.DepositType != "" && options.DepositType != "metadata" {
+			return func(data commonmeta.Data) (string, error) {
+				body, err := crossrefxml.ConvertResources(data, options.DepositType)
+				if errors.Is(err, commonmeta.ErrValidation) {
+					// records without resources or references fail in the deposit
+					return "", nil
+				} else if err != nil {
+					return "", err
+				}
+				output, err := xml.Marshal(body)
+				return jobs.Hash(output), err
+			}
+		}
+		return func(data commonmeta.Data) (string, error) {
+			body, err := crossrefxml.Convert(data)
+			if err != nil {
+				return "", err
+			}
+			output, err := xml.Marshal(body)
+			return jobs.Hash(output), err
+		}
+	case "datacite":
+		return func(data commonmeta.Data) (string, error) {
+			record, err := datacite.Convert(data)
+			if err != nil {
+				return "", err
+			}
+			output, err := json.Marshal(record)
+			return jobs.Hash(output), err
+		}
+	case "inveniordm":
+		return func(data commonmeta.Data) (string, error) {
+			record, err := inveniordm.Convert(data, fromHost)
+			if err != nil {
+				return "", err
+			}
+			output, err := json.Marshal(record)
+			return jobs.Hash(output), err
 		}
+	}
+	return nil
+}
 
-		json.Indent(&out, output, "", "  ")
-		cmd.Println(out.String())
+// pushOptions returns the options of a registration given as flags.
+func pushOptions(cmd *cobra.Command) jobs.Options {
+	depositType, _ := cmd.Flags().GetString("deposit-type")
+	event, _ := cmd.Flags().GetString("event")
+	files, _ := cmd.Flags().GetBool("files")
 
-		if err != nil {
-			cmd.PrintErr(err)
+	return jobs.Options{
+		DepositType: depositType,
+		Event:       event,
+		Files:       files,
+	}
+}
+
+// resolveDeposits updates the submitted Crossref deposits in the job store
+// with the results from their submission logs, so that records are not
+// deposited again while their deposit is queued or being processed.
+func resolveDeposits(cmd *cobra.Command, store *jobs.Store, to string) error {
+	if to != "crossrefxml" {
+		return nil
+	}
+	depositor, _ := cmd.Flags().GetString("depositor")
+	email, _ := cmd.Flags().GetString("email")
+	registrant, _ := cmd.Flags().GetString("registrant")
+	loginID, _ := cmd.Flags().GetString("login_id")
+	loginPasswd, _ := cmd.Flags().GetString("login_passwd")
+	development, _ := cmd.Flags().GetBool("development")
+
+	account := crossrefxml.Account{
+		Depositor:   depositor,
+		Email:       email,
+		Registrant:  registrant,
+		LoginID:     loginID,
+		LoginPasswd: loginPasswd,
+		Development: development,
+	}
+	var errs []error
+	for _, doiBatchID := range store.Batches(to) {
+		records, err := crossrefxml.Status(doiBatchID, account)
+		if errors.Is(err, commonmeta.ErrNotFound) {
+			// the deposit is not yet visible in the submission queue
+			continue
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("deposit %s: %w", doiBatchID, err))
+			continue
 		}
-	},
+		store.Resolve(to, records)
+	}
+	return errors.Join(errs...)
+}
+
+// skippedRecords returns the records skipped by push because their
+// metadata didn't change.
+func skippedRecords(list []commonmeta.Data) []commonmeta.APIResponse {
+	var records []commonmeta.APIResponse
+	for _, data := range list {
+		record := commonmeta.APIResponse{ID: data.ID, Status: "skipped"}
+		if doi, ok := doiutils.ValidateDOI(data.ID); ok {
+			record = commonmeta.APIResponse{DOI: doiutils.NormalizeDOI(doi), Status: "skipped"}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// openJobs opens the job store given with --jobs, or returns nil if the
+// job store is disabled with an empty path.
+func openJobs(cmd *cobra.Command) (*jobs.Store, error) {
+	path, _ := cmd.Flags().GetString("jobs")
+	if path == "" {
+		return nil, nil
+	}
+	return jobs.Open(path)
+}
+
+// setRateLimit sets the rate limit in requests per second for the host of
+// a service URL. A rate of 0 keeps the default.
+func setRateLimit(serviceURL string, r float64) {
+	if r <= 0 {
+		return
+	}
+	u, err := url.Parse(serviceURL)
+	if err != nil {
+		return
+	}
+	httputils.SetRateLimit(u.Hostname(), r, max(1, int(r)))
 }
 
 func init() {
 	rootCmd.AddCommand(pushCmd)
+
+	addPushFlags(pushCmd)
+	pushCmd.Flags().StringP("deposit-type", "", "metadata", "Crossref deposit type: metadata, resources (text-mining URLs only) or references")
+	pushCmd.Flags().BoolP("files", "", false, "upload files to InvenioRDM from their local path or URL")
+	pushCmd.Flags().StringP("event", "", datacite.DefaultEvent, "DataCite DOI state transition: draft, register, publish or hide")
+	pushCmd.Flags().BoolP("dry-run", "", false, "show the payloads and changes without registering")
+	pushCmd.Flags().BoolP("force", "", false, "push all records, including records whose metadata didn't change")
+}
+
+// addPushFlags adds the flags used to register records with a service,
+// except for the options recorded with each job.
+func addPushFlags(c *cobra.Command) {
+	c.Flags().IntP("batch-size", "", crossrefxml.DefaultBatchSize, "maximum number of records per Crossref deposit")
+	c.Flags().IntP("batch-bytes", "", crossrefxml.DefaultBatchBytes, "maximum size in bytes of a Crossref deposit")
+	c.Flags().IntP("workers", "", 1, "number of records or Crossref batches pushed concurrently")
+	c.Flags().Float64P("rate-limit", "", 0, "maximum requests per second to the service, 0 uses the default")
+	c.Flags().DurationP("wait", "", 0, "wait for the Crossref submission logs to confirm registration, e.g. 10m")
+	c.Flags().StringP("jobs", "", jobs.DefaultPath(), "job store recording registrations for retry, empty to disable")
 }
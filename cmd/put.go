@@ -4,7 +4,6 @@ Copyright © 2024-2025 Front Matter <info@front-matter.io>
 package cmd
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -32,7 +31,15 @@ var putCmd = &cobra.Command{
 a service. Multiple formats are supported, registration is currently
 only supported with InvenioRDM. Example usage:
 
-commonmeta put 10.5555/12345678 -f crossref -t inveniordm -h rogue-scholar.org --token mytoken`,
+commonmeta put 10.5555/12345678 -f crossref -t inveniordm -h rogue-scholar.org --token mytoken
+
+Use --dry-run to show the payload and the changes to the registered
+metadata without registering, for the given --deposit-type, --files and
+--new-version.
+
+Crossref resources deposits (--deposit-type resources) only update the
+text-mining URLs of a DOI, not the URL the DOI resolves to. Use a
+metadata deposit to change the resolution URL.`,
 
 	Run: func(cmd *cobra.Command, args []string) {
 		var id string  // an identifier, content fetched via API
@@ -54,12 +61,17 @@ commonmeta put 10.5555/12345678 -f crossref -t inveniordm -h rogue-scholar.org -
 		password, _ := cmd.Flags().GetString("password")
 		development, _ := cmd.Flags().GetBool("development")
 		match, _ := cmd.Flags().GetBool("match")
+		depositType, _ := cmd.Flags().GetString("deposit-type")
+		event, _ := cmd.Flags().GetString("event")
+		files, _ := cmd.Flags().GetBool("files")
+		newVersion, _ := cmd.Flags().GetBool("new-version")
+		dryRun_, _ := cmd.Flags().GetBool("dry-run")
 
 		cmd.SetOut(os.Stdout)
 		cmd.SetErr(os.Stderr)
 
 		if len(args) == 0 {
-			fmt.Println("Please provide an input")
+			exitWithError(cmd, usageError("Please provide an input"))
 			return
 		}
 		input := args[0]
@@ -67,7 +79,7 @@ commonmeta put 10.5555/12345678 -f crossref -t inveniordm -h rogue-scholar.org -
 		if id == "" {
 			_, err = os.Stat(input)
 			if err != nil {
-				fmt.Printf("File not found: %s", input)
+				exitWithError(cmd, fmt.Errorf("%w: file %s", commonmeta.ErrNotFound, input))
 				return
 			}
 			str = input
@@ -87,7 +99,7 @@ commonmeta put 10.5555/12345678 -f crossref -t inveniordm -h rogue-scholar.org -
 			case "datacite":
 				data, err = datacite.Fetch(id, match)
 			case "inveniordm":
-				rl := rate.NewLimiter(rate.Every(10*time.Second), 100)
+				rl := rate.NewLimiter(rate.Every(100*time.Millisecond), 100)
 				client := inveniordm.NewClient(rl, fromHost)
 				data, err = inveniordm.Fetch(id, match, client)
 			case "jsonfeed":
@@ -95,11 +107,11 @@ commonmeta put 10.5555/12345678 -f crossref -t inveniordm -h rogue-scholar.org -
 			case "schemaorg":
 				data, err = schemaorg.Fetch(id, match)
 			default:
-				fmt.Println("Please provide a valid input")
+				exitWithError(cmd, usageError("Please provide a valid input"))
 				return
 			}
 			if err != nil {
-				fmt.Println("An error occurred:", err)
+				exitWithError(cmd, err)
 				return
 			}
 		} else if str != "" {
@@ -117,17 +129,19 @@ commonmeta put 10.5555/12345678 -f crossref -t inveniordm -h rogue-scholar.org -
 			} else if from == "csl" {
 				data, err = csl.Load(str)
 			} else {
-				cmd.PrintErr("Please provide a valid input format")
+				exitWithError(cmd, usageError("Please provide a valid input format"))
 				return
 			}
 			if err != nil {
-				fmt.Println("An error occurred:", err)
+				exitWithError(cmd, err)
 				return
 			}
 		}
 
-		if err != nil {
-			cmd.PrintErr(err)
+		if dryRun_ {
+			previews, err := dryRun(cmd, to, []commonmeta.Data{data})
+			printPreviews(cmd, previews, err)
+			return
 		}
 
 		var record commonmeta.APIResponse
@@ -139,51 +153,54 @@ commonmeta put 10.5555/12345678 -f crossref -t inveniordm -h rogue-scholar.org -
 				Registrant:  registrant,
 				LoginID:     loginID,
 				LoginPasswd: loginPasswd,
+				Development: development,
+			}
+			if depositType == "" || depositType == "metadata" {
+				record, err = crossrefxml.Upsert(record, account, legacyKey, data)
+			} else {
+				var records []commonmeta.APIResponse
+				records, err = crossrefxml.UpsertResources([]commonmeta.Data{data}, account, depositType)
+				if len(records) > 0 {
+					record = records[0]
+				}
 			}
-			record, err = crossrefxml.Upsert(record, account, legacyKey, data)
 		case "datacite":
 			account := datacite.Account{
 				Client:      client_,
 				Password:    password,
 				Development: development,
+				Event:       event,
 			}
 			record, err = datacite.Upsert(record, account, data)
 		case "inveniordm":
 			if host == "" || token == "" {
-				fmt.Println("Please provide an inveniordm host and token")
+				exitWithError(cmd, usageError("Please provide an inveniordm host and token"))
 				return
 			}
-			rl := rate.NewLimiter(rate.Every(10*time.Second), 100) // 100 request every 10 seconds
+			rl := rate.NewLimiter(rate.Every(100*time.Millisecond), 100) // 10 requests per second, bursts of 100
 			client := inveniordm.NewClient(rl, host)
-			record, err = inveniordm.Upsert(record, fromHost, token, legacyKey, data, client)
-			if err != nil {
-				cmd.PrintErr(err)
-			}
+			record, err = inveniordm.Upsert(record, data, token, inveniordm.UpsertOptions{
+				FromHost:   fromHost,
+				LegacyKey:  legacyKey,
+				Files:      files,
+				NewVersion: newVersion,
+			}, client)
 		default:
-			fmt.Println("Please provide a valid service")
+			exitWithError(cmd, usageError("Please provide a valid service"))
 			return
 		}
 
-		if err != nil {
-			cmd.PrintErr(err)
-		}
-
-		var output []byte
-		var out bytes.Buffer
-		output, err = json.Marshal(record)
-		if err != nil {
-			cmd.PrintErr(err)
-		}
-
-		json.Indent(&out, output, "", "  ")
-		cmd.Println(out.String())
-
-		if err != nil {
-			cmd.PrintErr(err)
-		}
+		output, _ := json.Marshal(record)
+		printOutput(cmd, output, err)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(putCmd)
+
+	putCmd.Flags().StringP("deposit-type", "", "metadata", "Crossref deposit type: metadata, resources (text-mining URLs only) or references")
+	putCmd.Flags().BoolP("files", "", false, "upload files to InvenioRDM from their local path or URL")
+	putCmd.Flags().BoolP("new-version", "", false, "create a new InvenioRDM version of the record given as IsNewVersionOf or IsVersionOf relation, fails if the DOI exists")
+	putCmd.Flags().BoolP("dry-run", "", false, "show the payload and changes without registering")
+	putCmd.Flags().StringP("event", "", datacite.DefaultEvent, "DataCite DOI state transition: draft, register, publish or hide")
 }
@@ -0,0 +1,124 @@
+/*
+Copyright © 2025 Front Matter <info@front-matter.io>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/jobs"
+	"github.com/spf13/cobra"
+)
+
+// retryCmd represents the retry command
+var retryCmd = &cobra.Command{
+	Use:   "retry",
+	Short: "Retry failed registrations",
+	Long: `Retry failed or pending registrations recorded in the job store by push.
+Jobs are retried with exponential backoff, use --all to retry all jobs
+that are due or not. Crossref deposits that are queued or being processed
+are checked in the submission logs and not deposited again. Each job is
+retried with the deposit type, event and files option it was pushed with,
+credentials and other settings are the same as for push.
+Example usage:
+
+commonmeta retry --profile crossref-prod
+commonmeta retry -t datacite --all
+commonmeta retry --list`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		maxAttempts, _ := cmd.Flags().GetInt("max-attempts")
+		all, _ := cmd.Flags().GetBool("all")
+		list, _ := cmd.Flags().GetBool("list")
+		to, _ := cmd.Flags().GetString("to")
+
+		cmd.SetOut(os.Stdout)
+		cmd.SetErr(os.Stderr)
+
+		store, err := openJobs(cmd)
+		if err != nil {
+			exitWithError(cmd, err)
+			return
+		}
+		if store == nil {
+			exitWithError(cmd, usageError("Please provide a job store"))
+			return
+		}
+
+		if list {
+			output, _ := json.Marshal(store.List())
+			printOutput(cmd, output, nil)
+			return
+		}
+
+		var errs []error
+		if !cmd.Flags().Changed("to") || to == "crossrefxml" {
+			errs = append(errs, resolveDeposits(cmd, store, "crossrefxml"))
+		}
+
+		var due []jobs.Job
+		if all {
+			for _, job := range store.List(jobs.StatusFailed, jobs.StatusPending) {
+				if !job.Submitted() {
+					due = append(due, job)
+				}
+			}
+		} else {
+			due = store.Due(time.Now().UTC(), maxAttempts)
+		}
+
+		// group jobs by target and options, keeping the order of jobs
+		type group struct {
+			target  string
+			options jobs.Options
+		}
+		var groups []group
+		byGroup := make(map[group][]commonmeta.Data)
+		for _, job := range due {
+			if cmd.Flags().Changed("to") && job.Target != to {
+				continue
+			}
+			data, err := job.Data()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", job.ID, err))
+				continue
+			}
+			g := group{target: job.Target, options: job.Options}
+			if _, ok := byGroup[g]; !ok {
+				groups = append(groups, g)
+			}
+			byGroup[g] = append(byGroup[g], data)
+		}
+
+		var records []commonmeta.APIResponse
+		for _, g := range groups {
+			data := byGroup[g]
+			err = store.Start(g.target, g.options, data, contentHash(cmd, g.target, g.options))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			groupRecords, err := pushRecords(cmd, g.target, data, g.options)
+			store.Finish(g.target, g.options, data, groupRecords, err)
+			records = append(records, groupRecords...)
+			errs = append(errs, err)
+		}
+		errs = append(errs, store.Save())
+
+		printRecords(cmd, records, errors.Join(errs...))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(retryCmd)
+
+	addPushFlags(retryCmd)
+	retryCmd.Flags().IntP("max-attempts", "", 10, "maximum number of attempts per job, 0 for no limit")
+	retryCmd.Flags().BoolP("all", "", false, "retry all failed and pending jobs, ignoring the backoff")
+	retryCmd.Flags().BoolP("list", "", false, "list the jobs in the job store")
+}
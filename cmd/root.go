@@ -6,9 +6,15 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/user"
 
+	"github.com/front-matter/commonmeta/audit"
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
+	"github.com/front-matter/commonmeta/httputils"
+	"github.com/front-matter/commonmeta/inveniordm"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -23,6 +29,49 @@ JSON Feed, and ROR. Example usage:
 commonmeta convert 10.5555/12345678
 commonmeta convert org 10.5555/12345678`,
 
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		err := applyConfig(cmd)
+		if err != nil {
+			exitWithError(cmd, err)
+		}
+		output, _ := cmd.Flags().GetString("output")
+		if output != "text" && output != "json" {
+			exitWithError(cmd, usageError("Please provide a valid output mode: text or json"))
+		}
+		email, _ := cmd.Flags().GetString("email")
+		maxRetries, _ := cmd.Flags().GetInt("max-retries")
+		cache, _ := cmd.Flags().GetBool("cache")
+		cacheDir, _ := cmd.Flags().GetString("cache-dir")
+		cacheTTL, _ := cmd.Flags().GetDuration("cache-ttl")
+		httputils.Configure(httputils.Options{
+			Version:    commonmeta.Version,
+			Email:      email,
+			MaxRetries: &maxRetries,
+			Cache:      cache,
+			CacheDir:   cacheDir,
+			CacheTTL:   cacheTTL,
+		})
+		mapping, _ := cmd.Flags().GetString("mapping")
+		if mapping != "" {
+			m, err := inveniordm.LoadMapping(mapping)
+			if err != nil {
+				exitWithError(cmd, fmt.Errorf("%w: mapping file %s: %w", commonmeta.ErrValidation, mapping, err))
+			}
+			inveniordm.SetMapping(m)
+		}
+		auditLog, _ := cmd.Flags().GetString("audit-log")
+		profile, _ := cmd.Flags().GetString("profile")
+		if !cmd.Flags().Changed("profile") {
+			profile = os.Getenv(config.EnvName("profile"))
+		}
+		audit.Configure(audit.Log{
+			Path:    auditLog,
+			User:    currentUser(),
+			Profile: profile,
+			Command: cmd.Name(),
+		})
+	},
+
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("root called")
 	},
@@ -31,11 +80,54 @@ commonmeta convert org 10.5555/12345678`,
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(ExitUsage)
+	}
+}
+
+// currentUser returns the name of the user running the command.
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return os.Getenv("USER")
 	}
+	return u.Username
+}
+
+// applyConfig sets all flags not given on the command line from COMMONMETA_*
+// environment variables or the selected profile of the configuration file,
+// so that credentials don't need to be passed as flags.
+func applyConfig(cmd *cobra.Command) error {
+	path, _ := cmd.Flags().GetString("config")
+	c, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	config.SetEndpoints(c.Endpoints)
+
+	name, _ := cmd.Flags().GetString("profile")
+	if !cmd.Flags().Changed("profile") {
+		name = os.Getenv(config.EnvName("profile"))
+	}
+	profile, err := c.GetProfile(name)
+	if err != nil {
+		return err
+	}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "config" || f.Name == "profile" {
+			return
+		}
+		if v, ok := profile.Lookup(f.Name); ok {
+			err = cmd.Flags().Set(f.Name, v)
+		}
+	})
+	return err
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringP("config", "", config.DefaultPath(), "configuration file")
+	rootCmd.PersistentFlags().StringP("profile", "", "", "configuration profile, e.g. crossref-prod")
+
+	rootCmd.PersistentFlags().StringP("output", "o", "text", "output mode, text or json")
 	rootCmd.PersistentFlags().StringP("from", "f", "", "the format to convert from")
 	rootCmd.PersistentFlags().StringP("to", "t", "commonmeta", "the format to convert to")
 
@@ -70,10 +162,14 @@ func init() {
 	rootCmd.PersistentFlags().BoolP("is-archived", "", true, "is archived")
 	rootCmd.PersistentFlags().BoolP("vocabulary", "", false, "with vocabulary")
 	rootCmd.PersistentFlags().BoolP("match", "", true, "enable matching")
+	rootCmd.PersistentFlags().IntP("max-retries", "", 3, "maximum number of retries for failed HTTP requests")
+	rootCmd.PersistentFlags().BoolP("cache", "", false, "cache HTTP responses on disk")
+	rootCmd.PersistentFlags().StringP("cache-dir", "", httputils.DefaultCacheDir(), "HTTP cache directory")
+	rootCmd.PersistentFlags().DurationP("cache-ttl", "", httputils.DefaultCacheTTL, "HTTP cache time to live")
 
 	// needed for DOI registration
 	rootCmd.PersistentFlags().StringP("prefix", "", "", "DOI prefix")
-	rootCmd.PersistentFlags().BoolP("development", "", false, "Development mode")
+	rootCmd.PersistentFlags().BoolP("development", "", false, "Development mode, using the Crossref and DataCite test systems")
 
 	rootCmd.PersistentFlags().StringP("login_id", "", "", "Crossref account login")
 	rootCmd.PersistentFlags().StringP("login_passwd", "", "", "Crossref account password")
@@ -81,6 +177,8 @@ func init() {
 	rootCmd.PersistentFlags().StringP("email", "", "info@front-matter.io", "Account email")
 	rootCmd.PersistentFlags().StringP("registrant", "", "", "Crossref account registrant")
 	rootCmd.PersistentFlags().StringP("host", "", "", "InvenioRDM host")
+	rootCmd.PersistentFlags().StringP("audit-log", "", audit.DefaultPath(), "audit log of registration actions, empty to disable")
+	rootCmd.PersistentFlags().StringP("mapping", "", "", "InvenioRDM community, custom field and registration mapping file, see inveniordm/mapping.example.yaml")
 	rootCmd.PersistentFlags().StringP("token", "", "", "API token")
 	rootCmd.PersistentFlags().StringP("password", "", "", "DataCite client password")
 	rootCmd.PersistentFlags().StringP("legacyKey", "", "", "Legacy API token")
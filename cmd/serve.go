@@ -0,0 +1,192 @@
+/*
+Copyright © 2025 Front Matter <info@front-matter.io>
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
+	"github.com/front-matter/commonmeta/crossrefxml"
+	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/ghost"
+	"github.com/front-matter/commonmeta/inveniordm"
+	"github.com/front-matter/commonmeta/server"
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run commonmeta as a HTTP server",
+	Long: `Run commonmeta as a HTTP server for DOI content negotiation and
+metadata conversion. GET /{doi} fetches the metadata from Crossref or
+DataCite and returns the representation chosen by the Accept header,
+e.g. application/vnd.citationstyles.csl+json or text/x-bibliography.
+application/vnd.crossref.unixref+xml returns the UNIXREF registered with
+Crossref, for Crossref DOIs only. POST /convert?from=&to= converts the
+metadata in the request body, to=crossrefxml returns a Crossref deposit.
+
+With --webhooks, POST /webhooks/{source} accepts signed webhook calls
+from ghost, inveniordm or jsonfeed when an item is published, and runs
+the registration --pipeline for the item in the background. Calls from
+inveniordm and jsonfeed sign the body followed by the Unix time given in
+the X-Signature-Timestamp header, and calls signed more than 5 minutes
+ago are rejected. The status of a call is returned by
+GET /webhooks/jobs/{id}, finished jobs are kept for a day.
+
+With --oai, the commonmeta records in a JSON or JSON lines file are served
+to harvesters by an OAI-PMH data provider at /oai, in the oai_dc and
+oai_datacite formats. Example usage:
+
+commonmeta serve --port 8080
+curl -H "Accept: text/x-bibliography" localhost:8080/10.7554/elife.01567
+commonmeta serve --webhooks --webhook-secret mysecret --pipeline crossrefxml,ghost --profile crossref-prod
+commonmeta serve --oai collection.jsonl --email info@example.org
+curl "localhost:8080/oai?verb=ListRecords&metadataPrefix=oai_dc"`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		port, _ := cmd.Flags().GetInt("port")
+		match, _ := cmd.Flags().GetBool("match")
+		depositor, _ := cmd.Flags().GetString("depositor")
+		email, _ := cmd.Flags().GetString("email")
+		registrant, _ := cmd.Flags().GetString("registrant")
+
+		webhooks, _ := cmd.Flags().GetBool("webhooks")
+		secret, _ := cmd.Flags().GetString("webhook-secret")
+		steps, _ := cmd.Flags().GetStringSlice("pipeline")
+		workers, _ := cmd.Flags().GetInt("workers")
+		fromHost, _ := cmd.Flags().GetString("from-host")
+		oai, _ := cmd.Flags().GetString("oai")
+
+		cmd.SetOut(os.Stdout)
+		cmd.SetErr(os.Stderr)
+
+		var records []commonmeta.Data
+		if oai != "" {
+			var err error
+			records, err = commonmeta.LoadAll(oai)
+			if err != nil {
+				exitWithError(cmd, fmt.Errorf("%w: file %s: %v", commonmeta.ErrValidation, oai, err))
+				return
+			}
+			if records == nil {
+				records = []commonmeta.Data{}
+			}
+		}
+
+		var pipeline []server.Step
+		if webhooks {
+			if secret == "" {
+				exitWithError(cmd, usageError("Please provide a webhook secret"))
+				return
+			}
+			var err error
+			pipeline, err = webhookPipeline(cmd, steps)
+			if err != nil {
+				exitWithError(cmd, err)
+				return
+			}
+		}
+
+		handler := server.New(server.Options{
+			Match:          match,
+			Depositor:      depositor,
+			Email:          email,
+			Registrant:     registrant,
+			Webhooks:       webhooks,
+			WebhookSecret:  secret,
+			Pipeline:       pipeline,
+			Workers:        workers,
+			InvenioRDMHost: fromHost,
+			OAIRecords:     records,
+		})
+		srv := &http.Server{
+			Addr:              ":" + strconv.Itoa(port),
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Listening on %s\n", srv.Addr)
+		err := srv.ListenAndServe()
+		exitWithError(cmd, err)
+	},
+}
+
+// webhookPipeline returns the registration steps run for items published
+// via webhooks, using the credentials given as flags.
+func webhookPipeline(cmd *cobra.Command, steps []string) ([]server.Step, error) {
+	depositor, _ := cmd.Flags().GetString("depositor")
+	email, _ := cmd.Flags().GetString("email")
+	registrant, _ := cmd.Flags().GetString("registrant")
+	loginID, _ := cmd.Flags().GetString("login_id")
+	loginPasswd, _ := cmd.Flags().GetString("login_passwd")
+	development, _ := cmd.Flags().GetBool("development")
+	host, _ := cmd.Flags().GetString("host")
+	fromHost, _ := cmd.Flags().GetString("from-host")
+	token, _ := cmd.Flags().GetString("token")
+	legacyKey, _ := cmd.Flags().GetString("legacyKey")
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	apiURL, _ := cmd.Flags().GetString("api-url")
+
+	var pipeline []server.Step
+	for _, step := range steps {
+		switch step {
+		case "crossrefxml":
+			account := crossrefxml.Account{
+				Depositor:   depositor,
+				Email:       email,
+				Registrant:  registrant,
+				LoginID:     loginID,
+				LoginPasswd: loginPasswd,
+				Development: development,
+			}
+			pipeline = append(pipeline, server.Step{Name: step, Run: func(data commonmeta.Data) (string, error) {
+				record, err := crossrefxml.Upsert(commonmeta.APIResponse{}, account, legacyKey, data)
+				return record.Status, err
+			}})
+		case "inveniordm":
+			if host == "" || token == "" {
+				return nil, usageError("Please provide an inveniordm host and token")
+			}
+			client := inveniordm.NewClient(rate.NewLimiter(rate.Every(100*time.Millisecond), 100), host)
+			pipeline = append(pipeline, server.Step{Name: step, Run: func(data commonmeta.Data) (string, error) {
+				record, err := inveniordm.Upsert(commonmeta.APIResponse{}, data, token, inveniordm.UpsertOptions{
+					FromHost:  fromHost,
+					LegacyKey: legacyKey,
+				}, client)
+				return record.Status, err
+			}})
+		case "ghost":
+			if apiKey == "" || apiURL == "" {
+				return nil, usageError("Please provide a Ghost API key and URL")
+			}
+			pipeline = append(pipeline, server.Step{Name: step, Run: func(data commonmeta.Data) (string, error) {
+				doi, ok := doiutils.ValidateDOI(data.ID)
+				if !ok {
+					return "", fmt.Errorf("%w: missing DOI", commonmeta.ErrValidation)
+				}
+				return ghost.UpdateGhostPost(config.GetEndpoints().RogueScholarAPI+"/posts/"+doi, apiKey, apiURL)
+			}})
+		default:
+			return nil, usageError("Please provide a valid pipeline step: " + step)
+		}
+	}
+	return pipeline, nil
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().IntP("port", "", 8080, "port to listen on")
+	serveCmd.Flags().BoolP("webhooks", "", false, "accept webhook calls for published items")
+	serveCmd.Flags().StringP("webhook-secret", "", "", "shared secret for webhook signatures")
+	serveCmd.Flags().StringSliceP("pipeline", "", []string{"crossrefxml"}, "registration steps for published items: crossrefxml, inveniordm and ghost")
+	serveCmd.Flags().IntP("workers", "", 1, "number of webhook calls processed concurrently")
+	serveCmd.Flags().StringP("oai", "", "", "commonmeta file served by an OAI-PMH data provider at /oai")
+}
@@ -4,12 +4,13 @@ Copyright © 2025 Front Matter <info@front-matter.io>
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/front-matter/commonmeta/audit"
+	"github.com/front-matter/commonmeta/commonmeta"
 	"github.com/front-matter/commonmeta/inveniordm"
+	"github.com/front-matter/commonmeta/ror"
 	"github.com/spf13/cobra"
 	"golang.org/x/time/rate"
 )
@@ -18,9 +19,12 @@ import (
 var setupCmd = &cobra.Command{
 	Use:   "setup",
 	Short: "setup metadata",
-	Long: `Setup metadata, currently only InvenioRDM is supported. Example usage:
+	Long: `Setup metadata, currently only InvenioRDM is supported. The sync_vocabularies
+action creates or updates the affiliations, funders and awards vocabularies,
+using the ROR file written by list --vocabulary. Example usage:
 
-	commonmeta setup --to inveniordm --host example.org --token mytoken`,
+	commonmeta setup --to inveniordm --host example.org --token mytoken
+	commonmeta setup --action sync_vocabularies --file affiliations_ror.yaml --host example.org --token mytoken`,
 	Run: func(cmd *cobra.Command, args []string) {
 		var err error
 		var output []byte
@@ -32,6 +36,7 @@ var setupCmd = &cobra.Command{
 		host, _ := cmd.Flags().GetString("host")
 		token, _ := cmd.Flags().GetString("token")
 		action, _ := cmd.Flags().GetString("action")
+		file, _ := cmd.Flags().GetString("file")
 
 		if to == "" || to == "commonmeta" {
 			to = "inveniordm"
@@ -42,10 +47,10 @@ var setupCmd = &cobra.Command{
 
 		if to == "inveniordm" {
 			if host == "" || token == "" {
-				fmt.Println("Please provide an inveniordm host and token")
+				exitWithError(cmd, usageError("Please provide an inveniordm host and token"))
 				return
 			}
-			rl := rate.NewLimiter(rate.Every(10*time.Second), 100)
+			rl := rate.NewLimiter(rate.Every(100*time.Millisecond), 100)
 			client := inveniordm.NewClient(rl, host)
 			oldClient := inveniordm.NewClient(rl, fromHost)
 			switch action {
@@ -55,20 +60,29 @@ var setupCmd = &cobra.Command{
 				output, err = inveniordm.TransferCommunities("blog", token, fromToken, oldClient, client)
 			case "transfer_topic_communities":
 				output, err = inveniordm.TransferCommunities("topic", token, fromToken, oldClient, client)
+			case "sync_vocabularies":
+				if file == "" {
+					file = "affiliations_ror.yaml"
+				}
+				var affiliations []ror.ROR
+				affiliations, err = ror.LoadAll(file)
+				if err != nil {
+					exitWithError(cmd, fmt.Errorf("%w: %s: %w", commonmeta.ErrNotFound, file, err))
+					return
+				}
+				output, err = inveniordm.SyncVocabularies(affiliations, token, client)
+			default:
+				exitWithError(cmd, usageError("Please provide a valid action"))
+				return
 			}
+			audit.Record(action, to, host, output, "", err)
 			if err != nil {
-				cmd.PrintErr(err)
+				exitWithError(cmd, err)
 				return
 			}
 		}
 
-		var out bytes.Buffer
-		json.Indent(&out, output, "", "  ")
-		cmd.Println(out.String())
-
-		if err != nil {
-			cmd.PrintErr(err)
-		}
+		printOutput(cmd, output, err)
 	},
 }
 
@@ -0,0 +1,73 @@
+/*
+Copyright © 2025 Front Matter <info@front-matter.io>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/front-matter/commonmeta/crossrefxml"
+	"github.com/spf13/cobra"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Check the status of a Crossref deposit",
+	Long: `Check the status of a Crossref deposit. Crossref processes deposits
+asynchronously, the status of each DOI is taken from the submission log.
+Example usage:
+
+commonmeta status --batch 7f0c2fbc-1a45-4d5a-a7a8-5d7a4f0e9b2a --profile crossref-prod`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		batch, _ := cmd.Flags().GetString("batch")
+		loginID, _ := cmd.Flags().GetString("login_id")
+		loginPasswd, _ := cmd.Flags().GetString("login_passwd")
+		development, _ := cmd.Flags().GetBool("development")
+
+		cmd.SetOut(os.Stdout)
+		cmd.SetErr(os.Stderr)
+
+		if batch == "" && len(args) > 0 {
+			batch = args[0]
+		}
+		if batch == "" {
+			exitWithError(cmd, usageError("Please provide a doi_batch_id"))
+			return
+		}
+		if loginID == "" || loginPasswd == "" {
+			exitWithError(cmd, usageError("Please provide a Crossref login_id and login_passwd"))
+			return
+		}
+
+		account := crossrefxml.Account{
+			LoginID:     loginID,
+			LoginPasswd: loginPasswd,
+			Development: development,
+		}
+		records, err := crossrefxml.Status(batch, account)
+		if err == nil {
+			var failed int
+			for _, record := range records {
+				if record.Status == "failed" {
+					failed++
+				}
+			}
+			if failed > 0 {
+				err = fmt.Errorf("%d of %d records failed", failed, len(records))
+			}
+		}
+
+		output, _ := json.Marshal(records)
+		printOutput(cmd, output, err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringP("batch", "", "", "doi_batch_id of the Crossref deposit")
+}
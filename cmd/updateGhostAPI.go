@@ -20,7 +20,7 @@ This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
-			cmd.PrintErr("Please provide an input")
+			exitWithError(cmd, usageError("Please provide an input"))
 			return
 		}
 		id := args[0]
@@ -28,7 +28,8 @@ to quickly create a Cobra application.`,
 		apiURL, _ := cmd.Flags().GetString("api-url")
 		output, err := ghost.UpdateGhostPost(id, apiKey, apiURL)
 		if err != nil {
-			cmd.PrintErr(err)
+			exitWithError(cmd, err)
+			return
 		}
 		rootCmd.Println(output)
 	},
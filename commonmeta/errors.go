@@ -0,0 +1,17 @@
+package commonmeta
+
+import (
+	"errors"
+
+	"github.com/front-matter/commonmeta/httputils"
+)
+
+// Sentinel errors returned by commonmeta readers and writers, use errors.Is
+// to check for them. The HTTP-related errors are defined in httputils, so that
+// low-level packages can return them.
+var (
+	ErrNotFound     = httputils.ErrNotFound
+	ErrUnauthorized = httputils.ErrUnauthorized
+	ErrRateLimited  = httputils.ErrRateLimited
+	ErrValidation   = errors.New("validation failed")
+)
@@ -416,6 +416,8 @@ type APIResponse struct {
 	Updated     string `json:"updated,omitempty"`
 	Timestamp   string `json:"timestamp,omitempty"`
 	Status      string `json:"status,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Error       string `json:"error,omitempty"`
 }
 
 // CMToSOMappings maps Commonmeta types to Schema.org types.
@@ -514,12 +516,12 @@ func Load(filename string) (Data, error) {
 	return data, nil
 }
 
-// LoadAll loads a list of commonmeta metadata from a JSON string and returns Commonmeta metadata.
+// LoadAll loads a list of commonmeta metadata from a JSON or JSON lines file and returns Commonmeta metadata.
 func LoadAll(filename string) ([]Data, error) {
 	var data []Data
 
 	extension := path.Ext(filename)
-	if extension != ".json" {
+	if extension != ".json" && extension != ".jsonl" && extension != ".jsonlines" {
 		return data, errors.New("invalid file extension")
 	}
 	file, err := os.Open(filename)
@@ -529,9 +531,23 @@ func LoadAll(filename string) ([]Data, error) {
 	defer file.Close()
 
 	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&data)
-	if err != nil {
-		return data, err
+	if extension == ".json" {
+		err = decoder.Decode(&data)
+		if err != nil {
+			return data, err
+		}
+		return data, nil
+	}
+	// JSON lines, one record per line
+	for {
+		var item Data
+		err = decoder.Decode(&item)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return data, err
+		}
+		data = append(data, item)
 	}
 	return data, nil
 }
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of the commonmeta configuration file, e.g.
+//
+//	profile: crossref-prod
+//	endpoints:
+//	  crossref_api: http://localhost:8080
+//	profiles:
+//	  crossref-prod:
+//	    login_id: front-matter
+//	    login_passwd: secret
+//	    prefix: 10.59350
+//	  datacite-test:
+//	    client: FRONTMATTER.TEST
+//	    password: secret
+//	    development: true
+//
+// Profile settings are keyed by command line flag name.
+type Config struct {
+	Profile   string             `yaml:"profile,omitempty"`
+	Endpoints Endpoints          `yaml:"endpoints,omitempty"`
+	Profiles  map[string]Profile `yaml:"profiles,omitempty"`
+}
+
+// Profile is a named set of settings, e.g. credentials, hosts and prefixes.
+type Profile map[string]string
+
+// EnvPrefix is the prefix of environment variables overriding settings,
+// e.g. COMMONMETA_LOGIN_PASSWD for the login_passwd setting.
+const EnvPrefix = "COMMONMETA_"
+
+// ErrProfileNotFound is returned when a profile is not defined in the configuration file.
+var ErrProfileNotFound = errors.New("profile not found")
+
+// DefaultPath returns the path of the configuration file, set via the
+// COMMONMETA_CONFIG environment variable or ~/.config/commonmeta/config.yaml.
+func DefaultPath() string {
+	if path := os.Getenv(EnvPrefix + "CONFIG"); path != "" {
+		return path
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "commonmeta", "config.yaml")
+}
+
+// Load reads the configuration file at path. A missing file is not an error
+// and returns an empty configuration.
+func Load(path string) (Config, error) {
+	var c Config
+	if path == "" {
+		return c, nil
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return c, err
+	}
+	err = yaml.Unmarshal(b, &c)
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// GetProfile returns the settings of the named profile, or of the default
+// profile of the configuration file if name is empty.
+func (c Config) GetProfile(name string) (Profile, error) {
+	if name == "" {
+		name = c.Profile
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	return p, nil
+}
+
+// ProfileNames returns the sorted names of all profiles.
+func (c Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// EnvName returns the environment variable overriding a setting,
+// e.g. COMMONMETA_FROM_HOST for from-host.
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// Lookup returns the value of a setting, from the environment or the profile.
+// Environment variables take precedence.
+func (p Profile) Lookup(name string) (string, bool) {
+	if v, ok := os.LookupEnv(EnvName(name)); ok {
+		return v, true
+	}
+	v, ok := p[name]
+	return v, ok
+}
@@ -0,0 +1,97 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/front-matter/commonmeta/config"
+)
+
+const testConfig = `profile: rogue-scholar
+endpoints:
+  crossref_sandbox: http://localhost:9000/
+profiles:
+  rogue-scholar:
+    host: rogue-scholar.org
+    token: secret
+  datacite-test:
+    client: FRONTMATTER.TEST
+    password: secret
+    development: true
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(testConfig), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("COMMONMETA_PASSWORD", "from-env")
+
+	c, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Endpoints.CrossrefSandbox != "http://localhost:9000/" {
+		t.Errorf("Load(endpoints): want http://localhost:9000/, got %v", c.Endpoints.CrossrefSandbox)
+	}
+
+	type testCase struct {
+		profile string
+		name    string
+		want    string
+		ok      bool
+	}
+	testCases := []testCase{
+		{profile: "", name: "host", want: "rogue-scholar.org", ok: true},
+		{profile: "datacite-test", name: "client", want: "FRONTMATTER.TEST", ok: true},
+		{profile: "datacite-test", name: "development", want: "true", ok: true},
+		{profile: "datacite-test", name: "password", want: "from-env", ok: true},
+		{profile: "datacite-test", name: "host", want: "", ok: false},
+	}
+	for _, tc := range testCases {
+		p, err := c.GetProfile(tc.profile)
+		if err != nil {
+			t.Errorf("GetProfile(%v): error %v", tc.profile, err)
+			continue
+		}
+		got, ok := p.Lookup(tc.name)
+		if tc.want != got || tc.ok != ok {
+			t.Errorf("Lookup(%v, %v): want %v, got %v", tc.profile, tc.name, tc.want, got)
+		}
+	}
+
+	_, err = c.GetProfile("crossref-prod")
+	if !errors.Is(err, config.ErrProfileNotFound) {
+		t.Errorf("GetProfile(crossref-prod): want %v, got %v", config.ErrProfileNotFound, err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	t.Parallel()
+	c, err := config.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || len(c.Profiles) != 0 {
+		t.Errorf("Load(missing): want empty config, got %v, error %v", c, err)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	t.Parallel()
+	type testCase struct {
+		input string
+		want  string
+	}
+	testCases := []testCase{
+		{input: "login_passwd", want: "COMMONMETA_LOGIN_PASSWD"},
+		{input: "from-host", want: "COMMONMETA_FROM_HOST"},
+		{input: "legacyKey", want: "COMMONMETA_LEGACYKEY"},
+	}
+	for _, tc := range testCases {
+		got := config.EnvName(tc.input)
+		if tc.want != got {
+			t.Errorf("EnvName(%v): want %v, got %v", tc.input, tc.want, got)
+		}
+	}
+}
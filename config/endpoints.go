@@ -0,0 +1,135 @@
+// Package config provides the commonmeta configuration, e.g. the base URLs of the
+// services commonmeta reads from and registers with.
+package config
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// Endpoints are the base URLs of the services used by commonmeta, without trailing slash.
+// They can be overridden, e.g. to use sandboxes or local stand-ins for testing.
+type Endpoints struct {
+	CrossrefAPI     string `yaml:"crossref_api,omitempty" json:"crossref_api,omitempty"`
+	CrossrefDeposit string `yaml:"crossref_deposit,omitempty" json:"crossref_deposit,omitempty"`
+	CrossrefSandbox string `yaml:"crossref_sandbox,omitempty" json:"crossref_sandbox,omitempty"`
+	DataCiteAPI     string `yaml:"datacite_api,omitempty" json:"datacite_api,omitempty"`
+	DataCiteSandbox string `yaml:"datacite_sandbox,omitempty" json:"datacite_sandbox,omitempty"`
+	RORAPI          string `yaml:"ror_api,omitempty" json:"ror_api,omitempty"`
+	OpenAlexAPI     string `yaml:"openalex_api,omitempty" json:"openalex_api,omitempty"`
+	DOIResolver     string `yaml:"doi_resolver,omitempty" json:"doi_resolver,omitempty"`
+	RogueScholarAPI string `yaml:"rogue_scholar_api,omitempty" json:"rogue_scholar_api,omitempty"`
+}
+
+// DefaultEndpoints are the production endpoints of the services used by commonmeta.
+var DefaultEndpoints = Endpoints{
+	CrossrefAPI:     "https://api.crossref.org",
+	CrossrefDeposit: "https://doi.crossref.org",
+	CrossrefSandbox: "https://test.crossref.org",
+	DataCiteAPI:     "https://api.datacite.org",
+	DataCiteSandbox: "https://api.test.datacite.org",
+	RORAPI:          "https://api.ror.org",
+	OpenAlexAPI:     "https://api.openalex.org",
+	DOIResolver:     "https://doi.org",
+	RogueScholarAPI: "https://api.rogue-scholar.org",
+}
+
+// EndpointEnvVars maps environment variables to the endpoints they override.
+var EndpointEnvVars = map[string]func(*Endpoints) *string{
+	"COMMONMETA_CROSSREF_API_URL":      func(e *Endpoints) *string { return &e.CrossrefAPI },
+	"COMMONMETA_CROSSREF_DEPOSIT_URL":  func(e *Endpoints) *string { return &e.CrossrefDeposit },
+	"COMMONMETA_CROSSREF_SANDBOX_URL":  func(e *Endpoints) *string { return &e.CrossrefSandbox },
+	"COMMONMETA_DATACITE_API_URL":      func(e *Endpoints) *string { return &e.DataCiteAPI },
+	"COMMONMETA_DATACITE_SANDBOX_URL":  func(e *Endpoints) *string { return &e.DataCiteSandbox },
+	"COMMONMETA_ROR_API_URL":           func(e *Endpoints) *string { return &e.RORAPI },
+	"COMMONMETA_OPENALEX_API_URL":      func(e *Endpoints) *string { return &e.OpenAlexAPI },
+	"COMMONMETA_DOI_RESOLVER_URL":      func(e *Endpoints) *string { return &e.DOIResolver },
+	"COMMONMETA_ROGUE_SCHOLAR_API_URL": func(e *Endpoints) *string { return &e.RogueScholarAPI },
+}
+
+var (
+	mu        sync.RWMutex
+	endpoints *Endpoints
+)
+
+// GetEndpoints returns the current endpoints: the defaults, overridden by
+// SetEndpoints and by COMMONMETA_*_URL environment variables.
+func GetEndpoints() Endpoints {
+	mu.RLock()
+	if endpoints != nil {
+		defer mu.RUnlock()
+		return *endpoints
+	}
+	mu.RUnlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if endpoints == nil {
+		e := DefaultEndpoints
+		e.merge(envEndpoints())
+		endpoints = &e
+	}
+	return *endpoints
+}
+
+// SetEndpoints overrides the endpoints with all non-empty values in e.
+// Environment variables still take precedence.
+func SetEndpoints(e Endpoints) {
+	mu.Lock()
+	defer mu.Unlock()
+	current := DefaultEndpoints
+	if endpoints != nil {
+		current = *endpoints
+	}
+	current.merge(e)
+	current.merge(envEndpoints())
+	endpoints = &current
+}
+
+// ResetEndpoints restores the default endpoints, overridden by environment variables.
+func ResetEndpoints() {
+	mu.Lock()
+	defer mu.Unlock()
+	endpoints = nil
+}
+
+// CrossrefDepositURL returns the base URL for Crossref deposits, using the
+// Crossref sandbox if sandbox is true.
+func CrossrefDepositURL(sandbox bool) string {
+	e := GetEndpoints()
+	if sandbox {
+		return e.CrossrefSandbox
+	}
+	return e.CrossrefDeposit
+}
+
+// DataCiteURL returns the base URL for the DataCite REST API, using the
+// DataCite test system if sandbox is true.
+func DataCiteURL(sandbox bool) string {
+	e := GetEndpoints()
+	if sandbox {
+		return e.DataCiteSandbox
+	}
+	return e.DataCiteAPI
+}
+
+// merge overrides the endpoints with all non-empty values in o.
+func (e *Endpoints) merge(o Endpoints) {
+	for _, field := range EndpointEnvVars {
+		if v := *field(&o); v != "" {
+			*field(e) = strings.TrimSuffix(v, "/")
+		}
+	}
+}
+
+// envEndpoints returns the endpoints set via environment variables.
+func envEndpoints() Endpoints {
+	var e Endpoints
+	for name, field := range EndpointEnvVars {
+		if v := os.Getenv(name); v != "" {
+			*field(&e) = v
+		}
+	}
+	return e
+}
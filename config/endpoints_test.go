@@ -0,0 +1,44 @@
+package config_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/front-matter/commonmeta/config"
+)
+
+func TestGetEndpoints(t *testing.T) {
+	t.Setenv("COMMONMETA_ROR_API_URL", "http://localhost:8080/")
+	config.ResetEndpoints()
+	defer config.ResetEndpoints()
+
+	config.SetEndpoints(config.Endpoints{
+		CrossrefSandbox: "http://localhost:9000",
+		RORAPI:          "http://localhost:9001",
+	})
+	got := config.GetEndpoints()
+	type testCase struct {
+		name string
+		want string
+		got  string
+	}
+	testCases := []testCase{
+		{name: "default", want: "https://api.crossref.org", got: got.CrossrefAPI},
+		{name: "set", want: "http://localhost:9000", got: got.CrossrefSandbox},
+		{name: "environment takes precedence", want: "http://localhost:8080", got: got.RORAPI},
+		{name: "crossref sandbox", want: "http://localhost:9000", got: config.CrossrefDepositURL(true)},
+		{name: "crossref production", want: "https://doi.crossref.org", got: config.CrossrefDepositURL(false)},
+	}
+	for _, tc := range testCases {
+		if tc.want != tc.got {
+			t.Errorf("GetEndpoints(%v): want %v, got %v", tc.name, tc.want, tc.got)
+		}
+	}
+}
+
+func ExampleDataCiteURL() {
+	s := config.DataCiteURL(true)
+	fmt.Println(s)
+	// Output:
+	// https://api.test.datacite.org
+}
@@ -17,13 +17,14 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	"mvdan.cc/xurls/v2"
 
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/dateutils"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/ror"
 	"github.com/front-matter/commonmeta/utils"
 )
@@ -282,7 +283,7 @@ func Fetch(str string, match bool) (commonmeta.Data, error) {
 	var data commonmeta.Data
 	id, ok := doiutils.ValidateDOI(str)
 	if !ok {
-		return data, errors.New("invalid DOI")
+		return data, fmt.Errorf("%w: invalid DOI", commonmeta.ErrValidation)
 	}
 	content, err := Get(id)
 	if err != nil {
@@ -323,27 +324,23 @@ func Get(pid string) (Content, error) {
 	var response Response
 	doi, ok := doiutils.ValidateDOI(pid)
 	if !ok {
-		return response.Message, errors.New("invalid DOI")
+		return response.Message, fmt.Errorf("%w: invalid DOI", commonmeta.ErrValidation)
 	}
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	url := "https://api.crossref.org/works/" + doi
+	client := httputils.Client()
+	url := config.GetEndpoints().CrossrefAPI + "/works/" + doi
 	req, err := http.NewRequest(http.MethodGet, url, nil)
-	u := "info@front-matter.io"
-	userAgent := fmt.Sprintf("commonmeta/%s (https://commonmeta.org/; mailto: %s)", commonmeta.Version, u)
-	req.Header.Set("User-Agent", userAgent)
 	if err != nil {
-		log.Fatalln(err)
+		return response.Message, err
 	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return response.Message, err
 	}
-	if resp.StatusCode >= 400 {
-		return response.Message, errors.New(resp.Status)
-	}
 	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return response.Message, err
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return response.Message, err
@@ -371,26 +368,22 @@ func GetAll(number int, page int, member string, type_ string, sample bool, year
 	if number > 100 {
 		number = 1000
 	}
-	client := &http.Client{
-		Timeout: 20 * time.Second,
-	}
+	client := httputils.Client()
 	url := QueryURL(number, page, member, type_, sample, year, orcid, ror, hasORCID, hasROR, hasReferences, hasRelation, hasAbstract, hasAward, hasLicense, hasArchive)
 	req, err := http.NewRequest(http.MethodGet, url, nil)
-	u := "info@front-matter.io"
-	userAgent := fmt.Sprintf("commonmeta/%s (https://commonmeta.org; mailto: %s)", commonmeta.Version, u)
-	req.Header.Set("User-Agent", userAgent)
-	req.Header.Set("Cache-Control", "private")
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
+	req.Header.Set("Cache-Control", "private")
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode >= 400 {
-		return nil, errors.New(resp.Status)
-	}
 	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return nil, err
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
@@ -865,7 +858,7 @@ func QueryURL(number int, page int, member string, type_ string, sample bool, ye
 		"standard",
 	}
 
-	u, _ := url.Parse("https://api.crossref.org/works")
+	u, _ := url.Parse(config.GetEndpoints().CrossrefAPI + "/works")
 	values := u.Query()
 	if number <= 0 {
 		number = 10
@@ -951,10 +944,11 @@ func GetMember(memberId string) (string, bool) {
 	if memberId == "" {
 		return "", false
 	}
-	resp, err := http.Get(fmt.Sprintf("https://api.crossref.org/members/%s", memberId))
+	resp, err := httputils.Client().Get(fmt.Sprintf("%s/members/%s", config.GetEndpoints().CrossrefAPI, memberId))
 	if err != nil {
 		return "", false
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode == 404 {
 		return "", false
 	}
@@ -962,7 +956,6 @@ func GetMember(memberId string) (string, bool) {
 	if err != nil {
 		return "", false
 	}
-	defer resp.Body.Close()
 	err = json.Unmarshal(body, &result)
 	if err != nil {
 		return "", false
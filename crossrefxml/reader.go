@@ -12,11 +12,12 @@ import (
 	"path"
 	"slices"
 	"strings"
-	"time"
 
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/dateutils"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/ror"
 	"github.com/front-matter/commonmeta/utils"
 )
@@ -707,7 +708,7 @@ func Fetch(str string) (commonmeta.Data, error) {
 	var data commonmeta.Data
 	id, ok := doiutils.ValidateDOI(str)
 	if !ok {
-		return data, errors.New("invalid DOI")
+		return data, fmt.Errorf("%w: invalid DOI", commonmeta.ErrValidation)
 	}
 	content, err := Get(id)
 	if err != nil {
@@ -747,41 +748,53 @@ func Get(pid string) (Query, error) {
 		Xsi:            "http://www.w3.org/2001/XMLSchema-instance",
 		SchemaLocation: "http://www.crossref.org/qrschema/3.0 http://www.crossref.org/qrschema/crossref_query_output3.0.xsd",
 	}
+	body, err := GetUnixsd(pid)
+	if err != nil {
+		return query, err
+	}
+	err = xml.Unmarshal(body, &crossrefResult)
+	if err != nil {
+		fmt.Println("error:", err)
+	}
+	query = crossrefResult.QueryResult.Body.Query
+	return query, err
+}
+
+// GetUnixsd gets the metadata for a single work from the Crossref API in the
+// unixsd format, as registered with Crossref.
+func GetUnixsd(pid string) ([]byte, error) {
+	return getTransform(pid, "application/vnd.crossref.unixsd+xml")
+}
+
+// GetUnixref gets the metadata for a single work from the Crossref API in
+// the UNIXREF format, as registered with Crossref.
+func GetUnixref(pid string) ([]byte, error) {
+	return getTransform(pid, "application/vnd.crossref.unixref+xml")
+}
+
+// getTransform gets the metadata for a single work from the Crossref API in
+// the format of a media type.
+func getTransform(pid string, mediaType string) ([]byte, error) {
 	doi, ok := doiutils.ValidateDOI(pid)
 	if !ok {
-		return query, errors.New("invalid DOI")
+		return nil, fmt.Errorf("%w: invalid DOI", commonmeta.ErrValidation)
 	}
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	url := "https://api.crossref.org/works/" + doi + "/transform/application/vnd.crossref.unixsd+xml"
+	client := httputils.Client()
+	url := config.GetEndpoints().CrossrefAPI + "/works/" + doi + "/transform/" + mediaType
 	req, err := http.NewRequest(http.MethodGet, url, nil)
-	v := "0.1"
-	u := "info@front-matter.io"
-	userAgent := fmt.Sprintf("commonmeta/%s (https://commonmeta.org/; mailto: %s)", v, u)
-	req.Header.Set("User-Agent", userAgent)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return query, err
-	}
-	if resp.StatusCode >= 400 {
-		return query, errors.New(resp.Status)
+		return nil, err
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	err = httputils.CheckResponse(resp)
 	if err != nil {
-		fmt.Println("error:", err)
-		return query, err
+		return nil, err
 	}
-	err = xml.Unmarshal(body, &crossrefResult)
-	if err != nil {
-		fmt.Println("error:", err)
-	}
-	query = crossrefResult.QueryResult.Body.Query
-	return query, err
+	return io.ReadAll(resp.Body)
 }
 
 // Read Crossref XML response and return work struct in Commonmeta format
@@ -0,0 +1,216 @@
+package crossrefxml
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/front-matter/commonmeta/audit"
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/google/uuid"
+)
+
+// DepositTypes are the supported types of Crossref deposits: full metadata,
+// resource-only (text-mining URLs) and reference-only deposits.
+var DepositTypes = []string{"metadata", "resources", "references"}
+
+// ResourcesBatch is a Crossref deposit using the doi_resources schema, used for
+// resource-only and reference-only deposits that append or update metadata of
+// existing DOIs.
+type ResourcesBatch struct {
+	XMLName xml.Name      `xml:"doi_batch"`
+	Xmlns   string        `xml:"xmlns,attr,omitempty"`
+	Version string        `xml:"version,attr,omitempty"`
+	Head    ResourcesHead `xml:"head"`
+	Body    ResourcesBody `xml:"body"`
+}
+
+type ResourcesHead struct {
+	DOIBatchID string    `xml:"doi_batch_id"`
+	Depositor  Depositor `xml:"depositor"`
+}
+
+type ResourcesBody struct {
+	DOICitations []DOICitations `xml:"doi_citations,omitempty"`
+	DOIResources []DOIResources `xml:"doi_resources,omitempty"`
+}
+
+// DOICitations is the reference list of a DOI in a reference-only deposit.
+type DOICitations struct {
+	XMLName      xml.Name     `xml:"doi_citations"`
+	DOI          string       `xml:"doi"`
+	CitationList CitationList `xml:"citation_list"`
+}
+
+// DOIResources are the resources of a DOI in a resource-only deposit.
+type DOIResources struct {
+	XMLName    xml.Name   `xml:"doi_resources"`
+	DOI        string     `xml:"doi"`
+	Collection Collection `xml:"collection"`
+}
+
+// WriteResources writes a resource-only deposit for a list of commonmeta metadata,
+// updating the text-mining URLs of the landing page and files. Resource-only
+// deposits don't change the resolution URL of a DOI, that requires a full
+// metadata deposit. Records without a valid DOI or URL are skipped.
+func WriteResources(list []commonmeta.Data, account Account) ([]byte, error) {
+	var body ResourcesBody
+	for _, data := range list {
+		doi, ok := doiutils.ValidateDOI(data.ID)
+		if !ok || data.URL == "" {
+			continue
+		}
+		body.DOIResources = append(body.DOIResources, DOIResources{
+			DOI:        doi,
+			Collection: textMiningCollection(data),
+		})
+	}
+	if len(body.DOIResources) == 0 {
+		return nil, fmt.Errorf("%w: no resources to deposit", commonmeta.ErrValidation)
+	}
+	return writeResourcesBatch(body, account)
+}
+
+// WriteReferences writes a reference-only deposit for a list of commonmeta metadata.
+// The deposited reference list replaces the reference list of the DOI.
+// Records without a valid DOI or references are skipped.
+func WriteReferences(list []commonmeta.Data, account Account) ([]byte, error) {
+	var body ResourcesBody
+	for _, data := range list {
+		doi, ok := doiutils.ValidateDOI(data.ID)
+		if !ok {
+			continue
+		}
+		citationList := citations(data)
+		if len(citationList.Citation) == 0 {
+			continue
+		}
+		body.DOICitations = append(body.DOICitations, DOICitations{
+			DOI:          doi,
+			CitationList: citationList,
+		})
+	}
+	if len(body.DOICitations) == 0 {
+		return nil, fmt.Errorf("%w: no references to deposit", commonmeta.ErrValidation)
+	}
+	return writeResourcesBatch(body, account)
+}
+
+// ConvertResources converts commonmeta metadata into its doi_resources or
+// doi_citations element of a resource-only or reference-only deposit,
+// depending on depositType.
+func ConvertResources(data commonmeta.Data, depositType string) (any, error) {
+	doi, ok := doiutils.ValidateDOI(data.ID)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid DOI", commonmeta.ErrValidation)
+	}
+	switch depositType {
+	case "resources":
+		if data.URL == "" {
+			return nil, fmt.Errorf("%w: missing URL", commonmeta.ErrValidation)
+		}
+		return DOIResources{DOI: doi, Collection: textMiningCollection(data)}, nil
+	case "references":
+		citationList := citations(data)
+		if len(citationList.Citation) == 0 {
+			return nil, fmt.Errorf("%w: no references", commonmeta.ErrValidation)
+		}
+		return DOICitations{DOI: doi, CitationList: citationList}, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported deposit type %s", commonmeta.ErrValidation, depositType)
+}
+
+// UpsertResources uploads a resource-only or reference-only deposit for a list
+// of commonmeta metadata, depending on depositType. Records that can't be
+// deposited are returned as failed records.
+func UpsertResources(list []commonmeta.Data, account Account, depositType string) ([]commonmeta.APIResponse, error) {
+	var records []commonmeta.APIResponse
+	var output []byte
+	var err error
+	var failed []commonmeta.APIResponse
+	var valid []commonmeta.Data
+	for _, data := range list {
+		var message string
+		doi, ok := doiutils.ValidateDOI(data.ID)
+		switch {
+		case !ok:
+			message = "invalid DOI"
+		case depositType == "resources" && data.URL == "":
+			message = "missing URL"
+		case depositType == "references" && len(citations(data).Citation) == 0:
+			message = "no references"
+		}
+		if message != "" {
+			failed = append(failed, commonmeta.APIResponse{
+				DOI:     doiutils.NormalizeDOI(doi),
+				ID:      data.ID,
+				Status:  "failed",
+				Message: message,
+			})
+			continue
+		}
+		valid = append(valid, data)
+	}
+	switch depositType {
+	case "resources":
+		output, err = WriteResources(valid, account)
+	case "references":
+		output, err = WriteReferences(valid, account)
+	default:
+		return records, fmt.Errorf("%w: unsupported deposit type %s", commonmeta.ErrValidation, depositType)
+	}
+	if err != nil {
+		return failed, err
+	}
+
+	var batch ResourcesBatch
+	err = xml.Unmarshal(output, &batch)
+	if err != nil {
+		return records, err
+	}
+	for _, r := range batch.Body.DOIResources {
+		records = append(records, commonmeta.APIResponse{DOI: doiutils.NormalizeDOI(r.DOI)})
+	}
+	for _, c := range batch.Body.DOICitations {
+		records = append(records, commonmeta.APIResponse{DOI: doiutils.NormalizeDOI(c.DOI)})
+	}
+
+	err = deposit(output, account, "doDOICitUpload")
+	for i := range records {
+		if err == nil {
+			records[i].DOIBatchID = batch.Head.DOIBatchID
+			records[i].Status = "submitted"
+		} else {
+			records[i].Status = "failed"
+			records[i].Message = err.Error()
+			records[i].Error = err.Error()
+		}
+		audit.Record("upsert_"+depositType, "crossrefxml", records[i].DOI, output, records[i].Status, err)
+	}
+	return append(records, failed...), err
+}
+
+// writeResourcesBatch wraps a body in a doi_batch using the doi_resources schema.
+func writeResourcesBatch(body ResourcesBody, account Account) ([]byte, error) {
+	if account.Depositor == "" || account.Email == "" {
+		return nil, fmt.Errorf("%w: depositor and email are required", commonmeta.ErrValidation)
+	}
+	uuid, _ := uuid.NewRandom()
+	doiBatch := ResourcesBatch{
+		Xmlns:   "http://www.crossref.org/doi_resources_schema/5.4.0",
+		Version: "5.4.0",
+		Head: ResourcesHead{
+			DOIBatchID: uuid.String(),
+			Depositor: Depositor{
+				DepositorName: account.Depositor,
+				Email:         account.Email,
+			},
+		},
+		Body: body,
+	}
+	output, err := xml.MarshalIndent(doiBatch, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(xml.Header + string(output)), nil
+}
@@ -0,0 +1,157 @@
+package crossrefxml_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
+	"github.com/front-matter/commonmeta/crossrefxml"
+)
+
+func TestWriteResources(t *testing.T) {
+	t.Parallel()
+	account := crossrefxml.Account{Depositor: "Front Matter", Email: "info@front-matter.io"}
+	list := testList(t, 2)
+
+	output, err := crossrefxml.WriteResources(list, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resources crossrefxml.ResourcesBatch
+	err = xml.Unmarshal(output, &resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resources.Xmlns != "http://www.crossref.org/doi_resources_schema/5.4.0" || len(resources.Body.DOIResources) != 2 {
+		t.Errorf("WriteResources: want 2 doi_resources, got %v", len(resources.Body.DOIResources))
+	}
+	if got := resources.Body.DOIResources[0]; got.DOI != "10.59350/test-0" || got.Collection.Property != "text-mining" {
+		t.Errorf("WriteResources: want text-mining collection for 10.59350/test-0, got %v %v", got.DOI, got.Collection.Property)
+	}
+
+	output, err = crossrefxml.WriteReferences(list, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var references crossrefxml.ResourcesBatch
+	err = xml.Unmarshal(output, &references)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(references.Body.DOICitations) != 2 || len(references.Body.DOIResources) != 0 {
+		t.Errorf("WriteReferences: want 2 doi_citations, got %v", len(references.Body.DOICitations))
+	}
+	if got := len(references.Body.DOICitations[0].CitationList.Citation); got != len(list[0].References) {
+		t.Errorf("WriteReferences: want %v citations, got %v", len(list[0].References), got)
+	}
+
+	_, err = crossrefxml.WriteReferences([]commonmeta.Data{{ID: "https://doi.org/10.59350/test"}}, account)
+	if !errors.Is(err, commonmeta.ErrValidation) {
+		t.Errorf("WriteReferences(no references): want %v, got %v", commonmeta.ErrValidation, err)
+	}
+}
+
+func TestConvertResources(t *testing.T) {
+	t.Parallel()
+	data := testList(t, 1)[0]
+
+	type testCase struct {
+		input       commonmeta.Data
+		depositType string
+		want        string
+		err         error
+	}
+	testCases := []testCase{
+		{input: data, depositType: "resources", want: "<doi_resources><doi>10.59350/test-0</doi>", err: nil},
+		{input: data, depositType: "references", want: "<doi_citations><doi>10.59350/test-0</doi>", err: nil},
+		{input: commonmeta.Data{ID: "https://doi.org/10.59350/test"}, depositType: "resources", want: "", err: commonmeta.ErrValidation},
+		{input: commonmeta.Data{ID: "https://doi.org/10.59350/test"}, depositType: "references", want: "", err: commonmeta.ErrValidation},
+		{input: data, depositType: "metadata", want: "", err: commonmeta.ErrValidation},
+	}
+	for _, tc := range testCases {
+		body, err := crossrefxml.ConvertResources(tc.input, tc.depositType)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("ConvertResources(%v, %v): want %v, got %v", tc.input.ID, tc.depositType, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		output, err := xml.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(output), tc.want) {
+			t.Errorf("ConvertResources(%v, %v): want %v, got %v", tc.input.ID, tc.depositType, tc.want, string(output))
+		}
+	}
+}
+
+func TestUpsertResources(t *testing.T) {
+	var operation string
+	var output []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("login_passwd") == "invalid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		operation = r.FormValue("operation")
+		file, _, err := r.FormFile("fname")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		output, _ = io.ReadAll(file)
+		w.Write([]byte(`<html><head><title>SUCCESS</title></head><body><h2>SUCCESS</h2></body></html>`))
+	}))
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{CrossrefSandbox: ts.URL})
+	defer config.ResetEndpoints()
+
+	account := crossrefxml.Account{Depositor: "Front Matter", Email: "info@front-matter.io", Development: true}
+	records, err := crossrefxml.UpsertResources(testList(t, 3), account, "references")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operation != "doDOICitUpload" {
+		t.Errorf("UpsertResources: want operation doDOICitUpload, got %v", operation)
+	}
+	if len(records) != 3 || records[0].Status != "submitted" || records[0].DOIBatchID != crossrefxml.DOIBatchID(output) {
+		t.Errorf("UpsertResources: want 3 submitted records, got %v", records)
+	}
+
+	// records without a valid DOI or URL are returned as failed
+	list := testList(t, 3)
+	list[1].URL = ""
+	list[2].ID = "https://example.org/not-a-doi"
+	records, err = crossrefxml.UpsertResources(list, account, "resources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for _, record := range records {
+		statuses = append(statuses, record.Status+" "+record.Message)
+	}
+	want := "submitted |failed missing URL|failed invalid DOI"
+	if want != strings.Join(statuses, "|") {
+		t.Errorf("UpsertResources(resources): want %v, got %v", want, strings.Join(statuses, "|"))
+	}
+
+	// a failed deposit fails all its records
+	account.LoginPasswd = "invalid"
+	records, err = crossrefxml.UpsertResources(testList(t, 2), account, "resources")
+	if err == nil {
+		t.Fatal("UpsertResources(failed deposit): want an error, got nil")
+	}
+	for _, record := range records {
+		if record.Status != "failed" || record.Error == "" || record.DOIBatchID != "" {
+			t.Errorf("UpsertResources(failed deposit): want failed, got %v", record)
+		}
+	}
+}
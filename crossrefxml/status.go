@@ -0,0 +1,184 @@
+package crossrefxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
+	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
+)
+
+// Diagnostic is the submission log of a Crossref deposit, returned by the
+// submissionDownload servlet once the deposit has been processed.
+type Diagnostic struct {
+	XMLName      xml.Name           `xml:"doi_batch_diagnostic"`
+	Status       string             `xml:"status,attr"`
+	SubmissionID string             `xml:"submission_id"`
+	BatchID      string             `xml:"batch_id"`
+	Records      []RecordDiagnostic `xml:"record_diagnostic"`
+	BatchData    BatchData          `xml:"batch_data"`
+}
+
+// RecordDiagnostic is the result of a deposit for a single DOI.
+type RecordDiagnostic struct {
+	Status  string `xml:"status,attr"`
+	MsgID   string `xml:"msg_id,attr,omitempty"`
+	DOI     string `xml:"doi"`
+	Message string `xml:"msg"`
+}
+
+// BatchData summarizes the results of a deposit.
+type BatchData struct {
+	RecordCount  int `xml:"record_count"`
+	SuccessCount int `xml:"success_count"`
+	WarningCount int `xml:"warning_count"`
+	FailureCount int `xml:"failure_count"`
+}
+
+// DiagnosticToStatusMappings maps the status of a record diagnostic to the
+// status of a commonmeta.APIResponse.
+var DiagnosticToStatusMappings = map[string]string{
+	"Success": "registered",
+	"Warning": "registered_with_warning",
+	"Failure": "failed",
+}
+
+// GetStatus fetches the submission log of a Crossref deposit by doi_batch_id.
+func GetStatus(doiBatchID string, account Account) (Diagnostic, error) {
+	var diagnostic Diagnostic
+
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	w.WriteField("usr", account.LoginID)
+	w.WriteField("pwd", account.LoginPasswd)
+	w.WriteField("doi_batch_id", doiBatchID)
+	w.WriteField("type", "result")
+	w.Close()
+
+	// POST keeps the credentials out of the URL and the HTTP cache
+	postUrl := config.CrossrefDepositURL(account.Development) + "/servlet/submissionDownload"
+	req, err := http.NewRequest(http.MethodPost, postUrl, bytes.NewReader(b.Bytes()))
+	if err != nil {
+		return diagnostic, err
+	}
+	req.Header.Add("Content-Type", w.FormDataContentType())
+	client := httputils.NewClient(60 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return diagnostic, err
+	}
+	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return diagnostic, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return diagnostic, err
+	}
+	return ParseDiagnostic(body)
+}
+
+// ParseDiagnostic parses a doi_batch_diagnostic XML document.
+func ParseDiagnostic(body []byte) (Diagnostic, error) {
+	var diagnostic Diagnostic
+	err := xml.Unmarshal(body, &diagnostic)
+	if err != nil {
+		return diagnostic, fmt.Errorf("error parsing submission log: %w", err)
+	}
+	if diagnostic.Status == "unknown_submission" {
+		return diagnostic, fmt.Errorf("%w: submission %s", commonmeta.ErrNotFound, diagnostic.BatchID)
+	}
+	return diagnostic, nil
+}
+
+// UpdateStatus updates the status of records with the results of a deposit,
+// matching records by DOI. Records of deposits that are still queued or being
+// processed keep their status. Records not in the list are added.
+func UpdateStatus(records []commonmeta.APIResponse, diagnostic Diagnostic) []commonmeta.APIResponse {
+	for _, d := range diagnostic.Records {
+		status, ok := DiagnosticToStatusMappings[d.Status]
+		if !ok {
+			continue
+		}
+		idx := slices.IndexFunc(records, func(r commonmeta.APIResponse) bool {
+			doi, _ := doiutils.ValidateDOI(r.DOI)
+			return strings.EqualFold(doi, d.DOI)
+		})
+		if idx == -1 {
+			records = append(records, commonmeta.APIResponse{DOI: doiutils.NormalizeDOI(d.DOI)})
+			idx = len(records) - 1
+		}
+		records[idx].DOIBatchID = diagnostic.BatchID
+		records[idx].Status = status
+		records[idx].Message = strings.TrimSpace(d.Message)
+	}
+	return records
+}
+
+// Status returns the status of all records in a Crossref deposit. While the
+// deposit is queued or being processed, a single record with the status of
+// the deposit is returned.
+func Status(doiBatchID string, account Account) ([]commonmeta.APIResponse, error) {
+	var records []commonmeta.APIResponse
+	diagnostic, err := GetStatus(doiBatchID, account)
+	if err != nil {
+		return records, err
+	}
+	if diagnostic.Status != "completed" {
+		return []commonmeta.APIResponse{{DOIBatchID: doiBatchID, Status: diagnostic.Status}}, nil
+	}
+	return UpdateStatus(records, diagnostic), nil
+}
+
+// PollStatus polls the submission logs of all deposits referenced in records
+// every interval, until all deposits have been processed or the timeout is
+// reached, and updates the records with the result for each DOI. Deposits
+// unknown to Crossref are not yet visible in the submission queue and are
+// polled again.
+func PollStatus(records []commonmeta.APIResponse, account Account, interval time.Duration, timeout time.Duration) ([]commonmeta.APIResponse, error) {
+	var pending []string
+	for _, record := range records {
+		if record.DOIBatchID != "" && !slices.Contains(pending, record.DOIBatchID) {
+			pending = append(pending, record.DOIBatchID)
+		}
+	}
+	deadline := time.Now().Add(timeout)
+	for len(pending) > 0 {
+		var queued []string
+		for _, doiBatchID := range pending {
+			diagnostic, err := GetStatus(doiBatchID, account)
+			if errors.Is(err, commonmeta.ErrNotFound) {
+				queued = append(queued, doiBatchID)
+				continue
+			}
+			if err != nil {
+				return records, err
+			}
+			if diagnostic.Status != "completed" {
+				queued = append(queued, doiBatchID)
+				continue
+			}
+			records = UpdateStatus(records, diagnostic)
+		}
+		pending = queued
+		if len(pending) == 0 {
+			break
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return records, fmt.Errorf("timeout waiting for deposits %s", strings.Join(pending, ", "))
+		}
+		time.Sleep(interval)
+	}
+	return records, nil
+}
@@ -0,0 +1,138 @@
+package crossrefxml_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
+	"github.com/front-matter/commonmeta/crossrefxml"
+)
+
+func TestStatus(t *testing.T) {
+	log, err := os.ReadFile(filepath.Join("testdata", "doi_batch_diagnostic.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/servlet/submissionDownload" || r.FormValue("usr") != "user" || r.FormValue("type") != "result" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.FormValue("doi_batch_id") == "queued" {
+			w.Write([]byte(`<doi_batch_diagnostic status="queued"><batch_id>queued</batch_id></doi_batch_diagnostic>`))
+			return
+		}
+		if r.FormValue("doi_batch_id") != "7f0c2fbc-1a45-4d5a-a7a8-5d7a4f0e9b2a" {
+			w.Write([]byte(`<doi_batch_diagnostic status="unknown_submission"><batch_id>` + r.FormValue("doi_batch_id") + `</batch_id></doi_batch_diagnostic>`))
+			return
+		}
+		w.Write(log)
+	}))
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{CrossrefSandbox: ts.URL})
+	defer config.ResetEndpoints()
+
+	account := crossrefxml.Account{LoginID: "user", LoginPasswd: "secret", Development: true}
+	records, err := crossrefxml.Status("7f0c2fbc-1a45-4d5a-a7a8-5d7a4f0e9b2a", account)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		doi    string
+		status string
+	}
+	testCases := []testCase{
+		{doi: "https://doi.org/10.5555/12345678", status: "registered"},
+		{doi: "https://doi.org/10.5555/87654321", status: "registered_with_warning"},
+		{doi: "https://doi.org/10.5555/11111111", status: "failed"},
+	}
+	if len(records) != len(testCases) {
+		t.Fatalf("Status: want %v records, got %v", len(testCases), len(records))
+	}
+	for i, tc := range testCases {
+		got := records[i]
+		if tc.doi != got.DOI || tc.status != got.Status {
+			t.Errorf("Status(%v): want %v, got %v %v", tc.doi, tc.status, got.DOI, got.Status)
+		}
+	}
+
+	records, err = crossrefxml.Status("queued", account)
+	if err != nil || len(records) != 1 || records[0].Status != "queued" || records[0].DOIBatchID != "queued" {
+		t.Errorf("Status(queued): want a queued record, got %v %v", records, err)
+	}
+
+	_, err = crossrefxml.Status("unknown", account)
+	if !errors.Is(err, commonmeta.ErrNotFound) {
+		t.Errorf("Status(unknown): want %v, got %v", commonmeta.ErrNotFound, err)
+	}
+}
+
+func TestPollStatus(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		doiBatchID := r.FormValue("doi_batch_id")
+		switch {
+		case doiBatchID == "unknown":
+			fmt.Fprintf(w, `<doi_batch_diagnostic status="unknown_submission"><batch_id>%s</batch_id></doi_batch_diagnostic>`, doiBatchID)
+		case calls == 1:
+			fmt.Fprintf(w, `<doi_batch_diagnostic status="unknown_submission"><batch_id>%s</batch_id></doi_batch_diagnostic>`, doiBatchID)
+		case calls == 2:
+			fmt.Fprintf(w, `<doi_batch_diagnostic status="in_process"><batch_id>%s</batch_id></doi_batch_diagnostic>`, doiBatchID)
+		default:
+			fmt.Fprintf(w, `<doi_batch_diagnostic status="completed"><batch_id>%s</batch_id><record_diagnostic status="Success"><doi>10.5555/12345678</doi><msg>Successfully added</msg></record_diagnostic></doi_batch_diagnostic>`, doiBatchID)
+		}
+	}))
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{CrossrefSandbox: ts.URL})
+	defer config.ResetEndpoints()
+
+	account := crossrefxml.Account{LoginID: "user", LoginPasswd: "secret", Development: true}
+	records := []commonmeta.APIResponse{{DOI: "https://doi.org/10.5555/12345678", DOIBatchID: "batch", Status: "submitted"}}
+	records, err := crossrefxml.PollStatus(records, account, time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records[0].Status != "registered" || calls != 3 {
+		t.Errorf("PollStatus(%v): want registered after 3 calls, got %v after %v", records[0].DOI, records[0].Status, calls)
+	}
+
+	// deposits that never become visible time out
+	records = []commonmeta.APIResponse{{DOI: "https://doi.org/10.5555/12345678", DOIBatchID: "unknown", Status: "submitted"}}
+	records, err = crossrefxml.PollStatus(records, account, time.Millisecond, 10*time.Millisecond)
+	if err == nil || records[0].Status != "submitted" {
+		t.Errorf("PollStatus(%v): want timeout, got %v %v", records[0].DOI, records[0].Status, err)
+	}
+}
+
+func TestUpdateStatus(t *testing.T) {
+	t.Parallel()
+	log, err := os.ReadFile(filepath.Join("testdata", "doi_batch_diagnostic.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagnostic, err := crossrefxml.ParseDiagnostic(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []commonmeta.APIResponse{
+		{DOI: "https://doi.org/10.5555/11111111", UUID: "1b4b1f3a-7a8e-4e0a-9c4b-2f1b1d4f5e6a", Status: "submitted"},
+	}
+	records = crossrefxml.UpdateStatus(records, diagnostic)
+	got := records[0]
+	want := "Record not processed because submitted version: 1718784000 is less or equal to previously submitted version (DOI match)"
+	if got.Status != "failed" || got.Message != want || got.UUID == "" {
+		t.Errorf("UpdateStatus(%v): want failed %v, got %v %v", got.DOI, want, got.Status, got.Message)
+	}
+	if len(records) != 3 {
+		t.Errorf("UpdateStatus: want 3 records, got %v", len(records))
+	}
+}
@@ -0,0 +1,216 @@
+package crossrefxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/front-matter/commonmeta/audit"
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/dateutils"
+	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/google/uuid"
+)
+
+// abstractPredecessors are the elements that precede the abstract of a work
+// in the Crossref schema, by type of work. They are used to add an abstract
+// to a withdrawn work that has none.
+var abstractPredecessors = map[string][]string{
+	"conference_paper": {"contributors", "titles"},
+	"content_item":     {"contributors", "titles"},
+	"dissertation":     {"person_name", "titles"},
+	"journal_article":  {"titles", "contributors"},
+	"posted_content":   {"group_title", "contributors", "titles", "posted_date", "acceptance_date", "institution", "item_number"},
+}
+
+// Withdraw marks a Crossref DOI as withdrawn. Crossref DOIs can't be deleted,
+// instead the metadata is updated with a title prefixed with [Withdrawn] and
+// the reason as abstract. The DOI should then resolve to a page explaining the
+// withdrawal. The withdrawal is deposited from the Crossref XML currently
+// registered, replacing only the title and abstracts, so that all other
+// metadata are kept.
+func Withdraw(record commonmeta.APIResponse, account Account, reason string) (commonmeta.APIResponse, error) {
+	doi, ok := doiutils.ValidateDOI(record.DOI)
+	if !ok {
+		return record, fmt.Errorf("%w: invalid DOI", commonmeta.ErrValidation)
+	}
+	unixsd, err := GetUnixsd(doi)
+	if err != nil {
+		return record, err
+	}
+	description := "This work has been withdrawn."
+	if reason != "" {
+		description = "This work has been withdrawn: " + reason
+	}
+	output, err := WriteWithdrawal(unixsd, doi, description, account)
+	if err != nil {
+		record.Status = "failed"
+		record.Message = err.Error()
+		record.Error = err.Error()
+		return record, err
+	}
+	err = deposit(output, account, "doMDUpload")
+	if err != nil {
+		record.Status = "failed"
+		record.Message = err.Error()
+		record.Error = err.Error()
+		audit.Record("withdraw", "crossrefxml", record.DOI, output, record.Status, err)
+		return record, err
+	}
+	record.DOIBatchID = DOIBatchID(output)
+	record.Status = "withdrawn"
+	record.Message = description
+	audit.Record("withdraw", "crossrefxml", record.DOI, output, record.Status, nil)
+	return record, nil
+}
+
+// element is the position of an XML element in a document.
+type element struct {
+	name     string
+	parent   int
+	start    int64 // before the start tag
+	inner    int64 // after the start tag
+	innerEnd int64 // before the end tag
+	end      int64 // after the end tag
+}
+
+// WriteWithdrawal writes a deposit withdrawing a DOI from its metadata in
+// the unixsd format. The crossref element of the registered metadata is
+// copied unchanged, except for the title of the work, which is prefixed
+// with [Withdrawn], and its abstracts, which are replaced by description.
+func WriteWithdrawal(unixsd []byte, doi string, description string, account Account) ([]byte, error) {
+	elements, err := parseElements(unixsd)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Crossref XML: %w", err)
+	}
+	content := slices.IndexFunc(elements, func(e element) bool { return e.name == "crossref" })
+	work := -1
+	for _, e := range elements {
+		if e.name == "doi" && e.parent >= 0 && elements[e.parent].name == "doi_data" &&
+			strings.EqualFold(strings.TrimSpace(string(unixsd[e.inner:e.innerEnd])), doi) {
+			work = elements[e.parent].parent
+			break
+		}
+	}
+	if content == -1 || work < 0 {
+		return nil, fmt.Errorf("%w: no Crossref XML for %s", commonmeta.ErrNotFound, doi)
+	}
+
+	// edits of the registered metadata, at byte offsets in unixsd
+	type edit struct {
+		start, end int64
+		text       string
+	}
+	var edits []edit
+	var abstract bytes.Buffer
+	abstract.WriteString(`<jats:abstract xmlns:jats="http://www.ncbi.nlm.nih.gov/JATS1"><jats:p>`)
+	xml.EscapeText(&abstract, []byte(description))
+	abstract.WriteString(`</jats:p></jats:abstract>`)
+	var title, after int64 = -1, -1
+	var abstracts []element
+	for i, e := range elements {
+		if e.parent == work && e.name == "abstract" {
+			abstracts = append(abstracts, e)
+		} else if e.parent == work && slices.Contains(abstractPredecessors[elements[work].name], e.name) {
+			after = e.end
+		}
+		if e.name == "title" && e.parent >= 0 && elements[e.parent].name == "titles" && elements[e.parent].parent == work && title == -1 {
+			title = int64(i)
+		}
+	}
+	if title == -1 {
+		return nil, fmt.Errorf("%w: no title for %s", commonmeta.ErrValidation, doi)
+	}
+	t := elements[title]
+	if !strings.HasPrefix(strings.TrimSpace(string(unixsd[t.inner:t.innerEnd])), "[Withdrawn]") {
+		edits = append(edits, edit{start: t.inner, end: t.inner, text: "[Withdrawn] "})
+	}
+	switch {
+	case len(abstracts) > 0:
+		for i, a := range abstracts {
+			text := ""
+			if i == 0 {
+				text = abstract.String()
+			}
+			edits = append(edits, edit{start: a.start, end: a.end, text: text})
+		}
+	case after != -1:
+		edits = append(edits, edit{start: after, end: after, text: abstract.String()})
+	default:
+		return nil, fmt.Errorf("%w: can't add an abstract to %s", commonmeta.ErrValidation, elements[work].name)
+	}
+	slices.SortFunc(edits, func(a, b edit) int { return int(a.start - b.start) })
+
+	var body strings.Builder
+	offset := elements[content].inner
+	for _, e := range edits {
+		body.Write(unixsd[offset:e.start])
+		body.WriteString(e.text)
+		offset = e.end
+	}
+	body.Write(unixsd[offset:elements[content].innerEnd])
+
+	uuid, _ := uuid.NewRandom()
+	doiBatch := DOIBatch{
+		Xmlns:   "http://www.crossref.org/schema/5.4.0",
+		Version: "5.4.0",
+		Head: Head{
+			DOIBatchID: uuid.String(),
+			Timestamp:  time.Now().Format(dateutils.CrossrefDateTimeFormat),
+			Depositor: Depositor{
+				DepositorName: account.Depositor,
+				Email:         account.Email,
+			},
+			Registrant: account.Registrant,
+		},
+	}
+	output, err := xml.MarshalIndent(doiBatch, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	output = bytes.Replace(output, []byte("<body></body>"), []byte("<body>"+body.String()+"</body>"), 1)
+	return []byte(xml.Header + string(output)), nil
+}
+
+// parseElements returns the positions of all elements in an XML document,
+// in document order, with the index of their parent element.
+func parseElements(content []byte) ([]element, error) {
+	var elements []element
+	var stack []int
+	d := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		offset := d.InputOffset()
+		token, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			parent := -1
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			elements = append(elements, element{name: token.Name.Local, parent: parent, start: offset, inner: d.InputOffset()})
+			stack = append(stack, len(elements)-1)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unexpected end element %s", token.Name.Local)
+			}
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			elements[i].innerEnd = offset
+			elements[i].end = d.InputOffset()
+		}
+	}
+	if len(stack) > 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return elements, nil
+}
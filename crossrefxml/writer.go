@@ -13,9 +13,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/front-matter/commonmeta/audit"
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/dateutils"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/roguescholar"
 	"github.com/front-matter/commonmeta/utils"
 	"github.com/google/uuid"
@@ -45,12 +48,20 @@ type DOIBatch struct {
 	Body           Body     `xml:"body"`
 }
 
+// DefaultBatchSize is the maximum number of records in a Crossref deposit.
+const DefaultBatchSize = 100
+
+// DefaultBatchBytes is the maximum size of the Crossref XML of a deposit.
+// Crossref recommends deposit files smaller than 10 MB.
+const DefaultBatchBytes = 5 * 1024 * 1024
+
 type Account struct {
 	LoginID     string `xml:"login_id"`
 	LoginPasswd string `xml:"login_passwd"`
 	Depositor   string `xml:"depositor"`
 	Email       string `xml:"email"`
 	Registrant  string `xml:"registrant"`
+	Development bool   `xml:"-"`
 }
 
 // CMToCRMappings maps Commonmeta types to Crossref types
@@ -184,27 +195,6 @@ func Convert(data commonmeta.Data) (Body, error) {
 	}
 
 	doi, _ := doiutils.ValidateDOI(data.ID)
-	var items []Item
-	items = append(items, Item{
-		Resource: Resource{
-			Text:     data.URL,
-			MimeType: "text/html",
-		},
-	})
-	if len(data.Files) > 0 {
-		for _, file := range data.Files {
-			item := Item{
-				Resource: Resource{
-					Text:     file.URL,
-					MimeType: file.MimeType,
-				},
-			}
-			// as text/html item may already have been added
-			if !slices.Contains(items, item) {
-				items = append(items, item)
-			}
-		}
-	}
 
 	var issn []ISSN
 	if data.Container.IdentifierType == "issn" {
@@ -215,12 +205,9 @@ func Convert(data commonmeta.Data) (Body, error) {
 	}
 
 	doiData := DOIData{
-		DOI:      doi,
-		Resource: data.URL,
-		Collection: Collection{
-			Property: "text-mining",
-			Item:     items,
-		},
+		DOI:        doi,
+		Resource:   data.URL,
+		Collection: textMiningCollection(data),
 	}
 
 	var itemNumber ItemNumber
@@ -367,37 +354,7 @@ func Convert(data commonmeta.Data) (Body, error) {
 		})
 	}
 
-	citationList := CitationList{}
-	if len(data.References) > 0 {
-		for i, v := range data.References {
-			key := v.Key
-			if v.Key == "" {
-				key = fmt.Sprintf("ref%d", i+1)
-			}
-			type_ := CMToCRCitationMappings[v.Type]
-			d, _ := doiutils.ValidateDOI(v.ID)
-			if d != "" {
-				citationList.Citation = append(citationList.Citation, Citation{
-					Key:  key,
-					Type: type_,
-					DOI: &DOI{
-						Text: d,
-					},
-					ArticleTitle:       v.Title,
-					CYear:              v.PublicationYear,
-					UnstructedCitation: v.Unstructured,
-				})
-			} else if v.Unstructured != "" {
-				citationList.Citation = append(citationList.Citation, Citation{
-					Key:                key,
-					Type:               type_,
-					ArticleTitle:       v.Title,
-					CYear:              v.PublicationYear,
-					UnstructedCitation: v.Unstructured,
-				})
-			}
-		}
-	}
+	citationList := citations(data)
 
 	titles := Titles{}
 	if len(data.Titles) > 0 {
@@ -521,6 +478,72 @@ func Convert(data commonmeta.Data) (Body, error) {
 	return c, nil
 }
 
+// textMiningCollection returns the text-mining collection for the landing page
+// and files of a work.
+func textMiningCollection(data commonmeta.Data) Collection {
+	var items []Item
+	items = append(items, Item{
+		Resource: Resource{
+			Text:     data.URL,
+			MimeType: "text/html",
+		},
+	})
+	if len(data.Files) > 0 {
+		for _, file := range data.Files {
+			item := Item{
+				Resource: Resource{
+					Text:     file.URL,
+					MimeType: file.MimeType,
+				},
+			}
+			// as text/html item may already have been added
+			if !slices.Contains(items, item) {
+				items = append(items, item)
+			}
+		}
+	}
+	return Collection{
+		Property: "text-mining",
+		Item:     items,
+	}
+}
+
+// citations returns the Crossref citation list for the references of a work.
+func citations(data commonmeta.Data) CitationList {
+	citationList := CitationList{}
+	if len(data.References) > 0 {
+		for i, v := range data.References {
+			key := v.Key
+			if v.Key == "" {
+				key = fmt.Sprintf("ref%d", i+1)
+			}
+			type_ := CMToCRCitationMappings[v.Type]
+			d, _ := doiutils.ValidateDOI(v.ID)
+			if d != "" {
+				citationList.Citation = append(citationList.Citation, Citation{
+					Key:  key,
+					Type: type_,
+					DOI: &DOI{
+						Text: d,
+					},
+					ArticleTitle:       v.Title,
+					CYear:              v.PublicationYear,
+					UnstructedCitation: v.Unstructured,
+				})
+			} else if v.Unstructured != "" {
+				citationList.Citation = append(citationList.Citation, Citation{
+					Key:                key,
+					Type:               type_,
+					ArticleTitle:       v.Title,
+					CYear:              v.PublicationYear,
+					UnstructedCitation: v.Unstructured,
+				})
+			}
+		}
+	}
+	return citationList
+}
+
 // Write writes Crossrefxml metadata.
 func Write(data commonmeta.Data, account Account) ([]byte, error) {
 	body, err := Convert(data)
@@ -612,7 +635,7 @@ func WriteAll(list []commonmeta.Data, account Account) ([]byte, error) {
 func Upsert(record commonmeta.APIResponse, account Account, legacyKey string, data commonmeta.Data) (commonmeta.APIResponse, error) {
 	isCrossref, ok := doiutils.GetDOIRA(data.ID)
 	if !ok {
-		return record, errors.New("DOI is not a valid DOI")
+		return record, fmt.Errorf("%w: DOI is not a valid DOI", commonmeta.ErrValidation)
 	} else if isCrossref != "Crossref" {
 		return record, nil
 	}
@@ -628,65 +651,25 @@ func Upsert(record commonmeta.APIResponse, account Account, legacyKey string, da
 		}
 	}
 
-	type HTML struct {
-		Head struct {
-			Title string `xml:"title"`
-		} `xml:"head"`
-		Body struct {
-			H2 string `xml:"h2"`
-			P  string `xml:"p"`
-		} `xml:"body"`
-	}
-	type Response HTML
-	var response Response
-
 	crossrefxml, err := Write(data, account)
 	if err != nil {
-		return record, errors.New("JSON schema validation failed")
-	}
-	// the filename displayed in the Crossref admin interface, using the current UNIX timestamp
-	filename := strconv.FormatInt(time.Now().Unix(), 10)
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	var b bytes.Buffer
-	w := multipart.NewWriter(&b)
-	part, _ := w.CreateFormFile("fname", filename)
-	_, err = part.Write(crossrefxml)
-	if err != nil {
+		err = fmt.Errorf("%w: %w", commonmeta.ErrValidation, err)
+		record.Status = "failed"
+		record.Message = err.Error()
+		record.Error = err.Error()
 		return record, err
 	}
-	w.WriteField("operation", "doMDUpload")
-	w.WriteField("login_id", account.LoginID)
-	w.WriteField("login_passwd", account.LoginPasswd)
-	w.Close()
-
-	postUrl := "https://doi.crossref.org/servlet/deposit"
-	req, err := http.NewRequest(http.MethodPost, postUrl, strings.NewReader(b.String()))
-	req.Header.Add("Content-Type", w.FormDataContentType())
-	if err != nil {
-		return record, err
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error uploading batch", err)
-		return record, err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	err = deposit(crossrefxml, account, "doMDUpload")
 	if err != nil {
+		record.Status = "failed"
+		record.Message = err.Error()
+		record.Error = err.Error()
+		audit.Record("upsert", "crossrefxml", record.DOI, crossrefxml, record.Status, err)
 		return record, err
 	}
-	err = xml.Unmarshal(body, &response)
-	if err != nil {
-		return record, err
-	}
-	if response.Body.H2 == "FAILURE" {
-		return record, errors.New(response.Body.P)
-	}
+	record.DOIBatchID = DOIBatchID(crossrefxml)
 	record.Status = "submitted"
+	audit.Record("upsert", "crossrefxml", record.DOI, crossrefxml, record.Status, nil)
 
 	// update rogue-scholar legacy record if legacy key is provided
 	if doiutils.IsRogueScholarDOI(data.ID, "crossref") && legacyKey != "" {
@@ -700,15 +683,37 @@ func Upsert(record commonmeta.APIResponse, account Account, legacyKey string, da
 	return record, nil
 }
 
-// UpsertAll updates or creates a list of Crossrefxml metadata.
+// UpsertAll updates or creates a list of Crossrefxml metadata, using batches of
+// DefaultBatchSize records and at most DefaultBatchBytes.
 func UpsertAll(list []commonmeta.Data, account Account, legacyKey string) ([]commonmeta.APIResponse, error) {
+	return UpsertBatches(list, account, legacyKey, DefaultBatchSize, DefaultBatchBytes, 1)
+}
+
+// UpsertBatches updates or creates a list of Crossrefxml metadata, uploading
+// one deposit per batch of at most batchSize records and batchBytes bytes,
+// with at most workers concurrent uploads. All records of a batch get the
+// doi_batch_id of the deposit, use PollStatus to get the result for each DOI.
+// A failed deposit marks the records of its batch as failed, and the
+// remaining batches are still uploaded. Records without a valid Crossref DOI
+// are returned as failed records.
+func UpsertBatches(list []commonmeta.Data, account Account, legacyKey string, batchSize int, batchBytes int, workers int) ([]commonmeta.APIResponse, error) {
 	var records []commonmeta.APIResponse
+	var failed []commonmeta.APIResponse
+	var crossrefList []commonmeta.Data
 	for _, data := range list {
 		isCrossref, ok := doiutils.GetDOIRA(data.ID)
-		if !ok {
-			fmt.Println("DOI is not a valid DOI:", data.ID)
-			continue
-		} else if isCrossref != "Crossref" {
+		if !ok || isCrossref != "Crossref" {
+			message := "invalid DOI"
+			if ok {
+				message = "not a Crossref DOI"
+			}
+			doi, _ := doiutils.ValidateDOI(data.ID)
+			failed = append(failed, commonmeta.APIResponse{
+				DOI:     doiutils.NormalizeDOI(doi),
+				ID:      data.ID,
+				Status:  "failed",
+				Message: message,
+			})
 			continue
 		}
 
@@ -723,13 +728,112 @@ func UpsertAll(list []commonmeta.Data, account Account, legacyKey string) ([]com
 			}
 		}
 		records = append(records, record)
+		crossrefList = append(crossrefList, data)
 	}
 
-	// if no metadata to write, return empty list
+	// if no metadata to write, return the failed records
 	if len(records) == 0 {
-		return records, nil
+		return failed, nil
 	}
 
+	// each batch updates its own slice of records
+	type batchRecords struct {
+		list    []commonmeta.Data
+		records []commonmeta.APIResponse
+	}
+	var batches []batchRecords
+	var start int
+	for _, batch := range Batches(crossrefList, batchSize, batchBytes) {
+		batches = append(batches, batchRecords{list: batch, records: records[start : start+len(batch)]})
+		start += len(batch)
+	}
+	results := utils.ParallelMap(batches, workers, func(batch batchRecords) error {
+		return upsertBatch(batch.list, batch.records, account, legacyKey)
+	})
+	return append(records, failed...), errors.Join(results...)
+}
+
+// upsertBatch uploads one deposit for a batch and updates its records.
+func upsertBatch(list []commonmeta.Data, records []commonmeta.APIResponse, account Account, legacyKey string) error {
+	crossrefxml, err := WriteAll(list, account)
+	if err == nil {
+		err = deposit(crossrefxml, account, "doMDUpload")
+	}
+	if err != nil {
+		for i := range records {
+			records[i].Status = "failed"
+			records[i].Message = err.Error()
+			records[i].Error = err.Error()
+			audit.Record("upsert", "crossrefxml", records[i].DOI, crossrefxml, "", err)
+		}
+		return err
+	}
+	doiBatchID := DOIBatchID(crossrefxml)
+
+	// update rogue-scholar legacy record with doi if legacy key is provided
+	var errs []error
+	for i := range records {
+		records[i].DOIBatchID = doiBatchID
+		records[i].Status = "submitted"
+		audit.Record("upsert", "crossrefxml", records[i].DOI, crossrefxml, records[i].Status, nil)
+		if doiutils.IsRogueScholarDOI(records[i].DOI, "crossref") && legacyKey != "" {
+			records[i], err = roguescholar.UpdateLegacyRecord(records[i], legacyKey, "doi")
+			if err != nil {
+				records[i].Error = err.Error()
+				errs = append(errs, err)
+				continue
+			}
+			records[i].Status = "submitted_and_updated_legacy"
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Batches splits a list of commonmeta metadata into batches of at most size
+// records and at most maxBytes bytes of Crossref XML. The size of each record
+// is estimated from its Crossref XML body. A record larger than maxBytes
+// gets a batch of its own. A size or maxBytes of 0 means no limit.
+func Batches(list []commonmeta.Data, size int, maxBytes int) [][]commonmeta.Data {
+	var batches [][]commonmeta.Data
+	var batch []commonmeta.Data
+	var batchBytes int
+	for _, data := range list {
+		var n int
+		if maxBytes > 0 {
+			body, _ := Convert(data)
+			output, _ := xml.Marshal(body)
+			n = len(output)
+		}
+		if len(batch) > 0 && (size > 0 && len(batch) >= size || maxBytes > 0 && batchBytes+n > maxBytes) {
+			batches = append(batches, batch)
+			batch = nil
+			batchBytes = 0
+		}
+		batch = append(batch, data)
+		batchBytes += n
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// DOIBatchID returns the doi_batch_id of a Crossref XML deposit file.
+func DOIBatchID(output []byte) string {
+	var doiBatch struct {
+		Head Head `xml:"head"`
+	}
+	err := xml.Unmarshal(output, &doiBatch)
+	if err != nil {
+		return ""
+	}
+	return doiBatch.Head.DOIBatchID
+}
+
+// deposit uploads a Crossref XML file to the Crossref deposit servlet,
+// using operation doMDUpload for metadata and doDOICitUpload for resources.
+// Crossref processes deposits asynchronously, use GetStatus to check the result.
+func deposit(output []byte, account Account, operation string) error {
 	type HTML struct {
 		Head struct {
 			Title string `xml:"title"`
@@ -742,63 +846,48 @@ func UpsertAll(list []commonmeta.Data, account Account, legacyKey string) ([]com
 	type Response HTML
 	var response Response
 
-	crossrefxml, err := WriteAll(list, account)
-	if err != nil {
-		return records, errors.New("JSON schema validation failed")
-	}
 	// the filename displayed in the Crossref admin interface, using the current UNIX timestamp
 	filename := strconv.FormatInt(time.Now().Unix(), 10)
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := httputils.NewClient(60 * time.Second)
 
 	var b bytes.Buffer
 	w := multipart.NewWriter(&b)
 	part, _ := w.CreateFormFile("fname", filename)
-	_, err = part.Write(crossrefxml)
+	_, err := part.Write(output)
 	if err != nil {
-		return records, err
+		return err
 	}
-	w.WriteField("operation", "doMDUpload")
+	w.WriteField("operation", operation)
 	w.WriteField("login_id", account.LoginID)
 	w.WriteField("login_passwd", account.LoginPasswd)
 	w.Close()
 
-	postUrl := "https://doi.crossref.org/servlet/deposit"
-	req, err := http.NewRequest(http.MethodPost, postUrl, strings.NewReader(b.String()))
-	req.Header.Add("Content-Type", w.FormDataContentType())
+	postUrl := config.CrossrefDepositURL(account.Development) + "/servlet/deposit"
+	req, err := http.NewRequest(http.MethodPost, postUrl, bytes.NewReader(b.Bytes()))
 	if err != nil {
-		return records, err
+		return err
 	}
+	req.Header.Add("Content-Type", w.FormDataContentType())
 	resp, err := client.Do(req)
 	if err != nil {
-		fmt.Println("Error uploading batch", err)
-		return records, err
+		return err
 	}
 	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return err
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return records, err
+		return err
 	}
 	err = xml.Unmarshal(body, &response)
 	if err != nil {
-		return records, err
+		return err
 	}
 	if response.Body.H2 == "FAILURE" {
-		return records, errors.New(response.Body.P)
-	}
-
-	// update rogue-scholar legacy record with doi if legacy key is provided
-	for i := range records {
-		records[i].Status = "submitted"
-		if doiutils.IsRogueScholarDOI(records[i].DOI, "crossref") && legacyKey != "" {
-			records[i], err = roguescholar.UpdateLegacyRecord(records[i], legacyKey, "doi")
-			if err != nil {
-				return records, err
-			}
-			records[i].Status = "submitted_and_updated_legacy"
-		}
+		return errors.New(response.Body.P)
 	}
-	return records, nil
+	return nil
 }
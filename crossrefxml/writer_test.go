@@ -1,5 +1,26 @@
 package crossrefxml_test
 
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
+	"github.com/front-matter/commonmeta/crossrefxml"
+)
+
 // func TestConvert(t *testing.T) {
 // 	t.Parallel()
 
@@ -39,3 +60,240 @@ package crossrefxml_test
 // 		}
 // 	}
 // }
+
+func testList(t *testing.T, n int) []commonmeta.Data {
+	t.Helper()
+	data, err := commonmeta.Load(filepath.Join("..", "testdata", "commonmeta", "journal_article.commonmeta.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []commonmeta.Data
+	for i := range n {
+		d := data
+		d.ID = fmt.Sprintf("https://doi.org/10.59350/test-%d", i)
+		d.References = []commonmeta.Reference{
+			{Key: "ref1", ID: "https://doi.org/10.5555/12345678"},
+			{Key: "ref2", Unstructured: "Fenner M. Rogue Scholar. 2023."},
+		}
+		list = append(list, d)
+	}
+	return list
+}
+
+func TestBatches(t *testing.T) {
+	t.Parallel()
+	list := testList(t, 5)
+	type testCase struct {
+		name     string
+		size     int
+		maxBytes int
+		want     []int
+	}
+	testCases := []testCase{
+		{name: "no limit", size: 0, maxBytes: 0, want: []int{5}},
+		{name: "by count", size: 2, maxBytes: 0, want: []int{2, 2, 1}},
+		{name: "by bytes", size: 0, maxBytes: 1, want: []int{1, 1, 1, 1, 1}},
+		{name: "by count and bytes", size: 4, maxBytes: 1 << 20, want: []int{4, 1}},
+	}
+	for _, tc := range testCases {
+		var got []int
+		for _, batch := range crossrefxml.Batches(list, tc.size, tc.maxBytes) {
+			got = append(got, len(batch))
+		}
+		if !slices.Equal(tc.want, got) {
+			t.Errorf("Batches(%v): want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestUpsertBatches(t *testing.T) {
+	var mu sync.Mutex
+	deposits := map[string][]string{}
+	doiRegexp := regexp.MustCompile(`<doi>([^<]+)</doi>`)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/servlet/deposit":
+			file, _, err := r.FormFile("fname")
+			if err != nil || r.FormValue("operation") != "doMDUpload" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			output, _ := io.ReadAll(file)
+			var dois []string
+			for _, m := range doiRegexp.FindAllStringSubmatch(string(output), -1) {
+				dois = append(dois, m[1])
+			}
+			deposits[crossrefxml.DOIBatchID(output)] = dois
+			w.Write([]byte(`<html><head><title>SUCCESS</title></head><body><h2>SUCCESS</h2><p>Your batch submission was successfully received.</p></body></html>`))
+		case "/servlet/submissionDownload":
+			doiBatchID := r.FormValue("doi_batch_id")
+			fmt.Fprintf(w, `<doi_batch_diagnostic status="completed"><batch_id>%s</batch_id>`, doiBatchID)
+			for _, doi := range deposits[doiBatchID] {
+				fmt.Fprintf(w, `<record_diagnostic status="Success"><doi>%s</doi><msg>Successfully added</msg></record_diagnostic>`, doi)
+			}
+			w.Write([]byte(`</doi_batch_diagnostic>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{CrossrefSandbox: ts.URL})
+	defer config.ResetEndpoints()
+
+	account := crossrefxml.Account{LoginID: "user", LoginPasswd: "secret", Development: true}
+	records, err := crossrefxml.UpsertBatches(testList(t, 5), account, "", 2, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deposits) != 3 {
+		t.Errorf("UpsertBatches: want 3 deposits, got %v", len(deposits))
+	}
+	records, err = crossrefxml.PollStatus(records, account, time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if record.Status != "registered" || len(deposits[record.DOIBatchID]) == 0 {
+			t.Errorf("UpsertBatches(%v): want registered in batch, got %v in %v", record.DOI, record.Status, record.DOIBatchID)
+		}
+	}
+
+	// records without a valid Crossref DOI are returned as failed
+	list := testList(t, 3)
+	list[1].ID = "https://doi.org/10.83132/abcd-1234"
+	list[2].ID = "https://example.org/not-a-doi"
+	records, err = crossrefxml.UpsertBatches(list, account, "", 2, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for _, record := range records {
+		statuses = append(statuses, record.Status+" "+record.Message)
+	}
+	want := "submitted |failed not a Crossref DOI|failed invalid DOI"
+	if want != strings.Join(statuses, "|") {
+		t.Errorf("UpsertBatches(invalid DOIs): want %v, got %v", want, strings.Join(statuses, "|"))
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	unixsd, err := os.ReadFile(filepath.Join("..", "testdata", "crossrefxml", "crossref.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deposit string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/works/10.7554/elife.01567/transform/application/vnd.crossref.unixsd+xml" {
+			w.Write(unixsd)
+			return
+		}
+		file, _, err := r.FormFile("fname")
+		if err != nil || r.URL.Path != "/servlet/deposit" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		output, _ := io.ReadAll(file)
+		deposit = string(output)
+		w.Write([]byte(`<html><head><title>SUCCESS</title></head><body><h2>SUCCESS</h2></body></html>`))
+	}))
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{CrossrefAPI: ts.URL, CrossrefSandbox: ts.URL})
+	defer config.ResetEndpoints()
+
+	doi := "https://doi.org/10.7554/elife.01567"
+	account := crossrefxml.Account{LoginID: "user", LoginPasswd: "secret", Development: true}
+	record, err := crossrefxml.Withdraw(commonmeta.APIResponse{DOI: doi}, account, "registered in error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != "withdrawn" || record.DOIBatchID == "" {
+		t.Errorf("Withdraw(%v): want withdrawn, got %v %v", doi, record.Status, record.DOIBatchID)
+	}
+	var batch crossrefxml.DOIBatch
+	err = xml.Unmarshal([]byte(deposit), &batch)
+	if err != nil {
+		t.Fatalf("Withdraw(%v): want a doi_batch, got %v", doi, err)
+	}
+	if len(batch.Body.Journal) != 1 || batch.Head.DOIBatchID != record.DOIBatchID {
+		t.Fatalf("Withdraw(%v): want one journal article in batch %v, got %v", doi, record.DOIBatchID, deposit)
+	}
+	article := batch.Body.Journal[0].JournalArticle
+	want := "[Withdrawn] Automated quantitative histology reveals vascular morphodynamics during Arabidopsis hypocotyl secondary growth"
+	if article.Titles.Title != want {
+		t.Errorf("Withdraw(%v): want title %v, got %v", doi, want, article.Titles.Title)
+	}
+	want = `<jats:abstract xmlns:jats="http://www.ncbi.nlm.nih.gov/JATS1"><jats:p>This work has been withdrawn: registered in error</jats:p></jats:abstract>`
+	if !strings.Contains(deposit, want) || strings.Count(deposit, "<jats:abstract") != 1 {
+		t.Errorf("Withdraw(%v): want withdrawal notice as only abstract, got %v abstracts", doi, strings.Count(deposit, "<jats:abstract"))
+	}
+	// all other metadata are kept
+	for _, want := range []string{
+		`<fr:program xmlns:fr="http://www.crossref.org/fundref.xsd" name="fundref">`,
+		`<rel:program xmlns:rel="http://www.crossref.org/relations.xsd">`,
+		`<issn media_type="electronic">2050-084X</issn>`,
+		`<surname>Xenarios</surname>`,
+		`<component_list>`,
+	} {
+		if !strings.Contains(deposit, want) {
+			t.Errorf("Withdraw(%v): want %v in deposit", doi, want)
+		}
+	}
+}
+
+func TestWriteWithdrawal(t *testing.T) {
+	t.Parallel()
+	unixsd := `<crossref_result><query_result><body><query><doi_record>
+<crossref xmlns="http://www.crossref.org/xschema/1.1">
+<posted_content type="other">
+<titles><title>[Withdrawn] Test</title></titles>
+<posted_date><year>2024</year></posted_date>
+<item_number item_number_type="uuid">1234</item_number>
+<program xmlns="http://www.crossref.org/AccessIndicators.xsd" name="AccessIndicators"/>
+<doi_data><doi>10.59350/test</doi><resource>https://example.org/test</resource></doi_data>
+</posted_content>
+</crossref>
+</doi_record></query></body></query_result></crossref_result>`
+
+	type testCase struct {
+		doi  string
+		want string
+		err  error
+	}
+	testCases := []testCase{
+		{doi: "10.59350/test", want: `<title>[Withdrawn] Test</title></titles>
+<posted_date><year>2024</year></posted_date>
+<item_number item_number_type="uuid">1234</item_number><jats:abstract xmlns:jats="http://www.ncbi.nlm.nih.gov/JATS1"><jats:p>This work has been withdrawn: &lt;spam&gt;</jats:p></jats:abstract>
+<program`, err: nil},
+		{doi: "10.59350/other", want: "", err: commonmeta.ErrNotFound},
+	}
+	for _, tc := range testCases {
+		output, err := crossrefxml.WriteWithdrawal([]byte(unixsd), tc.doi, "This work has been withdrawn: <spam>", crossrefxml.Account{})
+		if !errors.Is(err, tc.err) {
+			t.Errorf("WriteWithdrawal(%v): want %v, got %v", tc.doi, tc.err, err)
+		}
+		if !strings.Contains(string(output), tc.want) {
+			t.Errorf("WriteWithdrawal(%v): want %v, got %v", tc.doi, tc.want, string(output))
+		}
+	}
+}
+
+func TestUpsertFailed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{CrossrefSandbox: ts.URL})
+	defer config.ResetEndpoints()
+
+	data := testList(t, 1)[0]
+	account := crossrefxml.Account{LoginID: "user", LoginPasswd: "invalid", Depositor: "Front Matter", Email: "info@front-matter.io", Development: true}
+	record, err := crossrefxml.Upsert(commonmeta.APIResponse{}, account, "", data)
+	if err == nil {
+		t.Fatalf("Upsert(%v): want an error, got nil", data.ID)
+	}
+	if record.Status != "failed" || record.Error == "" || record.DOIBatchID != "" {
+		t.Errorf("Upsert(%v): want failed without doi_batch_id, got %v %v %v", data.ID, record.Status, record.Error, record.DOIBatchID)
+	}
+}
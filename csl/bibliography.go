@@ -0,0 +1,101 @@
+package csl
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/doiutils"
+)
+
+// Bibliography formats commonmeta metadata as a plain text reference in
+// APA style, as returned by DOI content negotiation for text/x-bibliography.
+func Bibliography(data commonmeta.Data) string {
+	var parts []string
+	authors := formatAuthors(data.Contributors)
+	year := "n.d."
+	if len(data.Date.Published) >= 4 {
+		year = data.Date.Published[:4]
+	}
+	if authors != "" {
+		parts = append(parts, withPeriod(authors)+" ("+year+").")
+	} else {
+		parts = append(parts, "("+year+").")
+	}
+	if len(data.Titles) > 0 && data.Titles[0].Title != "" {
+		parts = append(parts, withPeriod(data.Titles[0].Title))
+	}
+
+	container := data.Container.Title
+	if container != "" {
+		if data.Container.Volume != "" {
+			container += ", " + data.Container.Volume
+			if data.Container.Issue != "" {
+				container += "(" + data.Container.Issue + ")"
+			}
+		}
+		if data.Container.FirstPage != "" {
+			container += ", " + data.Container.FirstPage
+			if data.Container.LastPage != "" {
+				container += "–" + data.Container.LastPage
+			}
+		}
+		parts = append(parts, container+".")
+	} else if data.Publisher.Name != "" {
+		parts = append(parts, withPeriod(data.Publisher.Name))
+	}
+
+	if doi, ok := doiutils.ValidateDOI(data.ID); ok {
+		parts = append(parts, doiutils.NormalizeDOI(doi))
+	} else if data.URL != "" {
+		parts = append(parts, data.URL)
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatAuthors formats the authors in APA style, e.g. Sankar, M., Nieminen, K., & Ragni, L.
+// Contributors without roles are treated as authors.
+func formatAuthors(contributors []commonmeta.Contributor) string {
+	var names []string
+	for _, c := range contributors {
+		if len(c.ContributorRoles) > 0 && !slices.Contains(c.ContributorRoles, "Author") {
+			continue
+		}
+		if c.FamilyName != "" {
+			name := c.FamilyName
+			if initials := formatInitials(c.GivenName); initials != "" {
+				name += ", " + initials
+			}
+			names = append(names, name)
+		} else if c.Name != "" {
+			names = append(names, c.Name)
+		}
+	}
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	default:
+		return strings.Join(names[:len(names)-1], ", ") + ", & " + names[len(names)-1]
+	}
+}
+
+// formatInitials returns the initials of given names, e.g. J. P. for Jean Paul.
+func formatInitials(givenName string) string {
+	var initials []string
+	for _, name := range strings.Fields(givenName) {
+		r, _ := utf8.DecodeRuneInString(name)
+		initials = append(initials, string(r)+".")
+	}
+	return strings.Join(initials, " ")
+}
+
+// withPeriod ends a string with a period, unless it ends with a punctuation mark.
+func withPeriod(s string) string {
+	if strings.HasSuffix(s, ".") || strings.HasSuffix(s, "?") || strings.HasSuffix(s, "!") {
+		return s
+	}
+	return s + "."
+}
@@ -0,0 +1,45 @@
+package csl_test
+
+import (
+	"testing"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/csl"
+)
+
+func TestBibliography(t *testing.T) {
+	t.Parallel()
+	type testCase struct {
+		name  string
+		input commonmeta.Data
+		want  string
+	}
+	testCases := []testCase{
+		{name: "journal article", input: commonmeta.Data{
+			ID:   "https://doi.org/10.7554/elife.01567",
+			Type: "JournalArticle",
+			Contributors: []commonmeta.Contributor{
+				{Type: "Person", GivenName: "Martial", FamilyName: "Sankar", ContributorRoles: []string{"Author"}},
+				{Type: "Person", GivenName: "Kaisa", FamilyName: "Nieminen", ContributorRoles: []string{"Author"}},
+				{Type: "Person", GivenName: "Christian S.", FamilyName: "Hardtke", ContributorRoles: []string{"Author"}},
+				{Type: "Person", GivenName: "Jane", FamilyName: "Editor", ContributorRoles: []string{"Editor"}},
+			},
+			Date:      commonmeta.Date{Published: "2014-02-11"},
+			Titles:    []commonmeta.Title{{Title: "Automated quantitative histology"}},
+			Container: commonmeta.Container{Title: "eLife", Volume: "3", FirstPage: "e01567"},
+		}, want: "Sankar, M., Nieminen, K., & Hardtke, C. S. (2014). Automated quantitative histology. eLife, 3, e01567. https://doi.org/10.7554/elife.01567"},
+		{name: "organization author without date", input: commonmeta.Data{
+			ID:           "https://example.org/report",
+			URL:          "https://example.org/report",
+			Contributors: []commonmeta.Contributor{{Type: "Organization", Name: "Front Matter"}},
+			Titles:       []commonmeta.Title{{Title: "Annual report?"}},
+			Publisher:    commonmeta.Publisher{Name: "Front Matter"},
+		}, want: "Front Matter. (n.d.). Annual report? Front Matter. https://example.org/report"},
+	}
+	for _, tc := range testCases {
+		got := csl.Bibliography(tc.input)
+		if tc.want != got {
+			t.Errorf("Bibliography(%v): want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -13,11 +13,12 @@ import (
 	"slices"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/dateutils"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/ror"
 
 	"github.com/front-matter/commonmeta/utils"
@@ -311,7 +312,7 @@ func Fetch(str string, match bool) (commonmeta.Data, error) {
 	var data commonmeta.Data
 	id, ok := doiutils.ValidateDOI(str)
 	if !ok {
-		return data, errors.New("invalid doi")
+		return data, fmt.Errorf("%w: invalid DOI", commonmeta.ErrValidation)
 	}
 	content, err := Get(id)
 	if err != nil {
@@ -413,20 +414,19 @@ func Get(id string) (Content, error) {
 	var response Response
 	doi, ok := doiutils.ValidateDOI(id)
 	if !ok {
-		return response.Data.Attributes, errors.New("invalid DOI")
-	}
-	url := "https://api.datacite.org/dois/" + doi + "?affiliation=true"
-	client := &http.Client{
-		Timeout: time.Second * 10,
+		return response.Data.Attributes, fmt.Errorf("%w: invalid DOI", commonmeta.ErrValidation)
 	}
+	url := config.GetEndpoints().DataCiteAPI + "/dois/" + doi + "?affiliation=true"
+	client := httputils.Client()
 	resp, err := client.Get(url)
 	if err != nil {
 		return response.Data.Attributes, err
 	}
-	if resp.StatusCode >= 400 {
-		return response.Data.Attributes, errors.New(resp.Status)
-	}
 	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return response.Data.Attributes, err
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return response.Data.Attributes, err
@@ -848,9 +848,7 @@ func GetAll(number int, page int, client_ string, type_ string, sample bool, yea
 		number = 1000
 	}
 	var response Response
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	client := httputils.Client()
 	url := QueryURL(number, page, client_, type_, sample, year, language, orcid, ror, hasORCID, hasROR, hasReferences, hasRelation, hasAbstract, hasAward, hasLicense)
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
@@ -860,10 +858,11 @@ func GetAll(number int, page int, client_ string, type_ string, sample bool, yea
 	if err != nil {
 		return content, err
 	}
-	if resp.StatusCode >= 400 {
-		return content, errors.New(resp.Status)
-	}
 	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return content, err
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return content, err
@@ -899,7 +898,7 @@ func QueryURL(number int, page int, client_ string, type_ string, sample bool, y
 	if page <= 0 {
 		page = 1
 	}
-	url := "https://api.datacite.org/dois?page[size]=" + strconv.Itoa(number)
+	url := config.GetEndpoints().DataCiteAPI + "/dois?page[size]=" + strconv.Itoa(number)
 	if sample {
 		url += "&random=true"
 	} else {
@@ -10,12 +10,14 @@ import (
 	"slices"
 	"strconv"
 	"strings"
-	"time"
 
+	"github.com/front-matter/commonmeta/audit"
 	"github.com/front-matter/commonmeta/bibtex"
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/csl"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/ris"
 	"github.com/front-matter/commonmeta/schemautils"
 	"github.com/front-matter/commonmeta/utils"
@@ -25,12 +27,13 @@ type Account struct {
 	Client      string
 	Password    string
 	Development bool
+	Event       string // draft, register, publish or hide
 }
 
 // trigger creation of findable or registered DOI
 type DataciteWithEvent struct {
 	Datacite
-	Event string `json:"event"`
+	Event string `json:"event,omitempty"`
 }
 
 // Convert converts Commonmeta metadata to DataCite metadata
@@ -302,12 +305,29 @@ func Convert(data commonmeta.Data) (Datacite, error) {
 	return datacite, nil
 }
 
+// Events are the DataCite DOI state transitions. Without an event a DOI is
+// created as draft, register creates a registered DOI, publish a findable DOI,
+// and hide turns a findable DOI into a registered DOI.
+var Events = []string{"draft", "register", "publish", "hide"}
+
+// DefaultEvent is the event used when none is given.
+const DefaultEvent = "publish"
+
 // Write writes commonmeta metadata.
 func Write(data commonmeta.Data) ([]byte, error) {
+	return WriteWithEvent(data, DefaultEvent)
+}
+
+// WriteWithEvent writes commonmeta metadata with a DOI state transition.
+func WriteWithEvent(data commonmeta.Data, event string) ([]byte, error) {
+	event, err := validateEvent(event)
+	if err != nil {
+		return nil, err
+	}
 	datacite, err := Convert(data)
 	dataciteWithEvent := DataciteWithEvent{
 		Datacite: datacite,
-		Event:    "publish",
+		Event:    event,
 	}
 	if err != nil {
 		fmt.Println(err)
@@ -331,7 +351,7 @@ func WriteAll(list []commonmeta.Data) ([]byte, error) {
 
 		dataciteWithEvent := DataciteWithEvent{
 			Datacite: datacite,
-			Event:    "publish",
+			Event:    DefaultEvent,
 		}
 		dataciteList = append(dataciteList, dataciteWithEvent)
 	}
@@ -343,7 +363,83 @@ func WriteAll(list []commonmeta.Data) ([]byte, error) {
 	return output, err
 }
 
-// Upsert updates or creates datacite metadata.
+// validateEvent checks the event, and returns an empty string for draft, as
+// DataCite creates draft DOIs when no event is sent.
+func validateEvent(event string) (string, error) {
+	if event == "" {
+		return DefaultEvent, nil
+	}
+	if !slices.Contains(Events, event) {
+		return "", fmt.Errorf("%w: unknown event %s", commonmeta.ErrValidation, event)
+	}
+	if event == "draft" {
+		return "", nil
+	}
+	return event, nil
+}
+
+// doiResponse is the JSON:API response of the DataCite REST API for a DOI.
+type doiResponse struct {
+	Data struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			DOI     string `json:"doi"`
+			State   string `json:"state"`
+			Created string `json:"created"`
+			Updated string `json:"updated"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// doRequest sends a request to the DataCite REST API and decodes the response.
+func doRequest(method string, requestURL string, account Account, body []byte) (doiResponse, error) {
+	var response doiResponse
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, requestURL, reader)
+	if err != nil {
+		return response, err
+	}
+	req.Header.Add("Accept", "application/vnd.api+json")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/vnd.api+json")
+	}
+	req.SetBasicAuth(account.Client, account.Password)
+	resp, err := httputils.Client().Do(req)
+	if err != nil {
+		return response, err
+	}
+	defer resp.Body.Close()
+	if err = httputils.CheckResponse(resp); err != nil {
+		return response, err
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return response, nil
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return response, err
+	}
+	err = json.Unmarshal(respBody, &response)
+	return response, err
+}
+
+// GetState returns the state of a DOI in DataCite: draft, registered or
+// findable. It returns ErrNotFound if the DOI doesn't exist.
+func GetState(doi string, account Account) (string, error) {
+	requestURL := config.DataCiteURL(account.Development) + "/dois/" + doi
+	response, err := doRequest(http.MethodGet, requestURL, account, nil)
+	if err != nil {
+		return "", err
+	}
+	return response.Data.Attributes.State, nil
+}
+
+// Upsert updates or creates datacite metadata. Existing DOIs are updated
+// with PUT, new DOIs are created with POST. The event of the account
+// sets the state transition, the default is publish.
 func Upsert(record commonmeta.APIResponse, account Account, data commonmeta.Data) (commonmeta.APIResponse, error) {
 	isDatacite, ok := doiutils.GetDOIRA(data.ID)
 	if !ok {
@@ -353,67 +449,96 @@ func Upsert(record commonmeta.APIResponse, account Account, data commonmeta.Data
 		record.Status = "failed_not_datacite_doi"
 		return record, nil
 	}
+	doi, _ := doiutils.ValidateDOI(data.ID)
 
-	datacite, err := Write(data)
+	datacite, err := WriteWithEvent(data, account.Event)
 	if err != nil {
-		return record, errors.New("JSON schema validation failed")
-	}
-
-	type Response struct {
-		*Datacite
-	}
-	var response Response
-
-	var requestURL string
-	var req *http.Request
-	var resp *http.Response
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	if account.Development {
-		requestURL = "https://api.test.datacite.org/dois"
-	} else {
-		requestURL = "https://api.datacite.org/dois"
+		return record, fmt.Errorf("%w: %w", commonmeta.ErrValidation, err)
 	}
 	var output = []byte(`{"data":{"type":"dois","attributes":` + string(datacite) + `}}`)
-	req, _ = http.NewRequest(http.MethodPost, requestURL, bytes.NewReader(output))
-	req.Header.Add("Content-Type", "application/vnd.api+json")
-	req.SetBasicAuth(account.Client, account.Password)
-	resp, err = client.Do(req)
-	if err != nil {
-		return record, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		fmt.Println(resp.StatusCode)
-		// return record, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
-	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+
+	method := http.MethodPut
+	requestURL := config.DataCiteURL(account.Development) + "/dois/" + doi
+	_, err = GetState(doi, account)
+	if errors.Is(err, commonmeta.ErrNotFound) {
+		method = http.MethodPost
+		requestURL = config.DataCiteURL(account.Development) + "/dois"
+	} else if err != nil {
+		record.Status = "failed"
+		record.Message = err.Error()
 		return record, err
 	}
-	err = json.Unmarshal(body, &response)
+
+	response, err := doRequest(method, requestURL, account, output)
 	if err != nil {
+		record.Status = "failed"
+		record.Message = err.Error()
+		audit.Record("upsert", "datacite", doi, output, record.Status, err)
 		return record, err
 	}
-	record.Status = "submitted"
+	record.Status = response.Data.Attributes.State
+	record.Created = response.Data.Attributes.Created
+	record.Updated = response.Data.Attributes.Updated
 
+	audit.Record("upsert", "datacite", doi, output, record.Status, nil)
 	return record, nil
 }
 
-// UpsertAll updates or creates a list of DataCite metadata.
-func UpsertAll(list []commonmeta.Data, account Account) ([]commonmeta.APIResponse, error) {
-	var records []commonmeta.APIResponse
-	for _, data := range list {
+// UpsertAll updates or creates a list of DataCite metadata, using at most
+// workers concurrent requests. Errors are stored in the Error field of each
+// record and returned together.
+func UpsertAll(list []commonmeta.Data, account Account, workers int) ([]commonmeta.APIResponse, error) {
+	type result struct {
+		record commonmeta.APIResponse
+		err    error
+	}
+	results := utils.ParallelMap(list, workers, func(data commonmeta.Data) result {
 		record := commonmeta.APIResponse{
 			DOI: data.ID,
 		}
 		record, err := Upsert(record, account, data)
-		if err != nil {
-			fmt.Println(err)
+		return result{record, err}
+	})
+
+	var records []commonmeta.APIResponse
+	var errs []error
+	for i, r := range results {
+		if r.err != nil {
+			r.record.Error = r.err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", list[i].ID, r.err))
 		}
-		records = append(records, record)
+		records = append(records, r.record)
 	}
+	return records, errors.Join(errs...)
+}
 
-	return records, nil
+// Delete deletes a DOI. DataCite only allows deleting draft DOIs, registered
+// and findable DOIs can only be hidden.
+func Delete(record commonmeta.APIResponse, account Account) (commonmeta.APIResponse, error) {
+	doi, ok := doiutils.ValidateDOI(record.DOI)
+	if !ok {
+		record.Status = "failed_missing_doi"
+		return record, fmt.Errorf("%w: invalid DOI %s", commonmeta.ErrValidation, record.DOI)
+	}
+	state, err := GetState(doi, account)
+	if err != nil {
+		record.Status = "failed"
+		record.Message = err.Error()
+		return record, err
+	}
+	if state != "draft" {
+		record.Status = state
+		return record, fmt.Errorf("%w: only draft DOIs can be deleted, %s is %s", commonmeta.ErrValidation, doi, state)
+	}
+	requestURL := config.DataCiteURL(account.Development) + "/dois/" + doi
+	_, err = doRequest(http.MethodDelete, requestURL, account, nil)
+	if err != nil {
+		record.Status = "failed"
+		record.Message = err.Error()
+		audit.Record("delete", "datacite", doi, nil, record.Status, err)
+		return record, err
+	}
+	record.Status = "deleted"
+	audit.Record("delete", "datacite", doi, nil, record.Status, nil)
+	return record, nil
 }
@@ -3,12 +3,16 @@ package datacite_test
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/crossref"
 	"github.com/front-matter/commonmeta/datacite"
 	"github.com/front-matter/commonmeta/doiutils"
@@ -67,3 +71,95 @@ func TestWrite(t *testing.T) {
 		}
 	}
 }
+
+func TestUpsert(t *testing.T) {
+	states := map[string]string{"10.57689/existing": "findable"}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, _ := r.BasicAuth(); user != "client" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		doi := strings.TrimPrefix(r.URL.Path, "/dois/")
+		switch r.Method {
+		case http.MethodGet:
+			state, ok := states[doi]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprintf(w, `{"data":{"id":"%s","attributes":{"doi":"%s","state":"%s"}}}`, doi, doi, state)
+		case http.MethodPost, http.MethodPut:
+			var body struct {
+				Data struct {
+					Attributes struct {
+						DOI   string `json:"doi"`
+						Event string `json:"event"`
+					} `json:"attributes"`
+				} `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			state := map[string]string{"": "draft", "register": "registered", "publish": "findable", "hide": "registered"}[body.Data.Attributes.Event]
+			doi = strings.ToLower(body.Data.Attributes.DOI)
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusCreated)
+			}
+			fmt.Fprintf(w, `{"data":{"id":"%s","attributes":{"doi":"%s","state":"%s"}}}`, doi, doi, state)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{DataCiteSandbox: ts.URL})
+	defer config.ResetEndpoints()
+
+	data, err := commonmeta.Load("../testdata/commonmeta/journal_article.commonmeta.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		id    string
+		event string
+		want  string
+	}
+	testCases := []testCase{
+		{id: "https://doi.org/10.57689/new", event: "draft", want: "draft"},
+		{id: "https://doi.org/10.57689/new", event: "register", want: "registered"},
+		{id: "https://doi.org/10.57689/existing", event: "", want: "findable"},
+		{id: "https://doi.org/10.57689/existing", event: "hide", want: "registered"},
+	}
+	for _, tc := range testCases {
+		data.ID = tc.id
+		account := datacite.Account{Client: "client", Password: "secret", Development: true, Event: tc.event}
+		got, err := datacite.Upsert(commonmeta.APIResponse{DOI: tc.id}, account, data)
+		if err != nil {
+			t.Errorf("Upsert(%v, %v): error %v", tc.id, tc.event, err)
+		}
+		if tc.want != got.Status {
+			t.Errorf("Upsert(%v, %v): want %v, got %v", tc.id, tc.event, tc.want, got.Status)
+		}
+	}
+
+	account := datacite.Account{Client: "client", Password: "secret", Development: true, Event: "unpublish"}
+	_, err = datacite.Upsert(commonmeta.APIResponse{}, account, data)
+	if !errors.Is(err, commonmeta.ErrValidation) {
+		t.Errorf("Upsert(unpublish): want %v, got %v", commonmeta.ErrValidation, err)
+	}
+
+	account = datacite.Account{Client: "other", Password: "secret", Development: true}
+	_, err = datacite.Upsert(commonmeta.APIResponse{}, account, data)
+	if !errors.Is(err, commonmeta.ErrUnauthorized) {
+		t.Errorf("Upsert(other): want %v, got %v", commonmeta.ErrUnauthorized, err)
+	}
+
+	account.Client = "client"
+	states["10.57689/draft"] = "draft"
+	got, err := datacite.Delete(commonmeta.APIResponse{DOI: "https://doi.org/10.57689/draft"}, account)
+	if err != nil || got.Status != "deleted" {
+		t.Errorf("Delete(draft): want deleted, got %v %v", got.Status, err)
+	}
+	_, err = datacite.Delete(commonmeta.APIResponse{DOI: "https://doi.org/10.57689/existing"}, account)
+	if !errors.Is(err, commonmeta.ErrValidation) {
+		t.Errorf("Delete(existing): want %v, got %v", commonmeta.ErrValidation, err)
+	}
+}
@@ -14,7 +14,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/crockford"
+	"github.com/front-matter/commonmeta/httputils"
 )
 
 // PrefixFromUrl extracts DOI prefix from URL
@@ -92,9 +94,7 @@ func IsRegisteredDOI(doi string) bool {
 	if url == "" {
 		return false
 	}
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := httputils.NewClient(10 * time.Second)
 	req, err := http.NewRequest(http.MethodHead, url, nil)
 	if err != nil {
 		return false
@@ -168,10 +168,11 @@ func GetDOIRA(doi string) (string, bool) {
 		RA  string `json:"RA"`
 	}
 	var result Response
-	resp, err := http.Get(fmt.Sprintf("https://doi.org/ra/%s", prefix))
+	resp, err := httputils.NewClient(10 * time.Second).Get(fmt.Sprintf("%s/ra/%s", config.GetEndpoints().DOIResolver, prefix))
 	if err != nil {
 		return "", false
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode == 404 {
 		return "", false
 	}
@@ -179,9 +180,8 @@ func GetDOIRA(doi string) (string, bool) {
 	if err != nil {
 		return "", false
 	}
-	defer resp.Body.Close()
 	err = json.Unmarshal(body, &result)
-	if err != nil {
+	if err != nil || len(result) == 0 {
 		return "", false
 	}
 	return result[0].RA, true
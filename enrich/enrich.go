@@ -0,0 +1,417 @@
+// Package enrich fills missing fields of commonmeta records from other
+// sources: the metadata registered for the same DOI with Crossref, DataCite
+// or OpenAlex, ROR IDs matched to affiliation names, and SPDX license IDs.
+// Every added field is recorded together with its source.
+package enrich
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/crossref"
+	"github.com/front-matter/commonmeta/datacite"
+	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/openalex"
+	"github.com/front-matter/commonmeta/ror"
+	"github.com/front-matter/commonmeta/spdx"
+	"gopkg.in/yaml.v3"
+)
+
+// Fields that can be enriched.
+const (
+	FieldAbstract   = "abstract"
+	FieldLicense    = "license"
+	FieldReferences = "references"
+	FieldFunding    = "funding"
+	FieldORCID      = "orcid"
+	FieldROR        = "ror"
+)
+
+// Sources of enrichment. Crossref, DataCite and OpenAlex are looked up by
+// DOI, ROR matches affiliation names and SPDX normalizes licenses.
+const (
+	SourceCrossref = "crossref"
+	SourceDataCite = "datacite"
+	SourceOpenAlex = "openalex"
+	SourceROR      = "ror"
+	SourceSPDX     = "spdx"
+)
+
+// DOISources are the sources looked up by DOI.
+var DOISources = []string{SourceCrossref, SourceDataCite, SourceOpenAlex}
+
+// Rules define for every field the sources it is filled from, in order of
+// preference. A field without sources is not enriched. Rules can be loaded
+// from a YAML file, e.g.
+//
+//	abstract: [crossref, datacite]
+//	license: [crossref, spdx]
+//	references: []
+//	ror: [ror]
+type Rules struct {
+	Abstract   []string `yaml:"abstract"`
+	License    []string `yaml:"license"`
+	References []string `yaml:"references"`
+	Funding    []string `yaml:"funding"`
+	ORCID      []string `yaml:"orcid"`
+	ROR        []string `yaml:"ror"`
+}
+
+// DefaultRules returns the rules used if no rules file is given: all fields
+// are filled from Crossref, DataCite and OpenAlex, licenses are normalized
+// via SPDX and affiliations without ROR ID are matched via ROR.
+func DefaultRules() Rules {
+	return Rules{
+		Abstract:   []string{SourceCrossref, SourceDataCite, SourceOpenAlex},
+		License:    []string{SourceCrossref, SourceDataCite, SourceOpenAlex, SourceSPDX},
+		References: []string{SourceCrossref, SourceOpenAlex},
+		Funding:    []string{SourceCrossref, SourceDataCite, SourceOpenAlex},
+		ORCID:      []string{SourceCrossref, SourceDataCite, SourceOpenAlex},
+		ROR:        []string{SourceCrossref, SourceDataCite, SourceOpenAlex, SourceROR},
+	}
+}
+
+// LoadRules loads rules from a YAML file. Fields missing from the file keep
+// their default.
+func LoadRules(filename string) (Rules, error) {
+	r := DefaultRules()
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return r, err
+	}
+	err = yaml.Unmarshal(content, &r)
+	if err != nil {
+		return r, err
+	}
+	return r, r.Validate()
+}
+
+// rule is the list of sources of a field.
+type rule struct {
+	field   string
+	sources []string
+}
+
+// list returns the rules of all fields, in the order fields are enriched.
+func (r Rules) list() []rule {
+	return []rule{
+		{FieldAbstract, r.Abstract},
+		{FieldLicense, r.License},
+		{FieldReferences, r.References},
+		{FieldFunding, r.Funding},
+		{FieldORCID, r.ORCID},
+		{FieldROR, r.ROR},
+	}
+}
+
+// Validate checks that the rules only use sources supported for a field.
+func (r Rules) Validate() error {
+	for _, rule := range r.list() {
+		for _, source := range rule.sources {
+			ok := slices.Contains(DOISources, source) ||
+				source == SourceSPDX && rule.field == FieldLicense ||
+				source == SourceROR && rule.field == FieldROR
+			if !ok {
+				return fmt.Errorf("%w: %s can't be enriched from %s", commonmeta.ErrValidation, rule.field, source)
+			}
+		}
+	}
+	return nil
+}
+
+// Change records a field added to a record, and where it came from.
+type Change struct {
+	ID     string `json:"id"`
+	Field  string `json:"field"`
+	Source string `json:"source"`
+	Value  string `json:"value,omitempty"`
+}
+
+// Enricher enriches records following a set of rules. Records fetched by
+// DOI are cached, so every source is queried at most once per DOI.
+type Enricher struct {
+	rules  Rules
+	email  string
+	cache  map[string]commonmeta.Data
+	errors map[string]error
+}
+
+// New returns an enricher. The email is sent to OpenAlex to use the polite pool.
+func New(rules Rules, email string) *Enricher {
+	return &Enricher{
+		rules:  rules,
+		email:  email,
+		cache:  make(map[string]commonmeta.Data),
+		errors: make(map[string]error),
+	}
+}
+
+// EnrichAll enriches a list of records.
+func (e *Enricher) EnrichAll(list []commonmeta.Data) ([]commonmeta.Data, []Change, error) {
+	var changes []Change
+	var errs []error
+	for i := range list {
+		data, c, err := e.Enrich(list[i])
+		list[i] = data
+		changes = append(changes, c...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return list, changes, errors.Join(errs...)
+}
+
+// Enrich fills the missing fields of a record, and returns the record and
+// the changes made. Errors of a source are returned after the record was
+// enriched from the remaining sources. Records not registered with a source
+// are skipped.
+func (e *Enricher) Enrich(data commonmeta.Data) (commonmeta.Data, []Change, error) {
+	var changes []Change
+	var errs []error
+	failed := make(map[string]bool)
+	for _, rule := range e.rules.list() {
+		for _, source := range rule.sources {
+			var values []string
+			var err error
+			switch {
+			case source == SourceSPDX:
+				values = normalizeLicense(&data)
+			case source == SourceROR:
+				values, err = matchROR(&data)
+			default:
+				var other commonmeta.Data
+				other, err = e.fetch(source, data.ID)
+				if err == nil && other.ID != "" {
+					values = fill(&data, rule.field, other)
+				}
+			}
+			if err != nil && !failed[source] {
+				failed[source] = true
+				errs = append(errs, err)
+			}
+			for _, value := range values {
+				changes = append(changes, Change{ID: data.ID, Field: rule.field, Source: source, Value: value})
+			}
+		}
+	}
+	return data, changes, errors.Join(errs...)
+}
+
+// fetch returns the metadata of a DOI registered with a source, or empty
+// metadata if the id is not a DOI or not registered with the source.
+func (e *Enricher) fetch(source string, id string) (commonmeta.Data, error) {
+	var data commonmeta.Data
+	doi, ok := doiutils.ValidateDOI(id)
+	if !ok {
+		return data, nil
+	}
+	key := source + " " + strings.ToLower(doi)
+	if data, ok := e.cache[key]; ok {
+		return data, nil
+	}
+	if err, ok := e.errors[key]; ok {
+		return data, err
+	}
+	var err error
+	switch source {
+	case SourceCrossref:
+		data, err = crossref.Fetch(doi, false)
+	case SourceDataCite:
+		data, err = datacite.Fetch(doi, false)
+	case SourceOpenAlex:
+		data, err = openalex.NewReader(e.email).Fetch(doiutils.NormalizeDOI(doi))
+	}
+	if errors.Is(err, commonmeta.ErrNotFound) {
+		data, err = commonmeta.Data{}, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("%s %s: %w", source, doi, err)
+		e.errors[key] = err
+		return commonmeta.Data{}, err
+	}
+	e.cache[key] = data
+	return data, nil
+}
+
+// fill copies a field missing in data from other, and returns the values added.
+func fill(data *commonmeta.Data, field string, other commonmeta.Data) []string {
+	var values []string
+	switch field {
+	case FieldAbstract:
+		if hasAbstract(*data) {
+			return nil
+		}
+		for _, d := range other.Descriptions {
+			if d.Type == "Abstract" {
+				data.Descriptions = append(data.Descriptions, d)
+				return []string{d.Description}
+			}
+		}
+	case FieldLicense:
+		if data.License.ID == "" && data.License.URL == "" && (other.License.ID != "" || other.License.URL != "") {
+			data.License = other.License
+			return []string{licenseValue(data.License)}
+		}
+	case FieldReferences:
+		if len(data.References) == 0 && len(other.References) > 0 {
+			data.References = other.References
+			return []string{fmt.Sprintf("%d references", len(other.References))}
+		}
+	case FieldFunding:
+		if len(data.FundingReferences) == 0 && len(other.FundingReferences) > 0 {
+			data.FundingReferences = other.FundingReferences
+			return []string{fmt.Sprintf("%d funding references", len(other.FundingReferences))}
+		}
+	case FieldORCID:
+		for i, c := range data.Contributors {
+			if c.ID != "" || c.Type != "Person" {
+				continue
+			}
+			if o := findContributor(other.Contributors, c); o != nil && o.ID != "" {
+				data.Contributors[i].ID = o.ID
+				values = append(values, contributorName(c)+" "+o.ID)
+			}
+		}
+	case FieldROR:
+		for i, c := range data.Contributors {
+			o := findContributor(other.Contributors, c)
+			if o == nil {
+				continue
+			}
+			for j, a := range c.Affiliations {
+				if a == nil || a.ID != "" || a.Name == "" {
+					continue
+				}
+				idx := slices.IndexFunc(o.Affiliations, func(b *commonmeta.Affiliation) bool {
+					return b != nil && b.ID != "" && strings.EqualFold(b.Name, a.Name)
+				})
+				if idx == -1 {
+					continue
+				}
+				affiliation := *a
+				affiliation.ID = o.Affiliations[idx].ID
+				affiliation.AssertedBy = o.Affiliations[idx].AssertedBy
+				data.Contributors[i].Affiliations[j] = &affiliation
+				values = append(values, a.Name+" "+affiliation.ID)
+			}
+		}
+	}
+	return values
+}
+
+// matchROR matches the names of affiliations without ROR ID against ROR,
+// and returns the IDs added.
+func matchROR(data *commonmeta.Data) ([]string, error) {
+	type match struct {
+		id         string
+		assertedBy string
+	}
+	var values []string
+	var errs []error
+	matched := make(map[string]match)
+	for i, c := range data.Contributors {
+		for j, a := range c.Affiliations {
+			if a == nil || a.ID != "" || a.Name == "" {
+				continue
+			}
+			m, ok := matched[a.Name]
+			if !ok {
+				var err error
+				m.id, _, m.assertedBy, err = ror.MapROR("", a.Name, a.AssertedBy, true)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("ror %s: %w", a.Name, err))
+					continue
+				}
+				matched[a.Name] = m
+			}
+			if m.id == "" {
+				continue
+			}
+			affiliation := *a
+			affiliation.ID = m.id
+			affiliation.AssertedBy = m.assertedBy
+			data.Contributors[i].Affiliations[j] = &affiliation
+			values = append(values, a.Name+" "+m.id)
+		}
+	}
+	return values, errors.Join(errs...)
+}
+
+// normalizeLicense sets the SPDX license ID and URL of a license given by
+// ID or URL, and returns the normalized license if it changed.
+func normalizeLicense(data *commonmeta.Data) []string {
+	if data.License.ID == "" && data.License.URL == "" {
+		return nil
+	}
+	key := data.License.ID
+	if key == "" {
+		key = data.License.URL
+	}
+	license, err := spdx.Search(key)
+	if err != nil || license.LicenseID == "" {
+		return nil
+	}
+	normalized := commonmeta.License{ID: license.LicenseID, URL: data.License.URL}
+	if normalized.URL == "" && len(license.SeeAlso) > 0 {
+		normalized.URL = license.SeeAlso[0]
+	}
+	if normalized == data.License {
+		return nil
+	}
+	data.License = normalized
+	return []string{licenseValue(normalized)}
+}
+
+func hasAbstract(data commonmeta.Data) bool {
+	return slices.ContainsFunc(data.Descriptions, func(d commonmeta.Description) bool {
+		return d.Type == "Abstract"
+	})
+}
+
+func licenseValue(license commonmeta.License) string {
+	return strings.TrimSpace(license.ID + " " + license.URL)
+}
+
+// findContributor returns the contributor in list that is the same person
+// or organization as c, or nil if no or several contributors match. A
+// person has the same family name and, if both are given, the same given
+// name, or only the same initial if no contributor has the same given name.
+// An organization has the same name.
+func findContributor(list []commonmeta.Contributor, c commonmeta.Contributor) *commonmeta.Contributor {
+	var full, partial []int
+	for i, o := range list {
+		if c.FamilyName != "" && strings.EqualFold(c.FamilyName, o.FamilyName) {
+			if c.GivenName != "" && strings.EqualFold(c.GivenName, o.GivenName) {
+				full = append(full, i)
+			} else if c.GivenName == "" || o.GivenName == "" || strings.EqualFold(initial(c.GivenName), initial(o.GivenName)) {
+				partial = append(partial, i)
+			}
+		} else if c.FamilyName == "" && c.Name != "" && strings.EqualFold(c.Name, contributorName(o)) {
+			full = append(full, i)
+		}
+	}
+	if len(full) == 1 {
+		return &list[full[0]]
+	} else if len(full) == 0 && len(partial) == 1 {
+		return &list[partial[0]]
+	}
+	return nil
+}
+
+func initial(name string) string {
+	for _, r := range name {
+		return string(r)
+	}
+	return ""
+}
+
+func contributorName(c commonmeta.Contributor) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return strings.TrimSpace(c.GivenName + " " + c.FamilyName)
+}
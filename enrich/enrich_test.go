@@ -0,0 +1,228 @@
+package enrich_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
+	"github.com/front-matter/commonmeta/enrich"
+)
+
+// crossrefWork is a work in the Crossref API with the fields missing from
+// the record to enrich.
+const crossrefWork = `{"status":"ok","message":{
+	"DOI":"10.5555/enrich.1",
+	"type":"journal-article",
+	"title":["An enriched article"],
+	"abstract":"<jats:p>The abstract.</jats:p>",
+	"license":[{"URL":"https://creativecommons.org/licenses/by/4.0/","content-version":"vor"}],
+	"reference":[{"key":"ref1","DOI":"10.5555/ref.1"}],
+	"author":[{"given":"Jane","family":"Doe","ORCID":"https://orcid.org/0000-0002-1825-0097","sequence":"first","affiliation":[{"name":"Example University","id":[{"id":"https://ror.org/05dxps055","id-type":"ROR","asserted-by":"publisher"}]}]}]
+}}`
+
+// crossrefAuthors is a work in the Crossref API with authors of the same
+// family name.
+const crossrefAuthors = `{"status":"ok","message":{
+	"DOI":"10.5555/enrich.2",
+	"type":"journal-article",
+	"title":["An article by the Does"],
+	"author":[
+		{"given":"Jane","family":"Doe","ORCID":"https://orcid.org/0000-0002-1825-0097","sequence":"first"},
+		{"given":"John","family":"Doe","ORCID":"https://orcid.org/0000-0001-5109-3700","sequence":"additional"},
+		{"given":"Anna","family":"Roe","ORCID":"https://orcid.org/0000-0002-1694-233X","sequence":"additional"}
+	]
+}}`
+
+// newAPI returns a stand-in for the Crossref, DataCite, OpenAlex and ROR
+// APIs, that only knows the Crossref work and one organization.
+func newAPI(t *testing.T, requests *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/crossref/works/10.5555/enrich.1":
+			w.Write([]byte(crossrefWork))
+		case r.URL.Path == "/crossref/works/10.5555/enrich.2":
+			w.Write([]byte(crossrefAuthors))
+		case r.URL.Path == "/ror/v2/organizations" && r.URL.Query().Get("affiliation") == "Other Institute":
+			w.Write([]byte(`{"number_of_results":1,"items":[{"chosen":true,"score":1,"organization":{"id":"https://ror.org/0abcde123"}}]}`))
+		case r.URL.Path == "/ror/v2/organizations":
+			w.Write([]byte(`{"number_of_results":0,"items":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func record() commonmeta.Data {
+	return commonmeta.Data{
+		ID:   "https://doi.org/10.5555/enrich.1",
+		Type: "Article",
+		Contributors: []commonmeta.Contributor{
+			{Type: "Person", GivenName: "Jane", FamilyName: "Doe", Affiliations: []*commonmeta.Affiliation{{Name: "Example University"}}},
+			{Type: "Person", GivenName: "John", FamilyName: "Roe", Affiliations: []*commonmeta.Affiliation{{Name: "Other Institute"}}},
+		},
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	var requests []string
+	ts := newAPI(t, &requests)
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{
+		CrossrefAPI: ts.URL + "/crossref",
+		DataCiteAPI: ts.URL + "/datacite",
+		OpenAlexAPI: ts.URL + "/openalex",
+		RORAPI:      ts.URL + "/ror",
+	})
+	defer config.ResetEndpoints()
+
+	e := enrich.New(enrich.DefaultRules(), "")
+	data, changes, err := e.Enrich(record())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		field string
+		want  string
+		got   string
+	}
+	var abstract string
+	if len(data.Descriptions) > 0 {
+		abstract = data.Descriptions[0].Description
+	}
+	testCases := []testCase{
+		{field: "abstract", want: "The abstract.", got: abstract},
+		{field: "license", want: "CC-BY-4.0", got: data.License.ID},
+		{field: "orcid", want: "https://orcid.org/0000-0002-1825-0097", got: data.Contributors[0].ID},
+		{field: "ror from crossref", want: "https://ror.org/05dxps055", got: data.Contributors[0].Affiliations[0].ID},
+		{field: "ror from ror", want: "https://ror.org/0abcde123", got: data.Contributors[1].Affiliations[0].ID},
+		{field: "orcid not found", want: "", got: data.Contributors[1].ID},
+	}
+	for _, tc := range testCases {
+		if tc.want != tc.got {
+			t.Errorf("Enrich(%v): want %v, got %v", tc.field, tc.want, tc.got)
+		}
+	}
+	if len(data.References) != 1 {
+		t.Errorf("Enrich(references): want 1, got %v", len(data.References))
+	}
+
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Field+":"+c.Source)
+	}
+	want := "abstract:crossref license:crossref references:crossref orcid:crossref ror:crossref ror:ror"
+	if want != strings.Join(got, " ") {
+		t.Errorf("Enrich(changes): want %v, got %v", want, strings.Join(got, " "))
+	}
+
+	// every source is queried once per DOI
+	requests = nil
+	e.Enrich(record())
+	for _, r := range requests {
+		if !strings.HasPrefix(r, "/ror") {
+			t.Errorf("Enrich: want cached records, got request %v", r)
+		}
+	}
+}
+
+func TestEnrichContributors(t *testing.T) {
+	var requests []string
+	ts := newAPI(t, &requests)
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{CrossrefAPI: ts.URL + "/crossref"})
+	defer config.ResetEndpoints()
+
+	input := commonmeta.Data{
+		ID:   "https://doi.org/10.5555/enrich.2",
+		Type: "Article",
+		Contributors: []commonmeta.Contributor{
+			{Type: "Person", GivenName: "John", FamilyName: "Doe"},
+			{Type: "Person", GivenName: "J.", FamilyName: "Doe"},
+			{Type: "Person", FamilyName: "Doe"},
+			{Type: "Person", GivenName: "A.", FamilyName: "Roe"},
+		},
+	}
+	rules := enrich.Rules{ORCID: []string{enrich.SourceCrossref}}
+	data, _, err := enrich.New(rules, "").Enrich(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testCase struct {
+		name string
+		want string
+		got  string
+	}
+	testCases := []testCase{
+		{name: "same given name", want: "https://orcid.org/0000-0001-5109-3700", got: data.Contributors[0].ID},
+		{name: "ambiguous initial", want: "", got: data.Contributors[1].ID},
+		{name: "no given name", want: "", got: data.Contributors[2].ID},
+		{name: "same initial", want: "https://orcid.org/0000-0002-1694-233X", got: data.Contributors[3].ID},
+	}
+	for _, tc := range testCases {
+		if tc.want != tc.got {
+			t.Errorf("Enrich(%v): want %v, got %v", tc.name, tc.want, tc.got)
+		}
+	}
+}
+
+func TestNormalizeLicense(t *testing.T) {
+	t.Parallel()
+	rules := enrich.Rules{License: []string{enrich.SourceSPDX}}
+	type testCase struct {
+		input commonmeta.License
+		want  commonmeta.License
+	}
+	testCases := []testCase{
+		{input: commonmeta.License{ID: "cc-by-4.0"}, want: commonmeta.License{ID: "CC-BY-4.0", URL: "https://creativecommons.org/licenses/by/4.0/legalcode"}},
+		{input: commonmeta.License{URL: "https://creativecommons.org/licenses/by/4.0/legalcode"}, want: commonmeta.License{ID: "CC-BY-4.0", URL: "https://creativecommons.org/licenses/by/4.0/legalcode"}},
+		{input: commonmeta.License{ID: "BSL"}, want: commonmeta.License{ID: "BSL"}},
+	}
+	for _, tc := range testCases {
+		data, _, err := enrich.New(rules, "").Enrich(commonmeta.Data{ID: "https://example.org/1", License: tc.input})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != data.License {
+			t.Errorf("Enrich(%v): want %v, got %v", tc.input, tc.want, data.License)
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	t.Parallel()
+	type testCase struct {
+		content string
+		want    []string
+		err     error
+	}
+	testCases := []testCase{
+		{content: "abstract: [datacite]\n", want: []string{enrich.SourceDataCite}},
+		{content: "license: [spdx]\n", want: enrich.DefaultRules().Abstract},
+		{content: "abstract: [ror]\n", err: commonmeta.ErrValidation},
+	}
+	for _, tc := range testCases {
+		path := filepath.Join(t.TempDir(), "rules.yaml")
+		err := os.WriteFile(path, []byte(tc.content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules, err := enrich.LoadRules(path)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("LoadRules(%v): want %v, got %v", tc.content, tc.err, err)
+			continue
+		}
+		if tc.err == nil && strings.Join(tc.want, " ") != strings.Join(rules.Abstract, " ") {
+			t.Errorf("LoadRules(%v): want %v, got %v", tc.content, tc.want, rules.Abstract)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"archive/zip"
 	"bytes"
 	"compress/gzip"
-	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -14,6 +13,8 @@ import (
 	"time"
 
 	"github.com/schollz/progressbar/v3"
+
+	"github.com/front-matter/commonmeta/httputils"
 )
 
 func ReadFile(filename string) ([]byte, error) {
@@ -120,15 +121,17 @@ func ReadZIPFile(filename string, name string) ([]byte, error) {
 func DownloadFile(url string, progress bool) ([]byte, error) {
 	var output []byte
 
-	client := &http.Client{}
+	// downloads bypass the on-disk cache and have no timeout
+	client := &http.Client{Transport: httputils.DefaultTransport()}
 	resp, err := client.Get(url)
 	if err != nil {
 		return output, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return output, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return output, err
 	}
 
 	// Create a buffer to store the response body
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/jsonfeed"
 	"github.com/golang-jwt/jwt"
 )
@@ -78,9 +79,7 @@ func UpdateGhostPost(id string, apiKey string, apiURL string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
+	client := httputils.Client()
 	u, _ := url.Parse(urlString)
 	path := strings.Split(u.Path, "/")
 	slug := path[len(path)-1]
@@ -95,10 +94,11 @@ func UpdateGhostPost(id string, apiKey string, apiURL string) (string, error) {
 		fmt.Println("Error fetching post", err)
 		return "", err
 	}
-	if resp.StatusCode >= 400 {
-		return "", errors.New("Error fetching post: " + resp.Status)
-	}
 	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return "", err
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
@@ -137,8 +137,9 @@ func UpdateGhostPost(id string, apiKey string, apiURL string) (string, error) {
 		return "", err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", errors.New("error updating post: " + resp.Status)
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return "", err
 	}
 	message := fmt.Sprintf("Canonical URL %s updated for GUID %s at %s", doi, guid, updatedAt)
 	return message, nil
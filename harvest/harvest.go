@@ -0,0 +1,245 @@
+// Package harvest fetches records from the Crossref, DataCite, OpenAlex and
+// InvenioRDM APIs incrementally. A local state file records a checkpoint per
+// source and query: the newest indexed or updated timestamp of the last
+// complete harvest, and the next page of a harvest that was interrupted.
+package harvest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/httputils"
+	"github.com/front-matter/commonmeta/inveniordm"
+)
+
+// Checkpoint is the harvest state of a query against a source.
+type Checkpoint struct {
+	Source string `json:"source"`
+	Query  string `json:"query"`
+	// Since is the newest indexed or updated timestamp of the last complete
+	// harvest, the next harvest starts there.
+	Since time.Time `json:"since,omitzero"`
+	// From, Latest and Next record a harvest in progress: the timestamp it
+	// started from, the newest timestamp seen so far, and the URL of the
+	// next page.
+	From      time.Time `json:"from,omitzero"`
+	Latest    time.Time `json:"latest,omitzero"`
+	Next      string    `json:"next,omitempty"`
+	Harvested int       `json:"harvested"`
+	// Skipped counts the records that couldn't be read.
+	Skipped int       `json:"skipped,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// Result is the result of a harvest: the records fetched, the errors of
+// the records that couldn't be read and were skipped, and the checkpoint
+// to commit once the records are written.
+type Result struct {
+	Data       []commonmeta.Data
+	Skipped    []error
+	Checkpoint Checkpoint
+}
+
+// Options configures a harvest.
+type Options struct {
+	// Number is the maximum number of records fetched in one run, 0 for no
+	// limit. It is also the page size, within the limits of the source.
+	Number int
+	// Since is where the first harvest of a query starts, zero for all records.
+	Since time.Time
+	// Match enables matching of references and funders when reading records.
+	Match bool
+	// Email is sent to OpenAlex to use the polite pool.
+	Email string
+	// Token authenticates requests to InvenioRDM.
+	Token string
+	// Client is the InvenioRDM client, required for the inveniordm source.
+	Client *inveniordm.InvenioRDMClient
+}
+
+// State is the harvest state in a JSON file.
+type State struct {
+	path        string
+	mu          sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+// DefaultPath returns the default harvest state: harvest.json in
+// $XDG_STATE_HOME/commonmeta, or ~/.local/state/commonmeta.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "commonmeta", "harvest.json")
+}
+
+// Open opens the harvest state at path. A missing file is an empty state.
+func Open(path string) (*State, error) {
+	s := &State{path: path, checkpoints: make(map[string]Checkpoint)}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var list []Checkpoint
+	err = json.Unmarshal(content, &list)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range list {
+		s.checkpoints[Key(c.Source, c.Query)] = c
+	}
+	return s, nil
+}
+
+// Save writes the harvest state to disk, replacing the file atomically.
+func (s *State) Save() error {
+	list := s.List()
+	content, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".harvest-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Key returns the key of the checkpoint of a query against a source.
+func Key(source string, query string) string {
+	return source + " " + query
+}
+
+// Get returns the checkpoint of a query against a source, or an empty
+// checkpoint if the query has not been harvested.
+func (s *State) Get(source string, query string) Checkpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.checkpoints[Key(source, query)]
+	if !ok {
+		return Checkpoint{Source: source, Query: query}
+	}
+	return c
+}
+
+// Commit stores a checkpoint and saves the harvest state.
+func (s *State) Commit(c Checkpoint) error {
+	s.Put(c)
+	return s.Save()
+}
+
+// Put stores a checkpoint.
+func (s *State) Put(c Checkpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.Updated = time.Now().UTC()
+	s.checkpoints[Key(c.Source, c.Query)] = c
+}
+
+// List returns all checkpoints, sorted by key.
+func (s *State) List() []Checkpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Checkpoint, 0, len(s.checkpoints))
+	for _, c := range s.checkpoints {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return Key(list[i].Source, list[i].Query) < Key(list[j].Source, list[j].Query)
+	})
+	return list
+}
+
+// Run harvests the records of a query against a source that were indexed or
+// updated since the last complete harvest. The state is not changed: the
+// result includes the checkpoint of the harvest, to commit once the records
+// are written. A harvest stopped by options.Number or by an error resumes
+// with the next page in the following run, and returns the records fetched
+// before the error. The query is the API URL returned by the QueryURL
+// function of the source package, paging and sorting parameters are
+// ignored.
+func Run(state *State, source string, query string, options Options) (Result, error) {
+	var result Result
+	s, ok := sources[source]
+	if !ok {
+		return result, fmt.Errorf("%w: harvesting from %s is not supported", commonmeta.ErrValidation, source)
+	}
+	if source == "inveniordm" && options.Client == nil {
+		return result, fmt.Errorf("%w: harvesting from InvenioRDM requires a client", commonmeta.ErrValidation)
+	}
+	query, err := s.normalize(query)
+	if err != nil {
+		return result, err
+	}
+
+	c := state.Get(source, query)
+	next := c.Next
+	resumed := next != ""
+	if !resumed {
+		c.From = c.Since
+		if c.From.IsZero() {
+			c.From = options.Since
+		}
+		c.Latest = c.From
+		next = s.first(query, c.From, options)
+	}
+	for next != "" && (options.Number <= 0 || len(result.Data) < options.Number) {
+		page, err := fetch(s, next, options)
+		var statusError *httputils.StatusError
+		if resumed && errors.As(err, &statusError) && (statusError.StatusCode == http.StatusBadRequest || statusError.StatusCode == http.StatusNotFound) {
+			// the cursor has expired, restart from the newest record seen
+			next = s.first(query, c.Latest, options)
+			page, err = fetch(s, next, options)
+		}
+		resumed = false
+		if err != nil {
+			c.Next = next
+			result.Checkpoint = c
+			return result, err
+		}
+		result.Data = append(result.Data, page.data...)
+		result.Skipped = append(result.Skipped, page.skipped...)
+		c.Harvested += len(page.data)
+		c.Skipped += len(page.skipped)
+		if page.latest.After(c.Latest) {
+			c.Latest = page.latest
+		}
+		next = page.next
+		c.Next = next
+	}
+	if next == "" {
+		// the harvest is complete
+		c.Since = c.Latest
+		c.From = time.Time{}
+		c.Latest = time.Time{}
+	}
+	result.Checkpoint = c
+	return result, nil
+}
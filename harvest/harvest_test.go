@@ -0,0 +1,261 @@
+package harvest_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
+	"github.com/front-matter/commonmeta/crossref"
+	"github.com/front-matter/commonmeta/datacite"
+	"github.com/front-matter/commonmeta/harvest"
+)
+
+// crossrefItem returns a minimal work in the Crossref works API.
+func crossrefItem(n int, indexed string) string {
+	return fmt.Sprintf(`{"DOI":"10.5555/harvest.%d","type":"journal-article","title":["Work %d"],"indexed":{"date-time":"%s"}}`, n, n, indexed)
+}
+
+// newCrossref returns a stand-in Crossref API with three works, two per
+// page, and logs the filter of every request.
+func newCrossref(t *testing.T, filters *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		*filters = append(*filters, query.Get("filter"))
+		if query.Get("sort") != "indexed" || query.Get("order") != "asc" || query.Get("offset") != "" {
+			http.Error(w, "unexpected request "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		var items []string
+		var next string
+		switch {
+		case query.Get("cursor") == "expired":
+			http.Error(w, "cursor expired", http.StatusBadRequest)
+			return
+		case strings.Contains(query.Get("filter"), "from-index-date:2025-02-01"):
+			items = []string{crossrefItem(3, "2025-02-01T12:00:00Z")}
+			next = "c3"
+		case query.Get("cursor") == "*":
+			items = []string{crossrefItem(1, "2025-01-10T12:00:00Z"), crossrefItem(2, "2025-01-20T12:00:00Z")}
+			next = "c2"
+		case query.Get("cursor") == "c2":
+			items = []string{crossrefItem(3, "2025-02-01T12:00:00Z")}
+			next = "c3"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"ok","message":{"next-cursor":"%s","items":[%s]}}`, next, strings.Join(items, ","))
+	}))
+}
+
+func ids(data []commonmeta.Data) string {
+	var list []string
+	for _, d := range data {
+		list = append(list, d.ID)
+	}
+	return strings.Join(list, " ")
+}
+
+func TestRunCrossref(t *testing.T) {
+	var filters []string
+	ts := newCrossref(t, &filters)
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{CrossrefAPI: ts.URL})
+	defer config.ResetEndpoints()
+
+	path := filepath.Join(t.TempDir(), "harvest.json")
+	query := crossref.QueryURL(2, 1, "", "journal-article", false, "", "", "", false, false, false, false, false, false, false, false)
+
+	type testCase struct {
+		name   string
+		want   string
+		filter string
+		since  string
+		next   bool
+	}
+	testCases := []testCase{
+		{name: "first run stops after number", want: "https://doi.org/10.5555/harvest.1 https://doi.org/10.5555/harvest.2", filter: "type:journal-article", next: true},
+		{name: "second run resumes", want: "https://doi.org/10.5555/harvest.3", filter: "type:journal-article", since: "2025-02-01T12:00:00Z"},
+		{name: "third run starts from checkpoint", want: "https://doi.org/10.5555/harvest.3", filter: "type:journal-article,from-index-date:2025-02-01", since: "2025-02-01T12:00:00Z"},
+	}
+	for _, tc := range testCases {
+		// reopen the state file for every run
+		state, err := harvest.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filters = nil
+		result, err := harvest.Run(state, "crossref", query, harvest.Options{Number: 2})
+		if err != nil {
+			t.Fatalf("Run(%v): %v", tc.name, err)
+		}
+		if tc.want != ids(result.Data) {
+			t.Errorf("Run(%v): want %v, got %v", tc.name, tc.want, ids(result.Data))
+		}
+		err = state.Commit(result.Checkpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(filters) == 0 || tc.filter != filters[0] {
+			t.Errorf("Run(%v): want filter %v, got %v", tc.name, tc.filter, filters)
+		}
+		state, _ = harvest.Open(path)
+		list := state.List()
+		if len(list) != 1 {
+			t.Fatalf("Run(%v): want 1 checkpoint, got %v", tc.name, list)
+		}
+		var since string
+		if !list[0].Since.IsZero() {
+			since = list[0].Since.Format(time.RFC3339)
+		}
+		if tc.since != since || tc.next != (list[0].Next != "") {
+			t.Errorf("Run(%v): want since %v and next %v, got %v", tc.name, tc.since, tc.next, list[0])
+		}
+	}
+}
+
+func TestRunExpiredCursor(t *testing.T) {
+	var filters []string
+	ts := newCrossref(t, &filters)
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{CrossrefAPI: ts.URL})
+	defer config.ResetEndpoints()
+
+	state, err := harvest.Open(filepath.Join(t.TempDir(), "harvest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := crossref.QueryURL(2, 1, "", "", false, "", "", "", false, false, false, false, false, false, false, false)
+	state.Put(harvest.Checkpoint{
+		Source: "crossref",
+		Query:  ts.URL + "/works",
+		Latest: time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC),
+		Next:   ts.URL + "/works?cursor=expired&order=asc&rows=2&sort=indexed",
+	})
+	result, err := harvest.Run(state, "crossref", query, harvest.Options{Number: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://doi.org/10.5555/harvest.3"
+	if want != ids(result.Data) {
+		t.Errorf("Run: want %v, got %v", want, ids(result.Data))
+	}
+	if len(filters) != 2 || filters[1] != "from-index-date:2025-02-01" {
+		t.Errorf("Run: want a restart from the newest record, got filters %v", filters)
+	}
+}
+
+func TestRunDataCite(t *testing.T) {
+	var queries []string
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		queries = append(queries, query.Get("query"))
+		w.Header().Set("Content-Type", "application/json")
+		switch query.Get("page[cursor]") {
+		case "1":
+			fmt.Fprintf(w, `{"data":[{"attributes":{"doi":"10.5555/dc.1","types":{"resourceTypeGeneral":"Dataset"},"titles":[{"title":"Dataset 1"}],"updated":"2025-03-01T10:00:00.000Z"}}],"links":{"next":"%s/dois?page[cursor]=abc"}}`, ts.URL)
+		case "abc":
+			fmt.Fprint(w, `{"data":[{"attributes":{"doi":"10.5555/dc.2","types":{"resourceTypeGeneral":"Dataset"},"titles":[{"title":"Dataset 2"}],"updated":"2025-03-02T10:00:00.000Z"}}],"links":{"next":""}}`)
+		default:
+			http.Error(w, "unexpected request "+r.URL.RawQuery, http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{DataCiteAPI: ts.URL})
+	defer config.ResetEndpoints()
+
+	state, err := harvest.Open(filepath.Join(t.TempDir(), "harvest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := datacite.QueryURL(10, 1, "", "", false, "2025", "", "", "", false, false, false, false, false, false, false)
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	result, err := harvest.Run(state, "datacite", query, harvest.Options{Since: since})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://doi.org/10.5555/dc.1 https://doi.org/10.5555/dc.2"
+	if want != ids(result.Data) {
+		t.Errorf("Run: want %v, got %v", want, ids(result.Data))
+	}
+	state.Put(result.Checkpoint)
+	if len(queries) == 0 || queries[0] != "publicationYear:2025 AND updated:[2025-01-01T00:00:00Z TO *]" {
+		t.Errorf("Run: want the updated query, got %v", queries)
+	}
+
+	// the next run starts from the newest record
+	queries = nil
+	harvest.Run(state, "datacite", query, harvest.Options{Since: since})
+	if len(queries) == 0 || queries[0] != "publicationYear:2025 AND updated:[2025-03-02T10:00:00Z TO *]" {
+		t.Errorf("Run: want the updated query, got %v", queries)
+	}
+}
+
+func TestRunSkipped(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":[{"attributes":{"doi":"10.5555/dc.1","types":{"resourceTypeGeneral":"Dataset"},"titles":[{"title":"Dataset 1"}],"updated":"2025-03-01T10:00:00.000Z"}},{"attributes":{"doi":"10.5555/dc.2","titles":"Dataset 2","updated":"2025-03-02T10:00:00.000Z"}}],"links":{"next":""}}`)
+	}))
+	defer ts.Close()
+	config.SetEndpoints(config.Endpoints{DataCiteAPI: ts.URL})
+	defer config.ResetEndpoints()
+
+	path := filepath.Join(t.TempDir(), "harvest.json")
+	state, err := harvest.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := datacite.QueryURL(10, 1, "", "", false, "", "", "", "", false, false, false, false, false, false, false)
+	result, err := harvest.Run(state, "datacite", query, harvest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://doi.org/10.5555/dc.1"
+	if want != ids(result.Data) || len(result.Skipped) != 1 || result.Checkpoint.Skipped != 1 {
+		t.Errorf("Run: want %v and 1 skipped, got %v and %v", want, ids(result.Data), result.Skipped)
+	}
+
+	// the state is unchanged until the checkpoint is committed
+	state, _ = harvest.Open(path)
+	if list := state.List(); len(list) != 0 {
+		t.Errorf("Run: want no checkpoint, got %v", list)
+	}
+	err = state.Commit(result.Checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _ = harvest.Open(path)
+	if list := state.List(); len(list) != 1 || list[0].Harvested != 1 || list[0].Skipped != 1 {
+		t.Errorf("Commit: want 1 checkpoint with 1 harvested and 1 skipped, got %v", list)
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	state, err := harvest.Open(filepath.Join(t.TempDir(), "harvest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testCase struct {
+		source string
+		query  string
+	}
+	testCases := []testCase{
+		{source: "jsonfeed", query: "https://api.rogue-scholar.org/posts"},
+		{source: "crossref", query: "https://api.crossref.org/works?sample=10"},
+		{source: "datacite", query: "https://api.datacite.org/dois?random=true"},
+		{source: "inveniordm", query: "https://rogue-scholar.org/api/records"},
+	}
+	for _, tc := range testCases {
+		_, err := harvest.Run(state, tc.source, tc.query, harvest.Options{})
+		if !errors.Is(err, commonmeta.ErrValidation) {
+			t.Errorf("Run(%v, %v): want %v, got %v", tc.source, tc.query, commonmeta.ErrValidation, err)
+		}
+	}
+}
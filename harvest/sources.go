@@ -0,0 +1,346 @@
+package harvest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/crossref"
+	"github.com/front-matter/commonmeta/datacite"
+	"github.com/front-matter/commonmeta/httputils"
+	"github.com/front-matter/commonmeta/inveniordm"
+	"github.com/front-matter/commonmeta/openalex"
+)
+
+// Sources are the sources that can be harvested incrementally.
+var Sources = []string{"crossref", "datacite", "openalex", "inveniordm"}
+
+// page is a page of harvested records.
+type page struct {
+	data []commonmeta.Data
+	// skipped are the errors of the records that can't be read.
+	skipped []error
+	// latest is the newest indexed or updated timestamp of the records.
+	latest time.Time
+	// next is the URL of the next page, empty on the last page.
+	next string
+}
+
+// source describes the API of a source: the query parameters used for
+// paging, sorting and sampling, how the first page of a harvest is
+// requested, and how a page is read.
+type source struct {
+	paging  []string
+	sample  string
+	maxSize int
+	start   func(values url.Values, from time.Time, size int, options Options)
+	read    func(body []byte, requestURL *url.URL, options Options) (page, error)
+}
+
+var sources = map[string]source{
+	"crossref": {
+		paging:  []string{"rows", "offset", "cursor", "sort", "order"},
+		sample:  "sample",
+		maxSize: 1000,
+		start: func(values url.Values, from time.Time, size int, options Options) {
+			if !from.IsZero() {
+				appendValue(values, "filter", ",", "from-index-date:"+from.UTC().Format(time.DateOnly))
+			}
+			values.Set("rows", strconv.Itoa(size))
+			values.Set("cursor", "*")
+			values.Set("sort", "indexed")
+			values.Set("order", "asc")
+		},
+		read: readCrossref,
+	},
+	"datacite": {
+		paging:  []string{"page[size]", "page[number]", "page[cursor]", "sort"},
+		sample:  "random",
+		maxSize: 1000,
+		start: func(values url.Values, from time.Time, size int, options Options) {
+			if !from.IsZero() {
+				appendValue(values, "query", " AND ", "updated:["+from.UTC().Format(time.RFC3339)+" TO *]")
+			}
+			values.Set("page[size]", strconv.Itoa(size))
+			values.Set("page[cursor]", "1")
+			values.Set("sort", "updated")
+		},
+		read: readDataCite,
+	},
+	"openalex": {
+		paging:  []string{"per-page", "page", "cursor", "sort", "mailto"},
+		sample:  "sample",
+		maxSize: 200,
+		start: func(values url.Values, from time.Time, size int, options Options) {
+			if !from.IsZero() {
+				appendValue(values, "filter", ",", "from_updated_date:"+from.UTC().Format(time.RFC3339))
+			}
+			values.Set("per-page", strconv.Itoa(size))
+			values.Set("cursor", "*")
+			if options.Email != "" {
+				values.Set("mailto", options.Email)
+			}
+		},
+		read: readOpenAlex,
+	},
+	"inveniordm": {
+		paging:  []string{"size", "page", "sort", "l"},
+		maxSize: 500,
+		start: func(values url.Values, from time.Time, size int, options Options) {
+			if !from.IsZero() {
+				appendValue(values, "q", " AND ", "updated:["+from.UTC().Format(time.RFC3339)+" TO *]")
+			}
+			values.Set("size", strconv.Itoa(size))
+			values.Set("page", "1")
+			values.Set("sort", "updated-asc")
+		},
+		read: readInvenioRDM,
+	},
+}
+
+// normalize removes the paging and sorting parameters from a query URL,
+// so that a query has the same checkpoint regardless of page size.
+func (s source) normalize(query string) (string, error) {
+	u, err := url.Parse(query)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("%w: invalid query URL %s", commonmeta.ErrValidation, query)
+	}
+	values := u.Query()
+	if s.sample != "" && values.Has(s.sample) {
+		return "", fmt.Errorf("%w: samples can't be harvested", commonmeta.ErrValidation)
+	}
+	for _, key := range s.paging {
+		values.Del(key)
+	}
+	u.RawQuery = values.Encode()
+	return u.String(), nil
+}
+
+// first returns the URL of the first page of a harvest of records indexed
+// or updated since from.
+func (s source) first(query string, from time.Time, options Options) string {
+	u, _ := url.Parse(query)
+	values := u.Query()
+	size := options.Number
+	if size <= 0 || size > s.maxSize {
+		size = s.maxSize
+	}
+	s.start(values, from, size, options)
+	u.RawQuery = values.Encode()
+	return u.String()
+}
+
+// appendValue appends v to the query parameter key, separated by sep.
+func appendValue(values url.Values, key string, sep string, v string) {
+	if values.Get(key) != "" {
+		v = values.Get(key) + sep + v
+	}
+	values.Set(key, v)
+}
+
+// fetch retrieves and reads a page of records.
+func fetch(s source, requestURL string, options Options) (page, error) {
+	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return page{}, err
+	}
+	var resp *http.Response
+	if options.Client != nil && options.Client.Host == req.URL.Host {
+		req.Header.Set("Content-Type", "application/json")
+		if options.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+options.Token)
+		}
+		resp, err = options.Client.Do(req)
+	} else {
+		req.Header.Set("Cache-Control", "private")
+		resp, err = httputils.Client().Do(req)
+	}
+	if err != nil {
+		return page{}, err
+	}
+	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return page{}, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return page{}, err
+	}
+	return s.read(body, req.URL, options)
+}
+
+// add appends a record to the page, or the error if it can't be read.
+func (p *page) add(data commonmeta.Data, err error, timestamp string) {
+	if err != nil {
+		p.skipped = append(p.skipped, err)
+	} else {
+		p.data = append(p.data, data)
+	}
+	if t := parseTime(timestamp); t.After(p.latest) {
+		p.latest = t
+	}
+}
+
+// parseTime parses the timestamps used by the sources, a zero time if
+// the timestamp is missing or invalid.
+func parseTime(timestamp string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", time.DateOnly} {
+		t, err := time.Parse(layout, timestamp)
+		if err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// withCursor returns the request URL with the cursor parameter set.
+func withCursor(requestURL *url.URL, cursor string) string {
+	u := *requestURL
+	values := u.Query()
+	values.Set("cursor", cursor)
+	u.RawQuery = values.Encode()
+	return u.String()
+}
+
+// readCrossref reads a page of the Crossref works API. Crossref returns a
+// next cursor also on the last page, so a short page ends the harvest.
+func readCrossref(body []byte, requestURL *url.URL, options Options) (page, error) {
+	var response struct {
+		Message struct {
+			NextCursor string            `json:"next-cursor"`
+			Items      []json.RawMessage `json:"items"`
+		} `json:"message"`
+	}
+	var p page
+	err := json.Unmarshal(body, &response)
+	if err != nil {
+		return p, err
+	}
+	for _, item := range response.Message.Items {
+		var content crossref.Content
+		var indexed struct {
+			Indexed struct {
+				DateTime string `json:"date-time"`
+			} `json:"indexed"`
+		}
+		if err := json.Unmarshal(item, &content); err != nil {
+			p.skipped = append(p.skipped, err)
+			continue
+		}
+		json.Unmarshal(item, &indexed)
+		data, err := crossref.Read(content, options.Match)
+		p.add(data, err, indexed.Indexed.DateTime)
+	}
+	rows, _ := strconv.Atoi(requestURL.Query().Get("rows"))
+	if response.Message.NextCursor != "" && len(response.Message.Items) > 0 && len(response.Message.Items) >= rows {
+		p.next = withCursor(requestURL, response.Message.NextCursor)
+	}
+	return p, nil
+}
+
+// readDataCite reads a page of the DataCite dois API.
+func readDataCite(body []byte, requestURL *url.URL, options Options) (page, error) {
+	var response struct {
+		Data []struct {
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"data"`
+		Links struct {
+			Next string `json:"next"`
+		} `json:"links"`
+	}
+	var p page
+	err := json.Unmarshal(body, &response)
+	if err != nil {
+		return p, err
+	}
+	for _, item := range response.Data {
+		var content datacite.Content
+		var updated struct {
+			Updated string `json:"updated"`
+		}
+		if err := json.Unmarshal(item.Attributes, &content); err != nil {
+			p.skipped = append(p.skipped, err)
+			continue
+		}
+		json.Unmarshal(item.Attributes, &updated)
+		data, err := datacite.Read(content, options.Match)
+		p.add(data, err, updated.Updated)
+	}
+	if len(response.Data) > 0 {
+		p.next = response.Links.Next
+	}
+	return p, nil
+}
+
+// readOpenAlex reads a page of the OpenAlex works API.
+func readOpenAlex(body []byte, requestURL *url.URL, options Options) (page, error) {
+	var response struct {
+		Meta struct {
+			NextCursor string `json:"next_cursor"`
+		} `json:"meta"`
+		Results []json.RawMessage `json:"results"`
+	}
+	var p page
+	err := json.Unmarshal(body, &response)
+	if err != nil {
+		return p, err
+	}
+	r := openalex.NewReader(options.Email)
+	for _, item := range response.Results {
+		var work openalex.Work
+		var updated struct {
+			UpdatedDate string `json:"updated_date"`
+		}
+		if err := json.Unmarshal(item, &work); err != nil {
+			p.skipped = append(p.skipped, err)
+			continue
+		}
+		json.Unmarshal(item, &updated)
+		data, err := r.Read(&work)
+		p.add(data, err, updated.UpdatedDate)
+	}
+	if response.Meta.NextCursor != "" && len(response.Results) > 0 {
+		p.next = withCursor(requestURL, response.Meta.NextCursor)
+	}
+	return p, nil
+}
+
+// readInvenioRDM reads a page of the InvenioRDM records API.
+func readInvenioRDM(body []byte, requestURL *url.URL, options Options) (page, error) {
+	var response struct {
+		Hits struct {
+			Hits []json.RawMessage `json:"hits"`
+		} `json:"hits"`
+		Links struct {
+			Next string `json:"next"`
+		} `json:"links"`
+	}
+	var p page
+	err := json.Unmarshal(body, &response)
+	if err != nil {
+		return p, err
+	}
+	for _, item := range response.Hits.Hits {
+		var content inveniordm.Content
+		var updated struct {
+			Updated string `json:"updated"`
+		}
+		if err := json.Unmarshal(item, &content); err != nil {
+			p.skipped = append(p.skipped, err)
+			continue
+		}
+		json.Unmarshal(item, &updated)
+		data, err := inveniordm.Read(content, options.Match)
+		p.add(data, err, updated.Updated)
+	}
+	if len(response.Hits.Hits) > 0 {
+		p.next = response.Links.Next
+	}
+	return p, nil
+}
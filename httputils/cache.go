@@ -0,0 +1,239 @@
+package httputils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTL is the time a cached response is considered fresh, unless
+// overridden for the host in DefaultCacheTTLs.
+const DefaultCacheTTL = 24 * time.Hour
+
+// DefaultCacheTTLs are the per-host cache TTLs. Lookups that rarely change,
+// e.g. the registration agency of a DOI prefix, are cached longer.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"doi.org":          30 * 24 * time.Hour,
+	"api.ror.org":      7 * 24 * time.Hour,
+	"api.crossref.org": 24 * time.Hour,
+	"api.openalex.org": 24 * time.Hour,
+	"api.datacite.org": 24 * time.Hour,
+}
+
+// Cache is a http.RoundTripper that stores successful GET responses on disk.
+// Fresh responses are served from disk, stale responses are revalidated using
+// ETag and Last-Modified headers. Requests with an Authorization header are
+// never cached.
+type Cache struct {
+	Base       http.RoundTripper
+	Dir        string
+	DefaultTTL time.Duration
+	TTLs       map[string]time.Duration
+}
+
+// CacheStats describes the content of the on-disk cache.
+type CacheStats struct {
+	Dir     string `json:"dir"`
+	Entries int    `json:"entries"`
+	Size    int64  `json:"size"`
+	Oldest  string `json:"oldest,omitempty"`
+	Newest  string `json:"newest,omitempty"`
+}
+
+const cacheExtension = ".http"
+
+// DefaultCacheDir returns the default cache directory, in the user cache directory.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "commonmeta", "http")
+}
+
+// NewCache returns a Cache storing responses in dir, wrapping base.
+func NewCache(base http.RoundTripper, dir string, ttl time.Duration) *Cache {
+	if dir == "" {
+		dir = DefaultCacheDir()
+	}
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	ttls := make(map[string]time.Duration, len(DefaultCacheTTLs))
+	for host, d := range DefaultCacheTTLs {
+		ttls[host] = d
+	}
+	return &Cache{
+		Base:       base,
+		Dir:        dir,
+		DefaultTTL: ttl,
+		TTLs:       ttls,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Cache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Authorization") != "" {
+		return c.Base.RoundTrip(req)
+	}
+	filename := c.filename(req)
+	cached, stored, err := c.load(filename, req)
+	if err == nil && time.Since(stored) < c.ttl(req.URL.Hostname()) {
+		return cached, nil
+	}
+
+	r := req
+	if cached != nil {
+		// revalidate the stale response
+		r = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+			r.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+	resp, err := c.Base.RoundTrip(r)
+	if err != nil {
+		if cached != nil {
+			cached.Body.Close()
+		}
+		return resp, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		now := time.Now()
+		_ = os.Chtimes(filename, now, now)
+		return cached, nil
+	}
+	if cached != nil {
+		cached.Body.Close()
+	}
+	if resp.StatusCode == http.StatusOK && !strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		// DumpResponse replaces the response body so that it can still be read by the caller
+		b, err := httputil.DumpResponse(resp, true)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		// failing to write the cache is not fatal for the request
+		_ = c.store(filename, b)
+	}
+	return resp, nil
+}
+
+// Stats returns statistics about the cache.
+func (c *Cache) Stats() (CacheStats, error) {
+	stats := CacheStats{Dir: c.Dir}
+	var oldest, newest time.Time
+	entries, err := os.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return stats, nil
+	} else if err != nil {
+		return stats, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != cacheExtension {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		stats.Entries++
+		stats.Size += info.Size()
+		if oldest.IsZero() || info.ModTime().Before(oldest) {
+			oldest = info.ModTime()
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	if stats.Entries > 0 {
+		stats.Oldest = oldest.UTC().Format(time.RFC3339)
+		stats.Newest = newest.UTC().Format(time.RFC3339)
+	}
+	return stats, nil
+}
+
+// Clear removes all cached responses and returns the number of removed entries.
+func (c *Cache) Clear() (int, error) {
+	var n int
+	entries, err := os.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return n, nil
+	} else if err != nil {
+		return n, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != cacheExtension {
+			continue
+		}
+		err = os.Remove(filepath.Join(c.Dir, e.Name()))
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// ttl returns the cache TTL for a host.
+func (c *Cache) ttl(host string) time.Duration {
+	if d, ok := c.TTLs[host]; ok {
+		return d
+	}
+	return c.DefaultTTL
+}
+
+// filename returns the cache filename for a request, using a hash of the URL
+// and the Accept header.
+func (c *Cache) filename(req *http.Request) string {
+	h := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Accept")))
+	return filepath.Join(c.Dir, hex.EncodeToString(h[:])+cacheExtension)
+}
+
+// load reads a cached response and the time it was stored.
+func (c *Cache) load(filename string, req *http.Request) (*http.Response, time.Time, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), req)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return resp, info.ModTime(), nil
+}
+
+// store atomically writes a dumped response to the cache.
+func (c *Cache) store(filename string, b []byte) error {
+	err := os.MkdirAll(c.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
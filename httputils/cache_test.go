@@ -0,0 +1,104 @@
+package httputils_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/front-matter/commonmeta/httputils"
+)
+
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+	var calls, revalidations atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, "hello")
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	cache := httputils.NewCache(http.DefaultTransport, dir, time.Hour)
+	client := &http.Client{Transport: cache}
+
+	// first request is fetched, second request served from cache
+	for range 2 {
+		if got := get(t, client, ts.URL); got != "hello" {
+			t.Errorf("Cache: want hello, got %v", got)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Cache: want 1 call, got %v", calls.Load())
+	}
+
+	// stale responses are revalidated with the ETag
+	files, _ := filepath.Glob(filepath.Join(dir, "*.http"))
+	past := time.Now().Add(-2 * time.Hour)
+	for _, f := range files {
+		os.Chtimes(f, past, past)
+	}
+	if got := get(t, client, ts.URL); got != "hello" {
+		t.Errorf("Cache revalidation: want hello, got %v", got)
+	}
+	if revalidations.Load() != 1 {
+		t.Errorf("Cache revalidation: want 1 revalidation, got %v", revalidations.Load())
+	}
+
+	stats, err := cache.Stats()
+	if err != nil || stats.Entries != 1 {
+		t.Errorf("Cache stats: want 1 entry, got %v, error %v", stats.Entries, err)
+	}
+	n, err := cache.Clear()
+	if err != nil || n != 1 {
+		t.Errorf("Cache clear: want 1 removed, got %v, error %v", n, err)
+	}
+}
+
+func TestCacheSkipsAuthorization(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		io.WriteString(w, "secret")
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: httputils.NewCache(http.DefaultTransport, t.TempDir(), time.Hour)}
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+		req.Header.Set("Authorization", "Bearer token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Cache with Authorization: want 2 calls, got %v", calls.Load())
+	}
+}
@@ -0,0 +1,115 @@
+package httputils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Sentinel errors for failed HTTP requests, use errors.Is to check for them.
+// They are re-exported by the commonmeta package.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+// StatusError is returned for HTTP responses with an unexpected status code.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	URL        string
+	Body       string
+}
+
+// maxErrorBody is the maximum number of bytes of the response body included in a StatusError.
+const maxErrorBody = 1024
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	msg := "status code error: " + e.Status
+	if e.Status == "" {
+		msg = fmt.Sprintf("status code error: %d", e.StatusCode)
+	}
+	if e.URL != "" {
+		msg += " (" + e.URL + ")"
+	}
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Is maps the status code to the sentinel errors.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// SensitiveQueryParams are the query parameters masked by RedactURL, e.g.
+// the Crossref deposit password. Names are compared case-insensitively.
+var SensitiveQueryParams = []string{
+	"login_passwd",
+	"passwd",
+	"password",
+	"pwd",
+	"token",
+	"access_token",
+	"api_key",
+	"apikey",
+	"key",
+	"secret",
+	"legacykey",
+}
+
+// RedactURL returns the URL with the password in the userinfo and the
+// values of sensitive query parameters replaced by "xxxxx".
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := *u
+	values := redacted.Query()
+	changed := false
+	for name := range values {
+		if slices.Contains(SensitiveQueryParams, strings.ToLower(name)) {
+			values[name] = []string{"xxxxx"}
+			changed = true
+		}
+	}
+	if changed {
+		redacted.RawQuery = values.Encode()
+	}
+	return redacted.Redacted()
+}
+
+// CheckResponse returns a StatusError if the response status code is not 2xx,
+// including the beginning of the response body. The body is consumed in that case.
+func CheckResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	e := &StatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		e.URL = RedactURL(resp.Request.URL)
+	}
+	if resp.Body != nil {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		e.Body = strings.TrimSpace(string(b))
+	}
+	return e
+}
@@ -0,0 +1,346 @@
+// Package httputils provides the shared HTTP client used by all commonmeta readers and writers.
+// It handles per-host rate limiting, retries with exponential backoff and a polite User-Agent.
+package httputils
+
+import (
+	"crypto/tls"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Options configures the shared HTTP client.
+type Options struct {
+	Version    string        // commonmeta version, used in the User-Agent
+	Email      string        // contact email, used in the User-Agent (Crossref polite pool)
+	MaxRetries *int          // maximum number of retries for failed requests, 0 disables retries, nil keeps the default
+	Timeout    time.Duration // timeout for requests made with Client(), 0 keeps the default
+	Cache      bool          // enable the on-disk cache for GET requests
+	CacheDir   string        // cache directory, defaults to DefaultCacheDir()
+	CacheTTL   time.Duration // cache TTL for hosts not listed in DefaultCacheTTLs
+}
+
+// Limit defines the rate limit for a host in requests per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultRateLimits are the per-host rate limits used unless overridden with SetRateLimit.
+// Hosts not listed are not rate limited.
+var DefaultRateLimits = map[string]Limit{
+	"api.crossref.org":  {Rate: 10, Burst: 10},
+	"api.datacite.org":  {Rate: 10, Burst: 10},
+	"api.openalex.org":  {Rate: 10, Burst: 10},
+	"api.ror.org":       {Rate: 5, Burst: 5},
+	"doi.org":           {Rate: 10, Burst: 10},
+	"doi.crossref.org":  {Rate: 2, Burst: 2},
+	"test.crossref.org": {Rate: 2, Burst: 2},
+}
+
+// DefaultTimeout is the timeout for requests made with Client().
+const DefaultTimeout = 30 * time.Second
+
+// Transport is a http.RoundTripper that adds a User-Agent header, waits for the
+// per-host rate limiter and retries requests that failed with a network error,
+// status 429 or a 5xx status, honouring the Retry-After header. Requests with
+// a method that is not idempotent, e.g. POST deposits, are only retried after
+// status 429, as the server may have accepted them before failing.
+type Transport struct {
+	Base          http.RoundTripper
+	UserAgent     string
+	MaxRetries    int
+	MinBackoff    time.Duration
+	MaxBackoff    time.Duration
+	MaxRetryAfter time.Duration
+
+	mu       sync.Mutex
+	limits   map[string]Limit
+	limiters map[string]*rate.Limiter
+}
+
+var (
+	mu                sync.RWMutex
+	defaultTransport  = NewTransport(nil)
+	insecureTransport = newInsecureTransport()
+	defaultTimeout    = DefaultTimeout
+	defaultCache      *Cache
+)
+
+// NewTransport returns a Transport wrapping base, or http.DefaultTransport if base is nil.
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	limits := make(map[string]Limit, len(DefaultRateLimits))
+	for host, l := range DefaultRateLimits {
+		limits[host] = l
+	}
+	return &Transport{
+		Base:          base,
+		UserAgent:     UserAgent("", ""),
+		MaxRetries:    3,
+		MinBackoff:    500 * time.Millisecond,
+		MaxBackoff:    30 * time.Second,
+		MaxRetryAfter: 2 * time.Minute,
+		limits:        limits,
+		limiters:      make(map[string]*rate.Limiter),
+	}
+}
+
+// newInsecureTransport returns a Transport that skips TLS certificate
+// verification.
+func newInsecureTransport() *Transport {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return NewTransport(base)
+}
+
+// Configure configures the shared transports used by Client, NewClient and
+// NewInsecureClient.
+func Configure(opts Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, t := range []*Transport{defaultTransport, insecureTransport} {
+		t.mu.Lock()
+		t.UserAgent = UserAgent(opts.Version, opts.Email)
+		if opts.MaxRetries != nil {
+			t.MaxRetries = max(*opts.MaxRetries, 0)
+		}
+		t.mu.Unlock()
+	}
+	if opts.Timeout > 0 {
+		defaultTimeout = opts.Timeout
+	}
+	if opts.Cache {
+		defaultCache = NewCache(defaultTransport, opts.CacheDir, opts.CacheTTL)
+	} else {
+		defaultCache = nil
+	}
+}
+
+// UserAgent returns the User-Agent string for a commonmeta version and contact email.
+func UserAgent(version string, email string) string {
+	ua := "commonmeta"
+	if version != "" {
+		ua += "/" + version
+	}
+	ua += " (https://commonmeta.org/"
+	if email != "" {
+		ua += "; mailto: " + email
+	}
+	return ua + ")"
+}
+
+// SetRateLimit sets the rate limit for a host in requests per second.
+// A rate of 0 removes the limit.
+func SetRateLimit(host string, r float64, burst int) {
+	defaultTransport.SetRateLimit(host, r, burst)
+	insecureTransport.SetRateLimit(host, r, burst)
+}
+
+// SetRateLimit sets the rate limit for a host in requests per second.
+// A rate of 0 removes the limit.
+func (t *Transport) SetRateLimit(host string, r float64, burst int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.limiters, host)
+	if r <= 0 {
+		delete(t.limits, host)
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	t.limits[host] = Limit{Rate: r, Burst: burst}
+}
+
+// DefaultTransport returns the shared transport.
+func DefaultTransport() *Transport {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultTransport
+}
+
+// DefaultCache returns the shared on-disk cache, or nil if caching is not enabled.
+func DefaultCache() *Cache {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultCache
+}
+
+// Client returns a http.Client using the shared transport and the default timeout.
+func Client() *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return &http.Client{
+		Timeout:   defaultTimeout,
+		Transport: roundTripper(),
+	}
+}
+
+// NewClient returns a http.Client using the shared transport with a custom timeout.
+// A timeout of 0 means no timeout, e.g. for large downloads.
+func NewClient(timeout time.Duration) *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: roundTripper(),
+	}
+}
+
+// roundTripper returns the shared cache if enabled, and the shared transport otherwise.
+// The caller must hold mu.
+func roundTripper() http.RoundTripper {
+	if defaultCache != nil {
+		return defaultCache
+	}
+	return defaultTransport
+}
+
+// NewInsecureClient returns a http.Client that skips TLS certificate verification,
+// e.g. for InvenioRDM running on localhost. All insecure clients share one
+// transport, with its own per-host rate limits and the retries of the shared
+// transport.
+func NewInsecureClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: insecureTransport,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	userAgent := t.UserAgent
+	maxRetries := t.MaxRetries
+	t.mu.Unlock()
+
+	ctx := req.Context()
+	// requests with a body can only be retried if the body can be rewound
+	canRewind := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	var resp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		r := req.Clone(ctx)
+		if attempt > 0 && req.GetBody != nil {
+			r.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if r.Header.Get("User-Agent") == "" && userAgent != "" {
+			r.Header.Set("User-Agent", userAgent)
+		}
+		if limiter := t.limiter(req.URL.Hostname()); limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err = t.Base.RoundTrip(r)
+		if !canRewind || attempt >= maxRetries || !shouldRetry(req.Method, resp, err) {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// limiter returns the rate limiter for a host, or nil if the host is not rate limited.
+func (t *Transport) limiter(host string) *rate.Limiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if l, ok := t.limiters[host]; ok {
+		return l
+	}
+	limit, ok := t.limits[host]
+	if !ok {
+		return nil
+	}
+	l := rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+	t.limiters[host] = l
+	return l
+}
+
+// backoff returns the time to wait before the next attempt, using the Retry-After
+// header if present, and exponential backoff with jitter otherwise.
+func (t *Transport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := RetryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(d, t.MaxRetryAfter)
+		}
+	}
+	d := t.MinBackoff << attempt
+	if d <= 0 || d > t.MaxBackoff {
+		d = t.MaxBackoff
+	}
+	// add up to 25% jitter
+	if d > 0 {
+		d += time.Duration(rand.Int64N(int64(d)/4 + 1))
+	}
+	return d
+}
+
+// shouldRetry reports whether a request should be retried. Requests that are
+// not idempotent are only retried if they were rate limited.
+func shouldRetry(method string, resp *http.Response, err error) bool {
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if !isIdempotent(method) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+// isIdempotent reports whether repeating a request with method has the same
+// effect as sending it once.
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// RetryAfter parses the value of a Retry-After header, given either in seconds
+// or as a HTTP date.
+func RetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		d := time.Until(date)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -0,0 +1,185 @@
+package httputils_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/front-matter/commonmeta/httputils"
+)
+
+func newTestClient() *http.Client {
+	t := httputils.NewTransport(nil)
+	t.MinBackoff = time.Millisecond
+	t.MaxBackoff = 10 * time.Millisecond
+	t.UserAgent = httputils.UserAgent("v0.1.0", "info@example.org")
+	return &http.Client{Transport: t}
+}
+
+func TestRoundTripRetries(t *testing.T) {
+	t.Parallel()
+	type testCase struct {
+		name     string
+		statuses []int
+		want     int
+		calls    int32
+	}
+	testCases := []testCase{
+		{name: "success", statuses: []int{200}, want: 200, calls: 1},
+		{name: "server error then success", statuses: []int{503, 502, 200}, want: 200, calls: 3},
+		{name: "rate limited then success", statuses: []int{429, 200}, want: 200, calls: 2},
+		{name: "not found is not retried", statuses: []int{404, 200}, want: 404, calls: 1},
+		{name: "gives up after max retries", statuses: []int{500, 500, 500, 500, 500}, want: 500, calls: 4},
+	}
+	for _, tc := range testCases {
+		var calls atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := calls.Add(1)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(tc.statuses[n-1])
+		}))
+		resp, err := newTestClient().Get(ts.URL)
+		if err != nil {
+			t.Errorf("RoundTrip(%v): error %v", tc.name, err)
+			ts.Close()
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want || calls.Load() != tc.calls {
+			t.Errorf("RoundTrip(%v): want %v after %v calls, got %v after %v calls",
+				tc.name, tc.want, tc.calls, resp.StatusCode, calls.Load())
+		}
+		ts.Close()
+	}
+}
+
+func TestRoundTripRetriesBody(t *testing.T) {
+	t.Parallel()
+	type testCase struct {
+		method string
+		status int
+		want   int
+		calls  int32
+	}
+	testCases := []testCase{
+		{method: http.MethodPut, status: http.StatusServiceUnavailable, want: http.StatusCreated, calls: 2},
+		{method: http.MethodPost, status: http.StatusTooManyRequests, want: http.StatusCreated, calls: 2},
+		// the server may have accepted a POST before failing
+		{method: http.MethodPost, status: http.StatusServiceUnavailable, want: http.StatusServiceUnavailable, calls: 1},
+	}
+	for _, tc := range testCases {
+		var calls atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != "payload" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if calls.Add(1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(tc.status)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		req, _ := http.NewRequest(tc.method, ts.URL, strings.NewReader("payload"))
+		resp, err := newTestClient().Do(req)
+		ts.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want || calls.Load() != tc.calls {
+			t.Errorf("RoundTrip(%v, %v): want %v after %v calls, got %v after %v calls", tc.method, tc.status, tc.want, tc.calls, resp.StatusCode, calls.Load())
+		}
+	}
+}
+
+func TestRoundTripUserAgent(t *testing.T) {
+	t.Parallel()
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+	}))
+	defer ts.Close()
+	resp, err := newTestClient().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	want := "commonmeta/v0.1.0 (https://commonmeta.org/; mailto: info@example.org)"
+	if got != want {
+		t.Errorf("User-Agent: want %v, got %v", want, got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+	type testCase struct {
+		input string
+		want  time.Duration
+		ok    bool
+	}
+	testCases := []testCase{
+		{input: "120", want: 120 * time.Second, ok: true},
+		{input: "0", want: 0, ok: true},
+		{input: "", want: 0, ok: false},
+		{input: "soon", want: 0, ok: false},
+		{input: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0, ok: true},
+	}
+	for _, tc := range testCases {
+		got, ok := httputils.RetryAfter(tc.input)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("RetryAfter(%v): want %v %v, got %v %v", tc.input, tc.want, tc.ok, got, ok)
+		}
+	}
+}
+
+func ExampleUserAgent() {
+	s := httputils.UserAgent("v0.35.2", "info@front-matter.io")
+	fmt.Println(s)
+	// Output:
+	// commonmeta/v0.35.2 (https://commonmeta.org/; mailto: info@front-matter.io)
+}
+
+func TestConfigureMaxRetries(t *testing.T) {
+	zero, three, five := 0, 3, 5
+	defer httputils.Configure(httputils.Options{MaxRetries: &three})
+	type testCase struct {
+		input *int
+		want  int
+	}
+	testCases := []testCase{
+		{input: &five, want: 5},
+		{input: nil, want: 5},
+		{input: &zero, want: 0},
+	}
+	for _, tc := range testCases {
+		httputils.Configure(httputils.Options{MaxRetries: tc.input})
+		got := httputils.DefaultTransport().MaxRetries
+		if got != tc.want {
+			t.Errorf("Configure(%v): want %v, got %v", tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestNewInsecureClient(t *testing.T) {
+	three, five := 3, 5
+	defer httputils.Configure(httputils.Options{MaxRetries: &three})
+	httputils.Configure(httputils.Options{MaxRetries: &five})
+
+	a := httputils.NewInsecureClient(time.Second)
+	b := httputils.NewInsecureClient(0)
+	if a.Transport != b.Transport {
+		t.Errorf("NewInsecureClient: want a shared transport, got %p and %p", a.Transport, b.Transport)
+	}
+	transport, ok := a.Transport.(*httputils.Transport)
+	if !ok || transport.MaxRetries != 5 {
+		t.Errorf("NewInsecureClient: want 5 retries, got %v", a.Transport)
+	}
+}
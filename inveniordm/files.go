@@ -0,0 +1,334 @@
+package inveniordm
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/httputils"
+)
+
+// FileEntry represents a file of a draft record in InvenioRDM.
+type FileEntry struct {
+	Key      string `json:"key"`
+	Checksum string `json:"checksum,omitempty"`
+	Size     int    `json:"size,omitempty"`
+	MimeType string `json:"mimetype,omitempty"`
+	Status   string `json:"status,omitempty"`
+}
+
+// FileEntries represents the files of a draft record in InvenioRDM.
+type FileEntries struct {
+	Enabled bool        `json:"enabled"`
+	Entries []FileEntry `json:"entries"`
+}
+
+// fileContent is a file to upload, with its md5 checksum in the format
+// used by InvenioRDM and its size.
+type fileContent struct {
+	File     commonmeta.File
+	Key      string
+	Checksum string
+	Size     int64
+}
+
+// FileKey returns the key of a file, i.e. its filename in InvenioRDM.
+func FileKey(file commonmeta.File) string {
+	if file.Key != "" {
+		return file.Key
+	}
+	u, err := url.Parse(file.URL)
+	if err == nil && u.Path != "" {
+		return path.Base(u.Path)
+	}
+	return path.Base(file.URL)
+}
+
+// OpenFile opens a file from a local path or URL for reading. The size is
+// -1 if the server doesn't send a Content-Length. Files are downloaded
+// without timeout and bypass the HTTP cache.
+func OpenFile(file commonmeta.File) (io.ReadCloser, int64, error) {
+	if isRemote(file) {
+		client := &http.Client{Transport: httputils.DefaultTransport()}
+		resp, err := client.Get(file.URL)
+		if err != nil {
+			return nil, 0, err
+		}
+		if err = httputils.CheckResponse(resp); err != nil {
+			resp.Body.Close()
+			return nil, 0, err
+		}
+		return resp.Body, resp.ContentLength, nil
+	}
+	f, err := os.Open(strings.TrimPrefix(file.URL, "file://"))
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// isRemote returns true if a file is downloaded from a URL.
+func isRemote(file commonmeta.File) bool {
+	return strings.HasPrefix(file.URL, "http://") || strings.HasPrefix(file.URL, "https://")
+}
+
+// downloadFile downloads a remote file to a temporary file, so that it is
+// downloaded only once for its checksum and upload. The key of the file is
+// kept. Local files are returned unchanged. The returned function removes
+// the temporary file.
+func downloadFile(file commonmeta.File) (commonmeta.File, func(), error) {
+	if !isRemote(file) {
+		return file, func() {}, nil
+	}
+	r, _, err := OpenFile(file)
+	if err != nil {
+		return file, func() {}, err
+	}
+	defer r.Close()
+	tmp, err := os.CreateTemp("", "commonmeta-file-*")
+	if err != nil {
+		return file, func() {}, err
+	}
+	remove := func() { os.Remove(tmp.Name()) }
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		remove()
+		return file, func() {}, err
+	}
+	local := file
+	local.Key = FileKey(file)
+	local.URL = tmp.Name()
+	return local, remove, nil
+}
+
+// FileChecksum reads a file from a local path or URL and returns its md5
+// checksum in the format used by InvenioRDM and its size. The file is
+// verified against the file checksum, if provided. Checksums are md5 or
+// sha256, optionally prefixed with the algorithm, e.g. md5:abc.
+func FileChecksum(file commonmeta.File) (string, int64, error) {
+	r, _, err := OpenFile(file)
+	if err != nil {
+		return "", 0, err
+	}
+	defer r.Close()
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), r)
+	if err != nil {
+		return "", 0, err
+	}
+	md5Sum := hex.EncodeToString(md5Hash.Sum(nil))
+	if file.Checksum != "" {
+		algorithm, value, found := strings.Cut(file.Checksum, ":")
+		if !found {
+			value = file.Checksum
+			algorithm = "md5"
+			if len(value) == 64 {
+				algorithm = "sha256"
+			}
+		}
+		var sum string
+		switch strings.ToLower(algorithm) {
+		case "md5":
+			sum = md5Sum
+		case "sha256":
+			sum = hex.EncodeToString(sha256Hash.Sum(nil))
+		}
+		if !strings.EqualFold(sum, value) {
+			return "", 0, fmt.Errorf("%w: checksum mismatch for %s", commonmeta.ErrValidation, FileKey(file))
+		}
+	}
+	return "md5:" + md5Sum, size, nil
+}
+
+// UpsertFiles uploads the files of a draft record in InvenioRDM. Files that
+// are unchanged are kept, changed files are replaced, and files no longer
+// listed are removed from the draft. Remote files are downloaded once to a
+// temporary file.
+func UpsertFiles(record commonmeta.APIResponse, files []commonmeta.File, apiKey string, client *InvenioRDMClient) (commonmeta.APIResponse, error) {
+	var contents []fileContent
+	for _, file := range files {
+		file, remove, err := downloadFile(file)
+		if err != nil {
+			record.Status = "failed_files"
+			return record, err
+		}
+		defer remove()
+		checksum, size, err := FileChecksum(file)
+		if err != nil {
+			record.Status = "failed_files"
+			return record, err
+		}
+		contents = append(contents, fileContent{
+			File:     file,
+			Key:      FileKey(file),
+			Checksum: checksum,
+			Size:     size,
+		})
+	}
+
+	existing, err := ListDraftFiles(record, apiKey, client)
+	if err != nil {
+		record.Status = "failed_files"
+		return record, err
+	}
+	for _, entry := range existing.Entries {
+		i := slices.IndexFunc(contents, func(c fileContent) bool { return c.Key == entry.Key })
+		if i >= 0 && contents[i].Checksum == entry.Checksum {
+			// unchanged file
+			contents = slices.Delete(contents, i, i+1)
+			continue
+		}
+		err = DeleteDraftFile(record, entry.Key, apiKey, client)
+		if err != nil {
+			record.Status = "failed_files"
+			return record, err
+		}
+	}
+
+	for _, c := range contents {
+		err = UploadDraftFile(record, c.File, c.Checksum, c.Size, apiKey, client)
+		if err != nil {
+			record.Status = "failed_files"
+			return record, err
+		}
+	}
+	return record, nil
+}
+
+// ListDraftFiles lists the files of a draft record in InvenioRDM.
+func ListDraftFiles(record commonmeta.APIResponse, apiKey string, client *InvenioRDMClient) (FileEntries, error) {
+	var entries FileEntries
+	requestURL := fmt.Sprintf("https://%s/api/records/%s/draft/files", client.Host, record.ID)
+	body, err := filesRequest(http.MethodGet, requestURL, "application/json", nil, apiKey, client)
+	if err != nil {
+		return entries, err
+	}
+	err = json.Unmarshal(body, &entries)
+	return entries, err
+}
+
+// UploadDraftFile uploads a file to a draft record in InvenioRDM in three
+// steps: initialize the file, stream its content from the local path or URL
+// and commit it. The checksum returned by InvenioRDM is compared with the
+// md5 checksum, and size is the Content-Length of the upload.
+func UploadDraftFile(record commonmeta.APIResponse, file commonmeta.File, checksum string, size int64, apiKey string, client *InvenioRDMClient) error {
+	key := FileKey(file)
+	baseURL := fmt.Sprintf("https://%s/api/records/%s/draft/files", client.Host, record.ID)
+	payload, err := json.Marshal([]FileEntry{{Key: key}})
+	if err != nil {
+		return err
+	}
+	_, err = filesRequest(http.MethodPost, baseURL, "application/json", payload, apiKey, client)
+	if err != nil {
+		return fmt.Errorf("failed to initialize file %s: %w", key, err)
+	}
+	fileURL := baseURL + "/" + url.PathEscape(key)
+	err = uploadContent(fileURL+"/content", file, size, apiKey, client)
+	if err != nil {
+		return fmt.Errorf("failed to upload file %s: %w", key, err)
+	}
+	body, err := filesRequest(http.MethodPost, fileURL+"/commit", "application/json", nil, apiKey, client)
+	if err != nil {
+		return fmt.Errorf("failed to commit file %s: %w", key, err)
+	}
+	var entry FileEntry
+	err = json.Unmarshal(body, &entry)
+	if err != nil {
+		return err
+	}
+	if entry.Checksum != checksum {
+		return fmt.Errorf("%w: checksum mismatch for %s, want %s, got %s", commonmeta.ErrValidation, key, checksum, entry.Checksum)
+	}
+	return nil
+}
+
+// DeleteDraftFile deletes a file from a draft record in InvenioRDM.
+func DeleteDraftFile(record commonmeta.APIResponse, key string, apiKey string, client *InvenioRDMClient) error {
+	requestURL := fmt.Sprintf("https://%s/api/records/%s/draft/files/%s", client.Host, record.ID, url.PathEscape(key))
+	_, err := filesRequest(http.MethodDelete, requestURL, "application/json", nil, apiKey, client)
+	return err
+}
+
+// uploadContent streams the content of a file to InvenioRDM, without
+// reading it into memory and without timeout.
+func uploadContent(requestURL string, file commonmeta.File, size int64, apiKey string, client *InvenioRDMClient) error {
+	r, _, err := OpenFile(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	req, err := http.NewRequest(http.MethodPut, requestURL, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	req.Header = http.Header{
+		"Content-Type":  {"application/octet-stream"},
+		"Authorization": {"Bearer " + apiKey},
+	}
+	if client.Ratelimiter != nil {
+		err = client.Ratelimiter.Wait(req.Context())
+		if err != nil {
+			return err
+		}
+	}
+	resp, err := client.transfer.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return httputils.CheckResponse(resp)
+}
+
+// filesRequest sends a request to the InvenioRDM files API and returns the response body.
+func filesRequest(method string, requestURL string, contentType string, payload []byte, apiKey string, client *InvenioRDMClient) ([]byte, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, requestURL, reader)
+	if err != nil {
+		return nil, err
+	}
+	return sendFilesRequest(req, contentType, apiKey, client)
+}
+
+// sendFilesRequest sends a request to the InvenioRDM files API and returns the response body.
+func sendFilesRequest(req *http.Request, contentType string, apiKey string, client *InvenioRDMClient) ([]byte, error) {
+	req.Header = http.Header{
+		"Content-Type":  {contentType},
+		"Authorization": {"Bearer " + apiKey},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = httputils.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,108 @@
+package inveniordm_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/inveniordm"
+)
+
+func checksum(content string) string {
+	sum := md5.Sum([]byte(content))
+	return "md5:" + hex.EncodeToString(sum[:])
+}
+
+func TestUpsertFiles(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var requests []string
+	uploaded := map[string]string{}
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, "/api/records/rec/draft/files")
+		requests = append(requests, r.Method+" "+path)
+		switch {
+		case r.Method == http.MethodGet && path == "":
+			json.NewEncoder(w).Encode(inveniordm.FileEntries{Enabled: true, Entries: []inveniordm.FileEntry{
+				{Key: "unchanged.txt", Checksum: checksum("unchanged")},
+				{Key: "removed.txt", Checksum: checksum("removed")},
+			}})
+		case r.Method == http.MethodPost && path == "":
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && strings.HasSuffix(path, "/content"):
+			content, _ := io.ReadAll(r.Body)
+			if r.ContentLength != int64(len(content)) || r.Header.Get("Content-Length") != strconv.Itoa(len(content)) {
+				http.Error(w, "missing Content-Length", http.StatusLengthRequired)
+				return
+			}
+			uploaded[strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/content")] = string(content)
+		case r.Method == http.MethodPost && strings.HasSuffix(path, "/commit"):
+			key := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/commit")
+			json.NewEncoder(w).Encode(inveniordm.FileEntry{Key: key, Checksum: checksum(uploaded[key])})
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	client := newTestClient(t, ts)
+	var downloads int
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		downloads++
+		mu.Unlock()
+		w.Write([]byte("remote content"))
+	}))
+	defer remote.Close()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"unchanged.txt": "unchanged", "paper.pdf": "new content"} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []commonmeta.File{
+		{URL: filepath.Join(dir, "unchanged.txt")},
+		{URL: "file://" + filepath.Join(dir, "paper.pdf"), Checksum: strings.TrimPrefix(checksum("new content"), "md5:")},
+		{URL: remote.URL + "/files/remote.txt"},
+	}
+	record, err := inveniordm.UpsertFiles(commonmeta.APIResponse{ID: "rec"}, files, "token", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status == "failed_files" {
+		t.Errorf("UpsertFiles: want uploaded files, got %v", record.Status)
+	}
+	want := "GET |DELETE /removed.txt|POST |PUT /paper.pdf/content|POST /paper.pdf/commit|POST |PUT /remote.txt/content|POST /remote.txt/commit"
+	if want != strings.Join(requests, "|") {
+		t.Errorf("UpsertFiles: want requests %v, got %v", want, strings.Join(requests, "|"))
+	}
+	if uploaded["paper.pdf"] != "new content" || uploaded["remote.txt"] != "remote content" {
+		t.Errorf("UpsertFiles: want content of paper.pdf and remote.txt, got %v", uploaded)
+	}
+	if downloads != 1 {
+		t.Errorf("UpsertFiles: want remote.txt downloaded once, got %v downloads", downloads)
+	}
+
+	// files that don't match their checksum are not uploaded
+	requests = nil
+	files = []commonmeta.File{{URL: filepath.Join(dir, "paper.pdf"), Checksum: checksum("other content")}}
+	record, err = inveniordm.UpsertFiles(commonmeta.APIResponse{ID: "rec"}, files, "token", client)
+	if err == nil || record.Status != "failed_files" || len(requests) > 0 {
+		t.Errorf("UpsertFiles(checksum mismatch): want failed_files, got %v %v after %v", record.Status, err, requests)
+	}
+}
@@ -0,0 +1,170 @@
+package inveniordm
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Mapping defines how commonmeta metadata are mapped to InvenioRDM
+// communities and custom fields, and which records are registered.
+// Instances with different rules can load their own mapping from a YAML
+// file, see mapping.example.yaml.
+type Mapping struct {
+	Communities  CommunityMapping    `yaml:"communities"`
+	CustomFields CustomFieldMapping  `yaml:"custom_fields"`
+	Registration RegistrationMapping `yaml:"registration"`
+}
+
+// CommunityMapping defines which commonmeta fields map to community slugs.
+// With relations, records are added to the communities given as IsPartOf
+// relation.
+type CommunityMapping struct {
+	Subjects  SlugMapping `yaml:"subjects"`
+	Container SlugMapping `yaml:"container"`
+	Publisher SlugMapping `yaml:"publisher"`
+	Relations bool        `yaml:"relations"`
+}
+
+// SlugMapping maps the values of a commonmeta field to community slugs.
+// Type is the community type, e.g. subject or blog. With slugify, values
+// not found in slugs are used as slug after normalization.
+type SlugMapping struct {
+	Enabled bool              `yaml:"enabled"`
+	Type    string            `yaml:"type,omitempty"`
+	Slugify bool              `yaml:"slugify,omitempty"`
+	Slugs   map[string]string `yaml:"slugs,omitempty"`
+}
+
+// CustomFieldMapping defines which InvenioRDM custom fields are written.
+type CustomFieldMapping struct {
+	Journal CustomFieldRule `yaml:"journal"`
+	Imprint CustomFieldRule `yaml:"imprint"`
+	Thesis  CustomFieldRule `yaml:"thesis"`
+	Code    CustomFieldRule `yaml:"code"`
+}
+
+// CustomFieldRule enables a custom field for commonmeta types, or for all
+// types if no types are given.
+type CustomFieldRule struct {
+	Enabled bool     `yaml:"enabled"`
+	Types   []string `yaml:"types,omitempty"`
+}
+
+// RegistrationMapping defines rules for registering records with the
+// InvenioRDM instances on hosts. With rogue_scholar_dois, only records with
+// a Rogue Scholar DOI are registered. With legacy_records, the Rogue Scholar
+// legacy records are updated with the InvenioRDM id.
+type RegistrationMapping struct {
+	Hosts            []string `yaml:"hosts,omitempty"`
+	RogueScholarDOIs bool     `yaml:"rogue_scholar_dois"`
+	LegacyRecords    bool     `yaml:"legacy_records"`
+}
+
+// DefaultMapping returns the mapping used by Rogue Scholar: subject area
+// communities, blog communities from IsPartOf relations, the journal
+// custom field, and only Rogue Scholar DOIs registered with rogue-scholar.org.
+func DefaultMapping() Mapping {
+	slugs := make(map[string]string, len(CommunityTranslations))
+	for k, v := range CommunityTranslations {
+		slugs[k] = v
+	}
+	return Mapping{
+		Communities: CommunityMapping{
+			Subjects: SlugMapping{
+				Enabled: true,
+				Type:    "subject",
+				Slugify: true,
+				Slugs:   slugs,
+			},
+			Container: SlugMapping{Type: "blog"},
+			Relations: true,
+		},
+		CustomFields: CustomFieldMapping{
+			Journal: CustomFieldRule{Enabled: true},
+			Imprint: CustomFieldRule{Types: []string{"Book", "BookChapter", "ProceedingsArticle"}},
+			Thesis:  CustomFieldRule{Types: []string{"Dissertation"}},
+			Code:    CustomFieldRule{Types: []string{"Software"}},
+		},
+		Registration: RegistrationMapping{
+			Hosts:            []string{"rogue-scholar.org"},
+			RogueScholarDOIs: true,
+			LegacyRecords:    true,
+		},
+	}
+}
+
+var (
+	mappingMu sync.RWMutex
+	mapping   = DefaultMapping()
+)
+
+// GetMapping returns the mapping used by Convert and Upsert.
+func GetMapping() Mapping {
+	mappingMu.RLock()
+	defer mappingMu.RUnlock()
+	return mapping
+}
+
+// SetMapping sets the mapping used by Convert and Upsert.
+func SetMapping(m Mapping) {
+	mappingMu.Lock()
+	defer mappingMu.Unlock()
+	mapping = m
+}
+
+// ResetMapping restores the default mapping.
+func ResetMapping() {
+	SetMapping(DefaultMapping())
+}
+
+// LoadMapping loads a mapping from a YAML file. Sections missing from the
+// file keep their default.
+func LoadMapping(filename string) (Mapping, error) {
+	m := DefaultMapping()
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return m, err
+	}
+	err = yaml.Unmarshal(content, &m)
+	return m, err
+}
+
+// Slug returns the community slug for a value, or an empty string if the
+// mapping is disabled or has no slug for the value.
+func (s SlugMapping) Slug(value string) string {
+	if !s.Enabled || value == "" {
+		return ""
+	}
+	key := utils.StringToSlug(value)
+	for _, k := range []string{key, value, strings.ToLower(value)} {
+		if slug := s.Slugs[k]; slug != "" {
+			return slug
+		}
+	}
+	if s.Slugify {
+		return key
+	}
+	return ""
+}
+
+// Applies reports whether the custom field is written for a commonmeta type.
+func (r CustomFieldRule) Applies(type_ string) bool {
+	return r.Enabled && (len(r.Types) == 0 || slices.Contains(r.Types, type_))
+}
+
+// Accepts reports whether a record with the DOI is registered with host.
+func (r RegistrationMapping) Accepts(host string, doi string) bool {
+	return !r.RogueScholarDOIs || !slices.Contains(r.Hosts, host) || doiutils.IsRogueScholarDOI(doi, "")
+}
+
+// UpdatesLegacyRecords reports whether the Rogue Scholar legacy records are
+// updated after registering with host.
+func (r RegistrationMapping) UpdatesLegacyRecords(host string) bool {
+	return r.LegacyRecords && slices.Contains(r.Hosts, host)
+}
@@ -0,0 +1,91 @@
+package inveniordm_test
+
+import (
+	"testing"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/inveniordm"
+)
+
+func TestLoadMapping(t *testing.T) {
+	t.Parallel()
+	m, err := inveniordm.LoadMapping("mapping.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testCase struct {
+		name string
+		want string
+		got  string
+	}
+	testCases := []testCase{
+		{name: "subject slug", want: "artificialintelligence", got: m.Communities.Subjects.Slug("AI")},
+		{name: "subject slugified", want: "naturalsciences", got: m.Communities.Subjects.Slug("Natural Sciences")},
+		{name: "default subject slug", want: "bookreview", got: m.Communities.Subjects.Slug("bjps%20review%20of%20books")},
+		{name: "container slug", want: "front_matter", got: m.Communities.Container.Slug("Front Matter")},
+		{name: "container not mapped", want: "", got: m.Communities.Container.Slug("Other Blog")},
+		{name: "publisher disabled", want: "", got: m.Communities.Publisher.Slug("Front Matter")},
+	}
+	for _, tc := range testCases {
+		if tc.want != tc.got {
+			t.Errorf("LoadMapping(%v): want %v, got %v", tc.name, tc.want, tc.got)
+		}
+	}
+	if !m.CustomFields.Imprint.Applies("BookChapter") || m.CustomFields.Imprint.Applies("Article") {
+		t.Errorf("LoadMapping(imprint): want BookChapter only, got %v", m.CustomFields.Imprint)
+	}
+	if m.Registration.UpdatesLegacyRecords("rogue-scholar.org") || !m.Registration.Accepts("rogue-scholar.org", "10.5555/12345678") {
+		t.Errorf("LoadMapping(registration): want no Rogue Scholar rules, got %v", m.Registration)
+	}
+}
+
+func TestRegistrationMapping(t *testing.T) {
+	t.Parallel()
+	r := inveniordm.DefaultMapping().Registration
+	type testCase struct {
+		host string
+		doi  string
+		want bool
+	}
+	testCases := []testCase{
+		{host: "rogue-scholar.org", doi: "https://doi.org/10.59350/k0746-rsc44", want: true},
+		{host: "rogue-scholar.org", doi: "https://doi.org/10.5555/12345678", want: false},
+		{host: "example.org", doi: "https://doi.org/10.5555/12345678", want: true},
+	}
+	for _, tc := range testCases {
+		got := r.Accepts(tc.host, tc.doi)
+		if tc.want != got {
+			t.Errorf("Accepts(%v, %v): want %v, got %v", tc.host, tc.doi, tc.want, got)
+		}
+	}
+	if !r.UpdatesLegacyRecords("rogue-scholar.org") || r.UpdatesLegacyRecords("example.org") {
+		t.Errorf("UpdatesLegacyRecords: want rogue-scholar.org only, got %v", r.Hosts)
+	}
+}
+
+func TestConvertMapping(t *testing.T) {
+	m, err := inveniordm.LoadMapping("mapping.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inveniordm.SetMapping(m)
+	defer inveniordm.ResetMapping()
+
+	data := commonmeta.Data{
+		ID:        "https://doi.org/10.5555/12345678",
+		Type:      "BookChapter",
+		Titles:    []commonmeta.Title{{Title: "A chapter"}},
+		Date:      commonmeta.Date{Published: "2025-01-01"},
+		Container: commonmeta.Container{Title: "A book", Identifier: "9780000000002", IdentifierType: "ISBN"},
+	}
+	got, err := inveniordm.Convert(data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CustomFields.Imprint == nil || got.CustomFields.Imprint.Title != "A book" || got.CustomFields.Imprint.ISBN != "9780000000002" {
+		t.Errorf("Convert(%v): want imprint A book, got %v", data.ID, got.CustomFields.Imprint)
+	}
+	if got.CustomFields.Journal.Title != "" {
+		t.Errorf("Convert(%v): want no journal, got %v", data.ID, got.CustomFields.Journal)
+	}
+}
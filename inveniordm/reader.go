@@ -2,7 +2,6 @@
 package inveniordm
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,11 +13,12 @@ import (
 	"path"
 	"slices"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/front-matter/commonmeta/authorutils"
 	"github.com/front-matter/commonmeta/commonmeta"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/ror"
 	"github.com/front-matter/commonmeta/spdx"
 	"github.com/front-matter/commonmeta/utils"
@@ -63,6 +63,7 @@ type Affiliation struct {
 type Parent struct {
 	ID          string      `json:"id"`
 	Communities Communities `json:"communities"`
+	Pids        *Pids       `json:"pids,omitempty"`
 }
 
 type Pids struct {
@@ -172,10 +173,20 @@ type Creator struct {
 }
 
 type CustomFields struct {
-	Journal      Journal `json:"journal:journal,omitempty"`
-	ContentHTML  string  `json:"rs:content_html,omitempty"`
-	FeatureImage string  `json:"rs:image,omitempty"`
-	Generator    string  `json:"rs:generator,omitempty"`
+	Journal          Journal  `json:"journal:journal,omitempty"`
+	Imprint          *Imprint `json:"imprint:imprint,omitempty"`
+	ThesisUniversity string   `json:"thesis:university,omitempty"`
+	CodeRepository   string   `json:"code:codeRepository,omitempty"`
+	ContentHTML      string   `json:"rs:content_html,omitempty"`
+	FeatureImage     string   `json:"rs:image,omitempty"`
+	Generator        string   `json:"rs:generator,omitempty"`
+}
+
+type Imprint struct {
+	Title string `json:"title,omitempty"`
+	ISBN  string `json:"isbn,omitempty"`
+	Pages string `json:"pages,omitempty"`
+	Place string `json:"place,omitempty"`
 }
 
 type Date struct {
@@ -294,7 +305,9 @@ type AwardVocabulary struct {
 }
 
 type InvenioRDMClient struct {
-	client      *http.Client
+	client *http.Client
+	// transfer is the client without timeout used for file uploads.
+	transfer    *http.Client
 	Host        string
 	Ratelimiter *rate.Limiter
 	Transport   http.RoundTripper
@@ -455,6 +468,8 @@ var InvenioToCMRelationTypeMappings = map[string]string{
 	"continues":         "Continues",
 	"isnewversionof":    "IsNewVersionOf",
 	"ispreviousversion": "IsPreviousVersion",
+	"isversionof":       "IsVersionOf",
+	"hasversion":        "HasVersion",
 	"ispartof":          "IsPartOf",
 	"haspart":           "HasPart",
 	"isreferencedby":    "IsReferencedBy",
@@ -489,6 +504,8 @@ var CMToInvenioRelationTypeMappings = map[string]string{
 	"Continues":         "continues",
 	"IsNewVersionOf":    "isnewversionof",
 	"IsPreviousVersion": "ispreviousversion",
+	"IsVersionOf":       "isversionof",
+	"HasVersion":        "hasversion",
 	"IsPartOf":          "ispartof",
 	"HasPart":           "haspart",
 	"IsReferencedBy":    "isreferencedby",
@@ -683,8 +700,9 @@ func Get(id string, client *InvenioRDMClient) (Content, error) {
 		return content, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return content, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return content, err
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -727,8 +745,9 @@ func GetAll(number int, page int, token string, community string, subject string
 		return content, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return content, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return content, err
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -1514,13 +1533,15 @@ func GetCommunityLogo(slug string, client *InvenioRDMClient) ([]byte, error) {
 	}
 }
 
+// Do sends an HTTP request, waiting for the client rate limiter if one is set.
+// Retries and per-host rate limits are handled by the shared httputils transport.
 func (c *InvenioRDMClient) Do(req *http.Request) (*http.Response, error) {
-	// Comment out the below 5 lines to turn off ratelimiting
-	// ctx := context.Background()
-	// err := c.Ratelimiter.Wait(ctx) // This is a blocking call. Honors the rate limit
-	// if err != nil {
-	// 	return nil, err
-	// }
+	if c.Ratelimiter != nil {
+		err := c.Ratelimiter.Wait(req.Context()) // This is a blocking call. Honors the rate limit
+		if err != nil {
+			return nil, err
+		}
+	}
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -1531,17 +1552,15 @@ func (c *InvenioRDMClient) Do(req *http.Request) (*http.Response, error) {
 // NewClient returns a new InvenioRDMClient. It handles rate limiting and insecure connections on localhost.
 func NewClient(rl *rate.Limiter, host string) *InvenioRDMClient {
 	c := &InvenioRDMClient{
-		client:      http.DefaultClient,
+		client:      httputils.Client(),
+		transfer:    httputils.NewClient(0),
 		Host:        host,
 		Ratelimiter: rl,
 	}
-	c.client.Timeout = time.Second * 30
-	if host == "localhost" {
-		// type assertion to check if client.Transport is of type *http.Transport
-		if tpt, ok := c.Transport.(*http.Transport); ok {
-			newTLSClientConfig := &tls.Config{InsecureSkipVerify: true}
-			tpt.TLSClientConfig = newTLSClientConfig
-		}
+	if hostname, _, _ := strings.Cut(host, ":"); hostname == "localhost" {
+		c.client = httputils.NewInsecureClient(httputils.DefaultTimeout)
+		c.transfer = httputils.NewInsecureClient(0)
 	}
+	c.Transport = c.client.Transport
 	return c
 }
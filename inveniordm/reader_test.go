@@ -42,8 +42,9 @@ func TestGet(t *testing.T) {
 		{pid: publication.ID, want: publication.Metadata.Title, err: nil},
 		{pid: preprint.ID, want: preprint.Metadata.Title, err: nil},
 	}
+	client := inveniordm.NewClient(rate.NewLimiter(rate.Every(10*time.Second), 100), "rogue-scholar.org")
 	for _, tc := range testCases {
-		got, err := inveniordm.Get(tc.pid)
+		got, err := inveniordm.Get(tc.pid, client)
 		if tc.want != got.Metadata.Title {
 			t.Errorf("InvenioRDM ID(%v): want %v, got %v, error %v",
 				tc.pid, tc.want, got, err)
@@ -85,8 +86,9 @@ func TestFetch(t *testing.T) {
 		{pid: preprint.ID, want: preprint.Metadata.Title, err: nil},
 	}
 	match := true
+	client := inveniordm.NewClient(rate.NewLimiter(rate.Every(10*time.Second), 100), "rogue-scholar.org")
 	for _, tc := range testCases {
-		got, err := inveniordm.Fetch(tc.pid, match)
+		got, err := inveniordm.Fetch(tc.pid, match, client)
 		if tc.want != got.Titles[0].Title {
 			t.Errorf("InvenioRDM ID(%v): want %v, got %v, error %v",
 				tc.pid, tc.want, got, err)
@@ -113,7 +115,9 @@ func ExampleSearchBySlug() {
 
 func ExampleSearchByType() {
 	host := "rogue-scholar.org"
-	blogs, _ := inveniordm.SearchByType(host, "blog")
+	rl := rate.NewLimiter(rate.Every(10*time.Second), 100)
+	client := inveniordm.NewClient(rl, host)
+	blogs, _ := inveniordm.SearchByType("blog", "", client)
 	fmt.Println(blogs)
 	// Output:
 	// f04b2ef6-257d-4aa1-8fcb-83039a3a9471
@@ -122,7 +126,9 @@ func ExampleSearchByType() {
 func ExampleGetCommunityLogo() {
 	host := "rogue-scholar.org"
 	slug := "front_matter"
-	logo, _ := inveniordm.GetCommunityLogo(host, slug)
+	rl := rate.NewLimiter(rate.Every(10*time.Second), 100)
+	client := inveniordm.NewClient(rl, host)
+	logo, _ := inveniordm.GetCommunityLogo(slug, client)
 	fmt.Println(len(logo))
 	// Output:
 	// 4026
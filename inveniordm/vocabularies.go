@@ -0,0 +1,237 @@
+package inveniordm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/httputils"
+	"github.com/front-matter/commonmeta/ror"
+	"gopkg.in/yaml.v3"
+)
+
+// AwardEntry represents an entry of the InvenioRDM awards vocabulary.
+type AwardEntry struct {
+	ID          string       `json:"id"`
+	Title       AwardTitle   `json:"title"`
+	Number      string       `json:"number,omitempty"`
+	Acronym     string       `json:"acronym,omitempty"`
+	Funder      AwardFunder  `json:"funder"`
+	Identifiers []Identifier `json:"identifiers,omitempty"`
+}
+
+// AwardFunder represents the funder of an award.
+type AwardFunder struct {
+	ID string `json:"id"`
+}
+
+// SyncResult counts the vocabulary entries created, updated and left unchanged.
+type SyncResult struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+// VocabularySyncResult is the result of SyncVocabularies.
+type VocabularySyncResult struct {
+	Affiliations SyncResult `json:"affiliations"`
+	Funders      SyncResult `json:"funders"`
+	Awards       SyncResult `json:"awards"`
+}
+
+// SyncVocabularies creates or updates the affiliations, funders and awards
+// vocabularies of an InvenioRDM instance. Affiliations are the ROR
+// organizations, funders are organizations of type funder plus the funders
+// of awards, and awards are taken from the embedded awards vocabulary. Only
+// entries that changed are updated.
+func SyncVocabularies(affiliations []ror.ROR, apiKey string, client *InvenioRDMClient) ([]byte, error) {
+	var result VocabularySyncResult
+	var errs []error
+
+	var awards []AwardVocabulary
+	yamlAwards, err := Vocabularies.ReadFile(filepath.Join("vocabularies", "awards.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(yamlAwards, &awards)
+	if err != nil {
+		return nil, err
+	}
+
+	// funders are converted once, and keyed by id to find the funders of awards
+	var funders []ror.InvenioRDM
+	funderIDs := map[string]bool{}
+	for _, v := range affiliations {
+		entry, err := ror.ConvertInvenioRDM(v)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, result.Affiliations.add(SyncVocabularyEntry("affiliations", entry.ID, entry, apiKey, client)))
+		if slices.Contains(v.Types, "funder") {
+			funders = append(funders, entry)
+			funderIDs[entry.ID] = true
+		}
+	}
+	for _, a := range awards {
+		if a.Funder.ID == "" || funderIDs[a.Funder.ID] {
+			continue
+		}
+		funder, err := ror.Get(a.Funder.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("funder %s: %w", a.Funder.ID, err))
+			continue
+		}
+		entry, err := ror.ConvertInvenioRDM(funder)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		funders = append(funders, entry)
+		funderIDs[a.Funder.ID] = true
+	}
+	for _, entry := range funders {
+		entry.Acronym = ""
+		errs = append(errs, result.Funders.add(SyncVocabularyEntry("funders", entry.ID, entry, apiKey, client)))
+	}
+
+	for _, a := range awards {
+		entry := AwardEntry{
+			ID:      a.ID,
+			Title:   AwardTitle{En: a.Title.En},
+			Number:  a.Number,
+			Acronym: a.Acronym,
+			Funder:  AwardFunder{ID: a.Funder.ID},
+		}
+		for _, i := range a.Identifiers {
+			entry.Identifiers = append(entry.Identifiers, Identifier{Identifier: i.Identifier, Scheme: i.Scheme})
+		}
+		errs = append(errs, result.Awards.add(SyncVocabularyEntry("awards", entry.ID, entry, apiKey, client)))
+	}
+
+	output, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return output, errors.Join(errs...)
+}
+
+// add counts the outcome of syncing a vocabulary entry.
+func (r *SyncResult) add(status string, err error) error {
+	switch status {
+	case "created":
+		r.Created++
+	case "updated":
+		r.Updated++
+	case "unchanged":
+		r.Unchanged++
+	}
+	return err
+}
+
+// SyncVocabularyEntry creates or updates an entry of an InvenioRDM
+// vocabulary, e.g. affiliations, funders or awards. The entry is only
+// updated if one of its fields changed. It returns created, updated or
+// unchanged.
+func SyncVocabularyEntry(vocabulary string, id string, entry any, apiKey string, client *InvenioRDMClient) (string, error) {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	requestURL := fmt.Sprintf("https://%s/api/%s/%s", client.Host, vocabulary, url.PathEscape(id))
+	existing, err := vocabularyRequest(http.MethodGet, requestURL, nil, apiKey, client)
+	if errors.Is(err, commonmeta.ErrNotFound) {
+		requestURL = fmt.Sprintf("https://%s/api/%s", client.Host, vocabulary)
+		_, err = vocabularyRequest(http.MethodPost, requestURL, payload, apiKey, client)
+		if err != nil {
+			return "", fmt.Errorf("%s %s: %w", vocabulary, id, err)
+		}
+		return "created", nil
+	} else if err != nil {
+		return "", fmt.Errorf("%s %s: %w", vocabulary, id, err)
+	}
+	if !vocabularyChanged(existing, payload) {
+		return "unchanged", nil
+	}
+	_, err = vocabularyRequest(http.MethodPut, requestURL, payload, apiKey, client)
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", vocabulary, id, err)
+	}
+	return "updated", nil
+}
+
+// vocabularyChanged reports whether any field of the new entry differs from
+// the existing entry. Only the fields that were sent are compared, as
+// InvenioRDM adds fields, e.g. created or revision_id, and expands nested
+// objects, e.g. the name of the funder of an award.
+func vocabularyChanged(existing []byte, entry []byte) bool {
+	var old, updated any
+	if json.Unmarshal(existing, &old) != nil || json.Unmarshal(entry, &updated) != nil {
+		return true
+	}
+	return !containsValue(old, updated)
+}
+
+// containsValue reports whether the existing value contains the sent value,
+// ignoring object fields that were not sent.
+func containsValue(existing any, sent any) bool {
+	switch sent := sent.(type) {
+	case map[string]any:
+		existing, ok := existing.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, value := range sent {
+			if !containsValue(existing[key], value) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		existing, ok := existing.([]any)
+		if !ok || len(existing) != len(sent) {
+			return false
+		}
+		for i, value := range sent {
+			if !containsValue(existing[i], value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(existing, sent)
+	}
+}
+
+// vocabularyRequest sends a request to the InvenioRDM vocabularies API and returns the response body.
+func vocabularyRequest(method string, requestURL string, payload []byte, apiKey string, client *InvenioRDMClient) ([]byte, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, requestURL, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = http.Header{
+		"Content-Type":  {"application/json"},
+		"Authorization": {"Bearer " + apiKey},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = httputils.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
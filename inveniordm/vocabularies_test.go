@@ -0,0 +1,161 @@
+package inveniordm_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/front-matter/commonmeta/inveniordm"
+	"github.com/front-matter/commonmeta/ror"
+)
+
+// newVocabularies returns a stand-in for the InvenioRDM vocabularies API,
+// storing entries by path.
+func newVocabularies(t *testing.T) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	entries := map[string][]byte{}
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method {
+		case http.MethodGet:
+			entry, ok := entries[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			// InvenioRDM adds fields of its own
+			var fields map[string]any
+			json.Unmarshal(entry, &fields)
+			fields["revision_id"] = 1
+			// and expands nested objects, e.g. the funder of an award
+			if funder, ok := fields["funder"].(map[string]any); ok {
+				funder["name"] = "Funder " + funder["id"].(string)
+			}
+			json.NewEncoder(w).Encode(fields)
+		case http.MethodPost:
+			var entry struct {
+				ID string `json:"id"`
+			}
+			json.Unmarshal(body, &entry)
+			entries[r.URL.Path+"/"+entry.ID] = body
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		case http.MethodPut:
+			entries[r.URL.Path] = body
+			w.Write(body)
+		}
+	}))
+}
+
+func organization(id string, name string, types ...string) ror.ROR {
+	return ror.ROR{
+		ID:    "https://ror.org/" + id,
+		Names: ror.Names{{Value: name, Types: ror.Strings{"ror_display"}}},
+		Types: types,
+	}
+}
+
+func TestSyncVocabularies(t *testing.T) {
+	t.Parallel()
+	ts := newVocabularies(t)
+	defer ts.Close()
+	client := newTestClient(t, ts)
+
+	// the funders of all awards in the embedded awards vocabulary
+	affiliations := []ror.ROR{
+		organization("00k4n6c32", "European Commission", "funder", "government"),
+		organization("018mejw64", "Deutsche Forschungsgemeinschaft", "funder"),
+		organization("05dxps055", "Example University", "education"),
+	}
+
+	type testCase struct {
+		name         string
+		affiliations []ror.ROR
+		want         inveniordm.VocabularySyncResult
+	}
+	updated := append([]ror.ROR{}, affiliations...)
+	updated[2] = organization("05dxps055", "Example University of Technology", "education")
+	testCases := []testCase{
+		{name: "create", affiliations: affiliations, want: inveniordm.VocabularySyncResult{
+			Affiliations: inveniordm.SyncResult{Created: 3},
+			Funders:      inveniordm.SyncResult{Created: 2},
+			Awards:       inveniordm.SyncResult{Created: 3},
+		}},
+		{name: "unchanged", affiliations: affiliations, want: inveniordm.VocabularySyncResult{
+			Affiliations: inveniordm.SyncResult{Unchanged: 3},
+			Funders:      inveniordm.SyncResult{Unchanged: 2},
+			Awards:       inveniordm.SyncResult{Unchanged: 3},
+		}},
+		{name: "update", affiliations: updated, want: inveniordm.VocabularySyncResult{
+			Affiliations: inveniordm.SyncResult{Updated: 1, Unchanged: 2},
+			Funders:      inveniordm.SyncResult{Unchanged: 2},
+			Awards:       inveniordm.SyncResult{Unchanged: 3},
+		}},
+	}
+	for _, tc := range testCases {
+		output, err := inveniordm.SyncVocabularies(tc.affiliations, "token", client)
+		if err != nil {
+			t.Fatalf("SyncVocabularies(%v): %v", tc.name, err)
+		}
+		var got inveniordm.VocabularySyncResult
+		err = json.Unmarshal(output, &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("SyncVocabularies(%v): want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestSyncVocabularyEntry(t *testing.T) {
+	t.Parallel()
+	var puts int
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			// the award as returned by InvenioRDM, with the funder expanded
+			w.Write([]byte(`{"id":"00k4n6c32::101000001","title":{"en":"Example award"},"number":"101000001","funder":{"id":"00k4n6c32","name":"European Commission"},"identifiers":[{"identifier":"https://cordis.europa.eu/project/id/101000001","scheme":"url"}],"created":"2024-01-01T00:00:00Z","revision_id":2}`))
+		case http.MethodPut:
+			puts++
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer ts.Close()
+	client := newTestClient(t, ts)
+
+	type testCase struct {
+		name  string
+		title string
+		want  string
+		puts  int
+	}
+	testCases := []testCase{
+		{name: "unchanged", title: "Example award", want: "unchanged", puts: 0},
+		{name: "updated", title: "Updated award", want: "updated", puts: 1},
+	}
+	for _, tc := range testCases {
+		entry := inveniordm.AwardEntry{
+			ID:     "00k4n6c32::101000001",
+			Title:  inveniordm.AwardTitle{En: tc.title},
+			Number: "101000001",
+			Funder: inveniordm.AwardFunder{ID: "00k4n6c32"},
+			Identifiers: []inveniordm.Identifier{
+				{Identifier: "https://cordis.europa.eu/project/id/101000001", Scheme: "url"},
+			},
+		}
+		got, err := inveniordm.SyncVocabularyEntry("awards", entry.ID, entry, "token", client)
+		if err != nil {
+			t.Fatalf("SyncVocabularyEntry(%v): %v", tc.name, err)
+		}
+		if tc.want != got || tc.puts != puts {
+			t.Errorf("SyncVocabularyEntry(%v): want %v with %d PUT, got %v with %d PUT", tc.name, tc.want, tc.puts, got, puts)
+		}
+	}
+}
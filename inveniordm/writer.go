@@ -13,9 +13,11 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/front-matter/commonmeta/audit"
 	"github.com/front-matter/commonmeta/commonmeta"
 	"github.com/front-matter/commonmeta/dateutils"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/roguescholar"
 	"github.com/front-matter/commonmeta/ror"
 	"github.com/front-matter/commonmeta/schemautils"
@@ -129,23 +131,48 @@ func Convert(data commonmeta.Data, fromHost string) (Inveniordm, error) {
 
 	// optional properties
 
-	if data.Container.Title != "" {
-		inveniordm.CustomFields.Journal.Title = data.Container.Title
-	}
 	if data.Container.Platform != "" {
 		inveniordm.CustomFields.Generator = data.Container.Platform
 	}
-	if data.Container.Volume != "" {
-		inveniordm.CustomFields.Journal.Volume = data.Container.Volume
+
+	// custom fields defined in the mapping
+	customFields := GetMapping().CustomFields
+	if customFields.Journal.Applies(data.Type) {
+		if data.Container.Title != "" {
+			inveniordm.CustomFields.Journal.Title = data.Container.Title
+		}
+		if data.Container.Volume != "" {
+			inveniordm.CustomFields.Journal.Volume = data.Container.Volume
+		}
+		if data.Container.Issue != "" {
+			inveniordm.CustomFields.Journal.Issue = data.Container.Issue
+		}
+		if data.Container.FirstPage != "" {
+			inveniordm.CustomFields.Journal.Pages = data.Container.Pages()
+		}
+		if data.Container.Identifier != "" && data.Container.IdentifierType == "ISSN" {
+			inveniordm.CustomFields.Journal.ISSN = data.Container.Identifier
+		}
 	}
-	if data.Container.Issue != "" {
-		inveniordm.CustomFields.Journal.Issue = data.Container.Issue
+	if customFields.Imprint.Applies(data.Type) && data.Container.Title != "" {
+		imprint := Imprint{Title: data.Container.Title}
+		if data.Container.IdentifierType == "ISBN" {
+			imprint.ISBN = data.Container.Identifier
+		}
+		if data.Container.FirstPage != "" {
+			imprint.Pages = data.Container.Pages()
+		}
+		inveniordm.CustomFields.Imprint = &imprint
 	}
-	if data.Container.FirstPage != "" {
-		inveniordm.CustomFields.Journal.Pages = data.Container.Pages()
+	if customFields.Thesis.Applies(data.Type) {
+		inveniordm.CustomFields.ThesisUniversity = data.Publisher.Name
 	}
-	if data.Container.Identifier != "" && data.Container.IdentifierType == "ISSN" {
-		inveniordm.CustomFields.Journal.ISSN = data.Container.Identifier
+	if customFields.Code.Applies(data.Type) {
+		if data.Container.IdentifierType == "URL" {
+			inveniordm.CustomFields.CodeRepository = data.Container.Identifier
+		} else {
+			inveniordm.CustomFields.CodeRepository = data.URL
+		}
 	}
 
 	// optional custom fields
@@ -418,22 +445,46 @@ func WriteAll(list []commonmeta.Data, fromHost string) ([]byte, error) {
 	return output, err
 }
 
+// UpsertOptions are the options of Upsert and UpsertAll.
+type UpsertOptions struct {
+	// FromHost is the InvenioRDM host the metadata were read from.
+	FromHost string
+	// LegacyKey is the API key of the Rogue Scholar legacy database.
+	LegacyKey string
+	// Files uploads the files of a record from their local path or URL.
+	Files bool
+	// NewVersion creates a record not yet in InvenioRDM as new version of
+	// the record it is a version of.
+	NewVersion bool
+	// Workers is the number of concurrent requests of UpsertAll.
+	Workers int
+}
+
 // Upsert updates or creates a record in InvenioRDM.
-func Upsert(record commonmeta.APIResponse, fromHost string, apiKey string, legacyKey string, data commonmeta.Data, client *InvenioRDMClient) (commonmeta.APIResponse, error) {
-	if client.Host == "rogue-scholar.org" && !doiutils.IsRogueScholarDOI(data.ID, "") {
+func Upsert(record commonmeta.APIResponse, data commonmeta.Data, apiKey string, options UpsertOptions, client *InvenioRDMClient) (result commonmeta.APIResponse, err error) {
+	if !GetMapping().Registration.Accepts(client.Host, data.ID) {
 		record.Status = "failed_not_rogue_scholar_doi"
 		return record, nil
 	}
 
-	inveniordm, err := Convert(data, fromHost)
+	inveniordm, err := Convert(data, options.FromHost)
 	if err != nil {
 		return record, err
 	}
 
+	// record the outcome in the audit log once the payload is known
+	payload, _ := json.Marshal(inveniordm)
+	defer func() {
+		audit.Record("upsert", "inveniordm", data.ID, payload, result.Status, err)
+	}()
+	if options.Files && len(data.Files) > 0 {
+		inveniordm.Files.Enabled = true
+	}
+
 	doi, ok := doiutils.ValidateDOI(data.ID)
 	if !ok {
 		record.Status = "failed"
-		return record, fmt.Errorf("missing or invalid DOI")
+		return record, fmt.Errorf("%w: missing or invalid DOI", commonmeta.ErrValidation)
 	}
 
 	record.DOI = doi
@@ -441,13 +492,15 @@ func Upsert(record commonmeta.APIResponse, fromHost string, apiKey string, legac
 	// check if required metadata are present
 	if inveniordm.Metadata.PublicationDate == "" {
 		record.Status = "failed"
-		return record, fmt.Errorf("missing publication date: %s", record.DOI)
+		return record, fmt.Errorf("%w: missing publication date: %s", commonmeta.ErrValidation, record.DOI)
 	}
 
+	communities := GetMapping().Communities
+
 	// remove IsPartOf relation with InvendioRDM community identifier after storing it
 	var communityIndex int
 	for i, v := range data.Relations {
-		if v.Type == "IsPartOf" && strings.HasPrefix(v.ID, fmt.Sprintf("https://%s/api/communities/", client.Host)) {
+		if communities.Relations && v.Type == "IsPartOf" && strings.HasPrefix(v.ID, fmt.Sprintf("https://%s/api/communities/", client.Host)) {
 			slug := strings.Split(v.ID, "/")[5]
 			communityID, _ := SearchBySlug(slug, "blog", client)
 			if communityID != "" {
@@ -480,35 +533,33 @@ func Upsert(record commonmeta.APIResponse, fromHost string, apiKey string, legac
 		}
 	}
 
-	// add record to subject area community if subject area community is specified and exists
-	// subject area communities should exist for all subjects in the FOSMappings
+	// add record to subject area communities defined in the mapping
 	if len(data.Subjects) > 0 {
-		var slug, communityID string
 		for _, v := range data.Subjects {
-			slug = utils.StringToSlug(v.Subject)
-			if synonym := CommunityTranslations[slug]; synonym != "" {
-				slug = synonym
-			}
-			communityID, err = SearchBySlug(slug, "subject", client)
+			record, err = addRecordToMappedCommunity(record, communities.Subjects, v.Subject, apiKey, client)
 			if err != nil {
-				fmt.Println(err)
-			}
-			if communityID != "" {
-				record, err = AddRecordToCommunity(record, client, apiKey, communityID)
-				if err != nil {
-					return record, err
-				}
+				return record, err
 			}
 		}
 	}
 
+	// add record to container and publisher communities defined in the mapping
+	record, err = addRecordToMappedCommunity(record, communities.Container, data.Container.Title, apiKey, client)
+	if err != nil {
+		return record, err
+	}
+	record, err = addRecordToMappedCommunity(record, communities.Publisher, data.Publisher.Name, apiKey, client)
+	if err != nil {
+		return record, err
+	}
+
 	// add record to communities defined as IsPartOf relation in inveniordm.Metadata.RelatedIdentifiers
-	if len(inveniordm.Metadata.RelatedIdentifiers) > 0 {
+	if communities.Relations && len(inveniordm.Metadata.RelatedIdentifiers) > 0 {
 		for i := len(inveniordm.Metadata.RelatedIdentifiers) - 1; i >= 0; i-- {
 			if inveniordm.Metadata.RelatedIdentifiers[i].RelationType.ID == "ispartof" {
 				u, _ := url.Parse(inveniordm.Metadata.RelatedIdentifiers[i].Identifier)
 				c := strings.Split(u.Path, "/")
-				if u.Host == fromHost && len(c) == 4 && c[2] == "communities" {
+				if u.Host == options.FromHost && len(c) == 4 && c[2] == "communities" {
 					record, err = AddRecordToCommunity(record, client, apiKey, c[3])
 					if err != nil {
 						return record, err
@@ -522,7 +573,53 @@ func Upsert(record commonmeta.APIResponse, fromHost string, apiKey string, legac
 
 	// check if record already exists in InvenioRDM
 	record.ID, _ = SearchByDOI(data.ID, client)
-	if record.ID == "" {
+	if record.ID != "" && options.NewVersion {
+		record.Status = "failed_version_exists"
+		return record, fmt.Errorf("%w: can't create a new version, %s already exists", commonmeta.ErrValidation, record.DOI)
+	} else if record.ID == "" && options.NewVersion {
+		// create new version from the latest published version
+		previousDOI := PreviousVersionDOI(data)
+		if previousDOI == "" {
+			record.Status = "failed"
+			return record, fmt.Errorf("%w: a new version requires an IsNewVersionOf or IsVersionOf relation: %s", commonmeta.ErrValidation, record.DOI)
+		}
+		record.ID, _ = SearchByDOI(previousDOI, client)
+		if record.ID == "" {
+			record.Status = "failed"
+			return record, fmt.Errorf("%w: previous version %s", commonmeta.ErrNotFound, previousDOI)
+		}
+		var draft Inveniordm
+		record, draft, err = CreateNewVersion(record, apiKey, client)
+		if err != nil {
+			return record, err
+		}
+		// keep the parent and its concept DOI, and carry over files and the
+		// IsVersionOf relation
+		inveniordm.Parent.ID = draft.Parent.ID
+		inveniordm.Parent.Pids = draft.Parent.Pids
+		if conceptDOI := ConceptDOI(data); inveniordm.Parent.Pids == nil && conceptDOI != "" {
+			inveniordm.Parent.Pids = &Pids{DOI: DOI{Identifier: conceptDOI, Provider: "external"}}
+		}
+		if draft.Files.Enabled {
+			inveniordm.Files.Enabled = true
+			record, err = ImportFiles(record, apiKey, client)
+			if err != nil {
+				return record, err
+			}
+		}
+		for _, v := range draft.Metadata.RelatedIdentifiers {
+			if v.RelationType.ID == "isversionof" && !slices.ContainsFunc(inveniordm.Metadata.RelatedIdentifiers, func(r RelatedIdentifier) bool {
+				return r.RelationType.ID == "isversionof"
+			}) {
+				inveniordm.Metadata.RelatedIdentifiers = append(inveniordm.Metadata.RelatedIdentifiers, v)
+			}
+		}
+		// update draft record with new DOI and version
+		record, err = UpdateDraftRecord(record, apiKey, inveniordm, client)
+		if err != nil {
+			return record, err
+		}
+	} else if record.ID == "" {
 		// create draft record
 		record, err = CreateDraftRecord(record, apiKey, inveniordm, client)
 		if err != nil {
@@ -541,15 +638,23 @@ func Upsert(record commonmeta.APIResponse, fromHost string, apiKey string, legac
 		}
 	}
 
+	// upload files, replacing changed files only
+	if options.Files && len(data.Files) > 0 {
+		record, err = UpsertFiles(record, data.Files, apiKey, client)
+		if err != nil {
+			return record, err
+		}
+	}
+
 	// publish draft record
 	record, err = PublishDraftRecord(record, apiKey, client)
 	if err != nil {
 		return record, err
 	}
 
-	// update rogue-scholar legacy record with Invenio rid if host is rogue-scholar.org
-	if client.Host == "rogue-scholar.org" && legacyKey != "" {
-		record, err = roguescholar.UpdateLegacyRecord(record, legacyKey, "rid")
+	// update Rogue Scholar legacy record with Invenio rid if the mapping says so
+	if GetMapping().Registration.UpdatesLegacyRecords(client.Host) && options.LegacyKey != "" {
+		record, err = roguescholar.UpdateLegacyRecord(record, options.LegacyKey, "rid")
 		if err != nil {
 			return record, err
 		}
@@ -557,25 +662,56 @@ func Upsert(record commonmeta.APIResponse, fromHost string, apiKey string, legac
 	return record, nil
 }
 
-// UpsertAll updates or creates a list of records in InvenioRDM.
-func UpsertAll(list []commonmeta.Data, fromHost string, apiKey string, legacyKey string, client *InvenioRDMClient) ([]commonmeta.APIResponse, error) {
-	var records []commonmeta.APIResponse
+// addRecordToMappedCommunity adds a record to the community a value is
+// mapped to, if the community exists.
+func addRecordToMappedCommunity(record commonmeta.APIResponse, m SlugMapping, value string, apiKey string, client *InvenioRDMClient) (commonmeta.APIResponse, error) {
+	slug := m.Slug(value)
+	if slug == "" {
+		return record, nil
+	}
+	communityID, err := SearchBySlug(slug, m.Type, client)
+	if err != nil {
+		fmt.Println(err)
+	}
+	if communityID == "" {
+		return record, nil
+	}
+	return AddRecordToCommunity(record, client, apiKey, communityID)
+}
 
-	for _, data := range list {
+// UpsertAll updates or creates a list of records in InvenioRDM, using at
+// most options.Workers concurrent requests. New versions are not created.
+// Errors are stored in the Error field of each record and returned together.
+func UpsertAll(list []commonmeta.Data, apiKey string, options UpsertOptions, client *InvenioRDMClient) ([]commonmeta.APIResponse, error) {
+	options.NewVersion = false
+	type result struct {
+		record commonmeta.APIResponse
+		err    error
+	}
+	results := utils.ParallelMap(list, options.Workers, func(data commonmeta.Data) result {
 		record := commonmeta.APIResponse{ID: data.ID}
 		doi, ok := doiutils.ValidateDOI(data.ID)
 		if !ok && doi == "" {
 			record.Status = "failed_missing_doi"
-		} else if client.Host == "rogue-scholar.org" && !doiutils.IsRogueScholarDOI(data.ID, "") {
+			return result{record, nil}
+		} else if !GetMapping().Registration.Accepts(client.Host, data.ID) {
 			record.Status = "failed_not_rogue_scholar_doi"
-		} else {
-			record, _ = Upsert(record, fromHost, apiKey, legacyKey, data, client)
+			return result{record, nil}
 		}
+		record, err := Upsert(record, data, apiKey, options, client)
+		return result{record, err}
+	})
 
-		records = append(records, record)
+	var records []commonmeta.APIResponse
+	var errs []error
+	for i, r := range results {
+		if r.err != nil {
+			r.record.Error = r.err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", list[i].ID, r.err))
+		}
+		records = append(records, r.record)
 	}
-
-	return records, nil
+	return records, errors.Join(errs...)
 }
 
 // CreateDraftRecord creates a draft record in InvenioRDM.
@@ -608,13 +744,17 @@ func CreateDraftRecord(record commonmeta.APIResponse, apiKey string, inveniordm
 	defer resp.Body.Close()
 	if resp.StatusCode == 429 {
 		record.Status = "failed_rate_limited"
-		return record, fmt.Errorf("rate limited")
+		return record, commonmeta.ErrRateLimited
 	}
 	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != 201 {
 		fmt.Println(string(body), record)
 		record.Status = "failed_create_draft"
-		return record, errors.New("failed to create draft record:" + string(body))
+		return record, fmt.Errorf("failed to create draft record: %w", &httputils.StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(body),
+		})
 	}
 	err = json.Unmarshal(body, &response)
 	if err != nil {
@@ -650,6 +790,10 @@ func EditPublishedRecord(record commonmeta.APIResponse, apiKey string, client *I
 		return record, err
 	}
 	defer resp.Body.Close()
+	if err = httputils.CheckResponse(resp); err != nil {
+		record.Status = "failed"
+		return record, fmt.Errorf("failed to edit published record: %w", err)
+	}
 	body, _ := io.ReadAll(resp.Body)
 	err = json.Unmarshal(body, &response)
 	if err != nil {
@@ -659,6 +803,119 @@ func EditPublishedRecord(record commonmeta.APIResponse, apiKey string, client *I
 	return record, nil
 }
 
+// PreviousVersionDOI returns the DOI of the previous version of a record,
+// falling back to the concept DOI it is a version of.
+func PreviousVersionDOI(data commonmeta.Data) string {
+	for _, relationType := range []string{"IsNewVersionOf", "IsVersionOf"} {
+		for _, v := range data.Relations {
+			if v.Type == relationType {
+				if doi, ok := doiutils.ValidateDOI(v.ID); ok {
+					return doi
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// ConceptDOI returns the concept DOI of a record, i.e. the DOI of all its
+// versions given as IsVersionOf relation.
+func ConceptDOI(data commonmeta.Data) string {
+	for _, v := range data.Relations {
+		if v.Type == "IsVersionOf" {
+			if doi, ok := doiutils.ValidateDOI(v.ID); ok {
+				return doi
+			}
+		}
+	}
+	return ""
+}
+
+// CreateNewVersion creates a new version of the latest published version of
+// a record in InvenioRDM. The new version is a draft record, returned with
+// the metadata copied from the previous version.
+func CreateNewVersion(record commonmeta.APIResponse, apiKey string, client *InvenioRDMClient) (commonmeta.APIResponse, Inveniordm, error) {
+	type Response struct {
+		Inveniordm
+		Created string `json:"created,omitempty"`
+		Updated string `json:"updated,omitempty"`
+	}
+	var response Response
+
+	// find the latest version
+	requestURL := fmt.Sprintf("https://%s/api/records/%s/versions/latest", client.Host, record.ID)
+	req, _ := http.NewRequest(http.MethodGet, requestURL, nil)
+	req.Header = http.Header{
+		"Content-Type":  {"application/json"},
+		"Authorization": {"Bearer " + apiKey},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return record, response.Inveniordm, err
+	}
+	defer resp.Body.Close()
+	if err = httputils.CheckResponse(resp); err != nil {
+		record.Status = "failed_new_version"
+		return record, response.Inveniordm, err
+	}
+	body, _ := io.ReadAll(resp.Body)
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return record, response.Inveniordm, err
+	}
+	if response.ID != "" {
+		record.ID = response.ID
+	}
+
+	requestURL = fmt.Sprintf("https://%s/api/records/%s/versions", client.Host, record.ID)
+	req, _ = http.NewRequest(http.MethodPost, requestURL, nil)
+	req.Header = http.Header{
+		"Content-Type":  {"application/json"},
+		"Authorization": {"Bearer " + apiKey},
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		return record, response.Inveniordm, err
+	}
+	defer resp.Body.Close()
+	if err = httputils.CheckResponse(resp); err != nil {
+		record.Status = "failed_new_version"
+		return record, response.Inveniordm, err
+	}
+	body, _ = io.ReadAll(resp.Body)
+	response = Response{}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return record, response.Inveniordm, err
+	}
+	record.ID = response.ID
+	record.Created = response.Created
+	record.Updated = response.Updated
+	record.Status = "draft"
+	return record, response.Inveniordm, nil
+}
+
+// ImportFiles imports the files of the previous version into a new version
+// draft record in InvenioRDM.
+func ImportFiles(record commonmeta.APIResponse, apiKey string, client *InvenioRDMClient) (commonmeta.APIResponse, error) {
+	requestURL := fmt.Sprintf("https://%s/api/records/%s/draft/actions/files-import", client.Host, record.ID)
+	req, _ := http.NewRequest(http.MethodPost, requestURL, nil)
+	req.Header = http.Header{
+		"Content-Type":  {"application/json"},
+		"Authorization": {"Bearer " + apiKey},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return record, err
+	}
+	defer resp.Body.Close()
+	if err = httputils.CheckResponse(resp); err != nil {
+		record.Status = "failed_files"
+		return record, err
+	}
+	return record, nil
+}
+
 // UpdateDraftRecord updates a draft record in InvenioRDM.
 func UpdateDraftRecord(record commonmeta.APIResponse, apiKey string, inveniordm Inveniordm, client *InvenioRDMClient) (commonmeta.APIResponse, error) {
 	output, err := json.Marshal(inveniordm)
@@ -683,11 +940,11 @@ func UpdateDraftRecord(record commonmeta.APIResponse, apiKey string, inveniordm
 		return record, err
 	}
 	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return record, err
+	if err = httputils.CheckResponse(resp); err != nil {
+		record.Status = "failed"
+		return record, fmt.Errorf("failed to update draft record: %w", err)
 	}
+	body, _ := io.ReadAll(resp.Body)
 	err = json.Unmarshal(body, &response)
 	if err != nil {
 		return record, err
@@ -716,11 +973,11 @@ func PublishDraftRecord(record commonmeta.APIResponse, apiKey string, client *In
 		return record, err
 	}
 	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != 202 {
-		fmt.Println(string(body), record)
-		return record, err
+	if err = httputils.CheckResponse(resp); err != nil {
+		record.Status = "failed"
+		return record, fmt.Errorf("failed to publish draft record: %w", err)
 	}
+	body, _ := io.ReadAll(resp.Body)
 	err = json.Unmarshal(body, &response)
 	if err != nil {
 		return record, err
@@ -731,7 +988,7 @@ func PublishDraftRecord(record commonmeta.APIResponse, apiKey string, client *In
 	return record, nil
 }
 
-// DeleteDraftRecord publishes a draft record in InvenioRDM.
+// DeleteDraftRecord deletes a draft record in InvenioRDM.
 func DeleteDraftRecord(record commonmeta.APIResponse, apiKey string, client *InvenioRDMClient) (commonmeta.APIResponse, error) {
 	requestURL := fmt.Sprintf("https://%s/api/records/%s/draft", client.Host, record.ID)
 	req, _ := http.NewRequest(http.MethodDelete, requestURL, nil)
@@ -744,15 +1001,110 @@ func DeleteDraftRecord(record commonmeta.APIResponse, apiKey string, client *Inv
 		return record, err
 	}
 	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != 204 {
-		fmt.Println(string(body), record)
+	if err = httputils.CheckResponse(resp); err != nil {
+		record.Status = "failed"
+		audit.Record("delete_draft", "inveniordm", record.ID, nil, record.Status, err)
 		return record, err
 	}
 	record.Status = "deleted"
+	audit.Record("delete_draft", "inveniordm", record.ID, nil, record.Status, nil)
 	return record, nil
 }
 
+// DeletePublishedRecord deletes a published record in InvenioRDM, leaving a
+// tombstone page with the reason. Reason is the id of a removal reason, e.g.
+// spam, misconduct, retracted or other. This requires an administrator token.
+func DeletePublishedRecord(record commonmeta.APIResponse, reason string, note string, apiKey string, client *InvenioRDMClient) (commonmeta.APIResponse, error) {
+	type RemovalReason struct {
+		ID string `json:"id"`
+	}
+	type Tombstone struct {
+		RemovalReason RemovalReason `json:"removal_reason"`
+		Note          string        `json:"note,omitempty"`
+		IsVisible     bool          `json:"is_visible"`
+	}
+	if reason == "" {
+		return record, fmt.Errorf("%w: a reason is required to delete published record %s", commonmeta.ErrValidation, record.ID)
+	}
+	tombstone := Tombstone{
+		RemovalReason: RemovalReason{ID: reason},
+		Note:          note,
+		IsVisible:     true,
+	}
+	payload, err := json.Marshal(tombstone)
+	if err != nil {
+		return record, err
+	}
+	requestURL := fmt.Sprintf("https://%s/api/records/%s/delete", client.Host, record.ID)
+	req, _ := http.NewRequest(http.MethodPost, requestURL, bytes.NewReader(payload))
+	req.Header = http.Header{
+		"Content-Type":  {"application/json"},
+		"Authorization": {"Bearer " + apiKey},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return record, err
+	}
+	defer resp.Body.Close()
+	if err = httputils.CheckResponse(resp); err != nil {
+		record.Status = "failed"
+		audit.Record("delete", "inveniordm", record.ID, payload, record.Status, err)
+		return record, err
+	}
+	record.Status = "deleted"
+	record.Message = reason
+	audit.Record("delete", "inveniordm", record.ID, payload, record.Status, nil)
+	return record, nil
+}
+
+// Delete deletes a record in InvenioRDM. A published record is deleted
+// with the reason, leaving a tombstone. With draft, the draft of the record
+// is deleted instead: an unpublished record, or the unpublished edits of a
+// published record. Records that only exist as a draft are not deleted
+// without draft.
+func Delete(record commonmeta.APIResponse, reason string, note string, draft bool, apiKey string, client *InvenioRDMClient) (commonmeta.APIResponse, error) {
+	if draft {
+		return DeleteDraftRecord(record, apiKey, client)
+	}
+	published, err := recordExists(fmt.Sprintf("https://%s/api/records/%s", client.Host, record.ID), apiKey, client)
+	if err != nil {
+		record.Status = "failed"
+		return record, err
+	}
+	if published {
+		return DeletePublishedRecord(record, reason, note, apiKey, client)
+	}
+	unpublished, err := recordExists(fmt.Sprintf("https://%s/api/records/%s/draft", client.Host, record.ID), apiKey, client)
+	if err != nil {
+		record.Status = "failed"
+		return record, err
+	}
+	record.Status = "failed"
+	if unpublished {
+		return record, fmt.Errorf("%w: record %s is an unpublished draft, delete the draft instead", commonmeta.ErrValidation, record.ID)
+	}
+	return record, fmt.Errorf("%w: record %s", commonmeta.ErrNotFound, record.ID)
+}
+
+// recordExists checks whether a record or draft exists in InvenioRDM.
+func recordExists(requestURL string, apiKey string, client *InvenioRDMClient) (bool, error) {
+	req, _ := http.NewRequest(http.MethodGet, requestURL, nil)
+	req.Header = http.Header{
+		"Content-Type":  {"application/json"},
+		"Authorization": {"Bearer " + apiKey},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if errors.Is(err, commonmeta.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 // CreateSubjectCommunities creates communities for each subject in FOSKeyMappings
 func CreateSubjectCommunities(apiKey string, client *InvenioRDMClient) ([]byte, error) {
 	var communities []Community
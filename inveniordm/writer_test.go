@@ -2,6 +2,10 @@ package inveniordm_test
 
 import (
 	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,6 +15,7 @@ import (
 	"github.com/front-matter/commonmeta/crossref"
 	"github.com/front-matter/commonmeta/datacite"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/inveniordm"
 	"github.com/google/go-cmp/cmp"
 )
@@ -94,3 +99,155 @@ func TestWrite(t *testing.T) {
 // 	// Output:
 // 	// 10.59350%2Fk0746-rsc44
 // }
+
+// newTestClient returns a client for a stand-in InvenioRDM server, using
+// localhost so that the self-signed certificate is accepted.
+func newTestClient(t *testing.T, ts *httptest.Server) *inveniordm.InvenioRDMClient {
+	t.Helper()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inveniordm.NewClient(nil, "localhost:"+u.Port())
+}
+
+func TestDelete(t *testing.T) {
+	t.Parallel()
+	var requests []string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/records/published", "GET /api/records/published/draft", "GET /api/records/draft/draft":
+			w.Write([]byte(`{}`))
+		case "POST /api/records/published/delete", "DELETE /api/records/published/draft", "DELETE /api/records/draft/draft":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	client := newTestClient(t, ts)
+
+	type testCase struct {
+		id     string
+		draft  bool
+		status string
+		want   string
+		err    error
+	}
+	testCases := []testCase{
+		{id: "published", status: "deleted", want: "POST /api/records/published/delete"},
+		{id: "published", draft: true, status: "deleted", want: "DELETE /api/records/published/draft"},
+		{id: "draft", status: "failed", want: "GET /api/records/draft/draft", err: commonmeta.ErrValidation},
+		{id: "draft", draft: true, status: "deleted", want: "DELETE /api/records/draft/draft"},
+		{id: "missing", status: "failed", want: "GET /api/records/missing/draft", err: commonmeta.ErrNotFound},
+	}
+	for _, tc := range testCases {
+		requests = nil
+		record, err := inveniordm.Delete(commonmeta.APIResponse{ID: tc.id}, "spam", "", tc.draft, "token", client)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("Delete(%v, %v): want error %v, got %v", tc.id, tc.draft, tc.err, err)
+		}
+		if tc.status != record.Status || len(requests) == 0 || tc.want != requests[len(requests)-1] {
+			t.Errorf("Delete(%v, %v): want %v after %v, got %v after %v", tc.id, tc.draft, tc.status, tc.want, record.Status, requests)
+		}
+	}
+}
+
+func TestUpsertNewVersion(t *testing.T) {
+	t.Parallel()
+	var requests []string
+	var draft inveniordm.Inveniordm
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/records":
+			switch {
+			case strings.Contains(r.URL.Query().Get("q"), "v1"), strings.Contains(r.URL.Query().Get("q"), "existing"):
+				w.Write([]byte(`{"hits":{"hits":[{"id":"v1"}],"total":1}}`))
+			default:
+				w.Write([]byte(`{"hits":{"hits":[],"total":0}}`))
+			}
+		case "GET /api/records/v1/versions/latest":
+			w.Write([]byte(`{"id":"v1"}`))
+		case "POST /api/records/v1/versions":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"v2","parent":{"id":"parent","pids":{"doi":{"identifier":"10.5555/concept","provider":"external"}}},"files":{"enabled":false},"metadata":{"related_identifiers":[{"identifier":"10.5555/concept","scheme":"doi","relation_type":{"id":"isversionof"}}]}}`))
+		case "PUT /api/records/v2/draft":
+			json.NewDecoder(r.Body).Decode(&draft)
+			w.Write([]byte(`{"id":"v2"}`))
+		case "POST /api/records/v2/draft/actions/publish":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"v2"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	client := newTestClient(t, ts)
+
+	data := commonmeta.Data{
+		ID:     "https://doi.org/10.5555/v2",
+		Type:   "Article",
+		Titles: []commonmeta.Title{{Title: "Version 2"}},
+		Date:   commonmeta.Date{Published: "2025-02-01"},
+		Relations: []commonmeta.Relation{
+			{ID: "https://doi.org/10.5555/v1", Type: "IsNewVersionOf"},
+		},
+	}
+	record, err := inveniordm.Upsert(commonmeta.APIResponse{}, data, "token", inveniordm.UpsertOptions{NewVersion: true}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.ID != "v2" || record.Status != "published" {
+		t.Errorf("Upsert(%v): want published v2, got %v %v after %v", data.ID, record.Status, record.ID, requests)
+	}
+	if draft.Parent.ID != "parent" || draft.Parent.Pids == nil || draft.Parent.Pids.DOI.Identifier != "10.5555/concept" {
+		t.Errorf("Upsert(%v): want parent with concept DOI 10.5555/concept, got %v", data.ID, draft.Parent)
+	}
+	if draft.Pids.DOI.Identifier != "10.5555/v2" {
+		t.Errorf("Upsert(%v): want DOI 10.5555/v2, got %v", data.ID, draft.Pids.DOI.Identifier)
+	}
+
+	// a DOI already in InvenioRDM is not created as new version
+	data.ID = "https://doi.org/10.5555/existing"
+	record, err = inveniordm.Upsert(commonmeta.APIResponse{}, data, "token", inveniordm.UpsertOptions{NewVersion: true}, client)
+	if !errors.Is(err, commonmeta.ErrValidation) || record.Status != "failed_version_exists" {
+		t.Errorf("Upsert(%v): want failed_version_exists, got %v %v", data.ID, record.Status, err)
+	}
+}
+
+func TestUpsertPublishFailed(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/records":
+			w.Write([]byte(`{"hits":{"hits":[],"total":0}}`))
+		case "POST /api/records":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"new"}`))
+		case "POST /api/records/new/draft/actions/publish":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"A validation error occurred."}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	client := newTestClient(t, ts)
+
+	data := commonmeta.Data{
+		ID:     "https://doi.org/10.5555/unpublished",
+		Type:   "Article",
+		Titles: []commonmeta.Title{{Title: "Not published"}},
+		Date:   commonmeta.Date{Published: "2025-02-01"},
+	}
+	records, err := inveniordm.UpsertAll([]commonmeta.Data{data}, "token", inveniordm.UpsertOptions{}, client)
+	var statusError *httputils.StatusError
+	if !errors.As(err, &statusError) || statusError.StatusCode != http.StatusBadRequest {
+		t.Errorf("UpsertAll(%v): want status error %v, got %v", data.ID, http.StatusBadRequest, err)
+	}
+	if len(records) != 1 || records[0].Status != "failed" || records[0].Error == "" {
+		t.Errorf("UpsertAll(%v): want failed record, got %v", data.ID, records)
+	}
+}
@@ -0,0 +1,471 @@
+// Package jobs records registration attempts with Crossref, DataCite and
+// InvenioRDM in a local job store, so that failed registrations can be
+// retried.
+package jobs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/doiutils"
+)
+
+// Job statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// SubmittedStatuses are the statuses of registrations that are processed
+// asynchronously, e.g. Crossref deposits, and not yet confirmed.
+var SubmittedStatuses = []string{"submitted", "queued", "in_process"}
+
+// MaxBackoff is the longest wait between two attempts of a job.
+const MaxBackoff = 24 * time.Hour
+
+// Job is a registration of a record with a service.
+type Job struct {
+	ID          string `json:"id"`
+	Target      string `json:"target"`
+	RecordID    string `json:"record_id"`
+	PayloadHash string `json:"payload_hash"`
+	// RegisteredHash is the payload hash of the last successful registration.
+	RegisteredHash string          `json:"registered_hash,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Response       string          `json:"response,omitempty"`
+	// DOIBatchID is the doi_batch_id of a submitted Crossref deposit.
+	DOIBatchID  string    `json:"doi_batch_id,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Attempts    int       `json:"attempts"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+	NextAttempt time.Time `json:"next_attempt,omitzero"`
+	// Options are the options the job was pushed with.
+	Options Options `json:"options,omitzero"`
+}
+
+// Options are the options of a registration that are used again when a
+// job is retried.
+type Options struct {
+	// DepositType is the Crossref deposit type: metadata, resources or
+	// references.
+	DepositType string `json:"deposit_type,omitempty"`
+	// Event is the DataCite DOI state transition.
+	Event string `json:"event,omitempty"`
+	// Files uploads files to InvenioRDM.
+	Files bool `json:"files,omitempty"`
+}
+
+// HashFunc returns the content hash of a record as registered with a
+// service, e.g. of its Crossref XML after conversion.
+type HashFunc func(data commonmeta.Data) (string, error)
+
+// Store is a job store in a JSON file.
+type Store struct {
+	path string
+	mu   sync.Mutex
+	jobs map[string]Job
+	// changed are the ids of the jobs changed since the store was opened
+	// or last saved.
+	changed map[string]bool
+}
+
+// DefaultPath returns the default job store: jobs.json in
+// $XDG_STATE_HOME/commonmeta, or ~/.local/state/commonmeta.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "commonmeta", "jobs.json")
+}
+
+// Open opens the job store at path. A missing file is an empty store.
+func Open(path string) (*Store, error) {
+	list, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: path, jobs: list, changed: make(map[string]bool)}, nil
+}
+
+// read reads the jobs in the job store at path.
+func read(path string) (map[string]Job, error) {
+	jobs := make(map[string]Job)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return jobs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var list []Job
+	err = json.Unmarshal(content, &list)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range list {
+		jobs[job.ID] = job
+	}
+	return jobs, nil
+}
+
+// Save writes the job store to disk, replacing the file atomically. The
+// job store is locked while the jobs saved by other processes since it
+// was opened are read and merged with the jobs changed in this store, so
+// that processes pushing or retrying at the same time keep their jobs.
+func (s *Store) Save() error {
+	err := os.MkdirAll(filepath.Dir(s.path), 0o755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = lock(f)
+	if err != nil {
+		return fmt.Errorf("job store %s: %w", s.path, err)
+	}
+	defer unlock(f)
+
+	saved, err := read(s.path)
+	if err != nil {
+		return fmt.Errorf("job store %s: %w", s.path, err)
+	}
+	s.mu.Lock()
+	for id, job := range saved {
+		if !s.changed[id] {
+			s.jobs[id] = job
+		}
+	}
+	list := s.list(nil)
+	s.mu.Unlock()
+
+	content, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".jobs-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), s.path)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	clear(s.changed)
+	s.mu.Unlock()
+	return nil
+}
+
+// RecordID returns the id of a record: the DOI if it has one, else the id.
+func RecordID(id string) string {
+	if doi, ok := doiutils.ValidateDOI(id); ok {
+		return doiutils.NormalizeDOI(doi)
+	}
+	return id
+}
+
+// JobID returns the id of the job registering a record with a target and
+// options. Crossref resource-only and reference-only deposits of a record
+// are separate jobs from its metadata deposit.
+func JobID(target string, options Options, recordID string) string {
+	if options.DepositType != "" && options.DepositType != "metadata" {
+		target += " " + options.DepositType
+	}
+	return target + " " + RecordID(recordID)
+}
+
+// Hash returns the hex encoded sha256 hash of content.
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Start records pending jobs for a list of records before they are
+// registered with target and options. The payload hash is computed with
+// hash, or is the hash of the commonmeta metadata if hash is nil.
+func (s *Store) Start(target string, options Options, list []commonmeta.Data, hash HashFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	for _, data := range list {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		payloadHash := Hash(payload)
+		if hash != nil {
+			payloadHash, err = hash(data)
+			if err != nil {
+				return err
+			}
+		}
+		id := JobID(target, options, data.ID)
+		job, ok := s.jobs[id]
+		if !ok {
+			job = Job{ID: id, Target: target, RecordID: RecordID(data.ID), Created: now}
+		}
+		job.Payload = payload
+		job.PayloadHash = payloadHash
+		job.Options = options
+		job.Status = StatusPending
+		job.Updated = now
+		s.jobs[id] = job
+		s.changed[id] = true
+	}
+	return nil
+}
+
+// Finish records the results of a registration. Records are matched to
+// their jobs by DOI or id. Submitted registrations that are not yet
+// confirmed stay pending, and failed jobs are scheduled for a retry with
+// exponential backoff. Pending jobs without result are counted as failed.
+func (s *Store) Finish(target string, options Options, list []commonmeta.Data, records []commonmeta.APIResponse, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	results := make(map[string]commonmeta.APIResponse, len(records))
+	for _, record := range records {
+		id := record.DOI
+		if id == "" {
+			id = record.ID
+		}
+		results[JobID(target, options, id)] = record
+	}
+	for _, data := range list {
+		id := JobID(target, options, data.ID)
+		job, ok := s.jobs[id]
+		if !ok {
+			continue
+		}
+		job.Attempts++
+		job.Updated = now
+		record, ok := results[id]
+		job.DOIBatchID = record.DOIBatchID
+		switch {
+		case ok && record.Error == "" && slices.Contains(SubmittedStatuses, record.Status):
+			job.Status = StatusPending
+			job.Response = record.Status
+			job.Error = ""
+			job.NextAttempt = now.Add(Backoff(job.Attempts))
+		case ok && record.Error == "" && !strings.HasPrefix(record.Status, "failed"):
+			job.Status = StatusSucceeded
+			job.RegisteredHash = job.PayloadHash
+			job.Response = record.Status
+			job.Error = ""
+			job.NextAttempt = time.Time{}
+		default:
+			job.Status = StatusFailed
+			job.Response = record.Status
+			job.Error = record.Error
+			if job.Error == "" && record.Message != "" {
+				job.Error = record.Message
+			}
+			if job.Error == "" && err != nil {
+				job.Error = err.Error()
+			}
+			if job.Error == "" {
+				job.Error = "no result"
+			}
+			job.NextAttempt = now.Add(Backoff(job.Attempts))
+		}
+		s.jobs[id] = job
+		s.changed[id] = true
+	}
+}
+
+// Changed splits a list of records into the records whose content hash
+// changed since their last successful registration or unconfirmed deposit
+// with target and options, and the records that are unchanged.
+func (s *Store) Changed(target string, options Options, list []commonmeta.Data, hash HashFunc) ([]commonmeta.Data, []commonmeta.Data, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changed, unchanged []commonmeta.Data
+	for _, data := range list {
+		h, err := hash(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		job, ok := s.jobs[JobID(target, options, data.ID)]
+		if ok && job.RegisteredHash != "" && job.RegisteredHash == h {
+			unchanged = append(unchanged, data)
+		} else if ok && job.Submitted() && job.PayloadHash == h {
+			// the deposit is still waiting to be processed
+			unchanged = append(unchanged, data)
+		} else {
+			changed = append(changed, data)
+		}
+	}
+	return changed, unchanged, nil
+}
+
+// Backoff returns the wait before the next attempt of a job, doubling from
+// one minute with each attempt up to MaxBackoff.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	if attempts > 11 {
+		return MaxBackoff
+	}
+	return min(time.Minute<<(attempts-1), MaxBackoff)
+}
+
+// List returns the jobs with one of the given statuses, or all jobs if no
+// status is given, ordered by id.
+func (s *Store) List(statuses ...string) []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list(statuses)
+}
+
+func (s *Store) list(statuses []string) []Job {
+	var list []Job
+	for _, job := range s.jobs {
+		if len(statuses) == 0 || slices.Contains(statuses, job.Status) {
+			list = append(list, job)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Due returns the failed and pending jobs whose next attempt is due at now,
+// with less than maxAttempts attempts. A maxAttempts of 0 means no limit.
+// Submitted deposits are not due until Resolve confirms that they failed.
+func (s *Store) Due(now time.Time, maxAttempts int) []Job {
+	var due []Job
+	for _, job := range s.List(StatusFailed, StatusPending) {
+		if job.Submitted() {
+			continue
+		}
+		if maxAttempts > 0 && job.Attempts >= maxAttempts {
+			continue
+		}
+		if job.NextAttempt.After(now) {
+			continue
+		}
+		due = append(due, job)
+	}
+	return due
+}
+
+// Data returns the commonmeta metadata registered by a job.
+func (j Job) Data() (commonmeta.Data, error) {
+	var data commonmeta.Data
+	err := json.Unmarshal(j.Payload, &data)
+	return data, err
+}
+
+// Submitted returns true for a pending job whose deposit was submitted, but
+// not yet processed, e.g. a queued Crossref deposit.
+func (j Job) Submitted() bool {
+	return j.Status == StatusPending && j.DOIBatchID != "" && slices.Contains(SubmittedStatuses, j.Response)
+}
+
+// Batches returns the doi_batch_ids of the submitted deposits with target.
+func (s *Store) Batches(target string) []string {
+	var batches []string
+	for _, job := range s.List(StatusPending) {
+		if job.Target == target && job.Submitted() && !slices.Contains(batches, job.DOIBatchID) {
+			batches = append(batches, job.DOIBatchID)
+		}
+	}
+	return batches
+}
+
+// Resolve updates the submitted jobs with target with the results of their
+// deposits, e.g. from the Crossref submission logs. A record without DOI or
+// id is the status of a deposit that is still queued or being processed.
+// Jobs of a processed deposit without result are counted as failed.
+func (s *Store) Resolve(target string, records []commonmeta.APIResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	var processed []string
+	for _, record := range records {
+		id := record.DOI
+		if id == "" {
+			id = record.ID
+		}
+		if id == "" {
+			for jobID, job := range s.jobs {
+				if job.Target == target && job.Submitted() && job.DOIBatchID == record.DOIBatchID {
+					job.Response = record.Status
+					job.Updated = now
+					s.jobs[jobID] = job
+					s.changed[jobID] = true
+				}
+			}
+			continue
+		}
+		if !slices.Contains(processed, record.DOIBatchID) {
+			processed = append(processed, record.DOIBatchID)
+		}
+		// the status doesn't include the deposit type, jobs are matched by
+		// deposit and record
+		for jobID, job := range s.jobs {
+			if job.Target == target && job.Submitted() && job.DOIBatchID == record.DOIBatchID && job.RecordID == RecordID(id) {
+				s.jobs[jobID] = resolve(job, record, now)
+				s.changed[jobID] = true
+			}
+		}
+	}
+	for jobID, job := range s.jobs {
+		if job.Target == target && job.Submitted() && slices.Contains(processed, job.DOIBatchID) {
+			s.jobs[jobID] = resolve(job, commonmeta.APIResponse{Status: "failed", Error: "no result"}, now)
+			s.changed[jobID] = true
+		}
+	}
+}
+
+// resolve updates a submitted job with the result of its deposit.
+func resolve(job Job, record commonmeta.APIResponse, now time.Time) Job {
+	job.Updated = now
+	job.Response = record.Status
+	if record.Error == "" && !strings.HasPrefix(record.Status, "failed") {
+		job.Status = StatusSucceeded
+		job.RegisteredHash = job.PayloadHash
+		job.Error = ""
+		job.NextAttempt = time.Time{}
+		return job
+	}
+	job.Status = StatusFailed
+	job.Error = record.Error
+	if job.Error == "" {
+		job.Error = record.Message
+	}
+	if job.Error == "" {
+		job.Error = "failed"
+	}
+	job.NextAttempt = now.Add(Backoff(job.Attempts))
+	return job
+}
@@ -0,0 +1,323 @@
+package jobs_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/jobs"
+)
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+	type testCase struct {
+		input int
+		want  time.Duration
+	}
+	testCases := []testCase{
+		{input: 0, want: 0},
+		{input: 1, want: time.Minute},
+		{input: 2, want: 2 * time.Minute},
+		{input: 5, want: 16 * time.Minute},
+		{input: 12, want: jobs.MaxBackoff},
+		{input: 100, want: jobs.MaxBackoff},
+	}
+	for _, tc := range testCases {
+		got := jobs.Backoff(tc.input)
+		if tc.want != got {
+			t.Errorf("Backoff(%v): want %v, got %v", tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	store, err := jobs.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := []commonmeta.Data{
+		{ID: "https://doi.org/10.59350/ok", Type: "Article"},
+		{ID: "https://doi.org/10.59350/failed", Type: "Article"},
+		{ID: "https://doi.org/10.59350/missing", Type: "Article"},
+		{ID: "https://doi.org/10.59350/submitted", Type: "Article"},
+	}
+	err = store.Start("crossrefxml", jobs.Options{DepositType: "metadata"}, list, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []commonmeta.APIResponse{
+		{DOI: "https://doi.org/10.59350/ok", Status: "registered"},
+		{DOI: "https://doi.org/10.59350/failed", Status: "failed", Error: "503 Service Unavailable"},
+		{DOI: "https://doi.org/10.59350/submitted", Status: "submitted", DOIBatchID: "batch"},
+	}
+	store.Finish("crossrefxml", jobs.Options{DepositType: "metadata"}, list, records, errors.New("push failed"))
+	err = store.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := jobs.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testCase struct {
+		id     string
+		status string
+		error  string
+	}
+	testCases := []testCase{
+		{id: "crossrefxml https://doi.org/10.59350/failed", status: jobs.StatusFailed, error: "503 Service Unavailable"},
+		{id: "crossrefxml https://doi.org/10.59350/missing", status: jobs.StatusFailed, error: "push failed"},
+		{id: "crossrefxml https://doi.org/10.59350/ok", status: jobs.StatusSucceeded, error: ""},
+		{id: "crossrefxml https://doi.org/10.59350/submitted", status: jobs.StatusPending, error: ""},
+	}
+	got := reopened.List()
+	if len(got) != len(testCases) {
+		t.Fatalf("List(): want %v jobs, got %v", len(testCases), len(got))
+	}
+	for i, tc := range testCases {
+		if tc.id != got[i].ID || tc.status != got[i].Status || tc.error != got[i].Error {
+			t.Errorf("List(%v): want %v %q, got %v %v %q", tc.id, tc.status, tc.error, got[i].ID, got[i].Status, got[i].Error)
+		}
+		if got[i].Attempts != 1 {
+			t.Errorf("List(%v): want 1 attempt, got %v", tc.id, got[i].Attempts)
+		}
+		if got[i].Options.DepositType != "metadata" {
+			t.Errorf("List(%v): want deposit type metadata, got %q", tc.id, got[i].Options.DepositType)
+		}
+		if tc.status == jobs.StatusPending && (got[i].RegisteredHash != "" || got[i].DOIBatchID != "batch") {
+			t.Errorf("List(%v): want unconfirmed deposit in batch, got %v %v", tc.id, got[i].RegisteredHash, got[i].DOIBatchID)
+		}
+	}
+
+	data, err := got[0].Data()
+	if err != nil || data.ID != list[1].ID {
+		t.Errorf("Data(%v): want %v, got %v", got[0].ID, list[1].ID, data.ID)
+	}
+
+	now := time.Now().UTC()
+	if due := reopened.Due(now, 10); len(due) != 0 {
+		t.Errorf("Due(now): want 0 jobs, got %v", len(due))
+	}
+	if due := reopened.Due(now.Add(time.Hour), 10); len(due) != 2 {
+		t.Errorf("Due(now + 1h): want 2 jobs, got %v", len(due))
+	}
+	if due := reopened.Due(now.Add(time.Hour), 1); len(due) != 0 {
+		t.Errorf("Due(now + 1h, 1): want 0 jobs, got %v", len(due))
+	}
+}
+
+func TestChanged(t *testing.T) {
+	t.Parallel()
+	store, err := jobs.Open(filepath.Join(t.TempDir(), "jobs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := func(data commonmeta.Data) (string, error) {
+		return jobs.Hash([]byte(data.ID + " " + data.Type)), nil
+	}
+	list := []commonmeta.Data{
+		{ID: "https://doi.org/10.59350/ok", Type: "Article"},
+		{ID: "https://doi.org/10.59350/failed", Type: "Article"},
+	}
+	err = store.Start("datacite", jobs.Options{}, list, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []commonmeta.APIResponse{
+		{DOI: "https://doi.org/10.59350/ok", Status: "findable"},
+		{DOI: "https://doi.org/10.59350/failed", Status: "failed"},
+	}
+	store.Finish("datacite", jobs.Options{}, list, records, nil)
+
+	type testCase struct {
+		input commonmeta.Data
+		want  bool
+	}
+	testCases := []testCase{
+		{input: commonmeta.Data{ID: "https://doi.org/10.59350/ok", Type: "Article"}, want: false},
+		{input: commonmeta.Data{ID: "https://doi.org/10.59350/ok", Type: "Preprint"}, want: true},
+		{input: commonmeta.Data{ID: "https://doi.org/10.59350/failed", Type: "Article"}, want: true},
+		{input: commonmeta.Data{ID: "https://doi.org/10.59350/new", Type: "Article"}, want: true},
+	}
+	for _, tc := range testCases {
+		changed, _, err := store.Changed("datacite", jobs.Options{}, []commonmeta.Data{tc.input}, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := len(changed) == 1
+		if tc.want != got {
+			t.Errorf("Changed(%v %v): want %v, got %v", tc.input.ID, tc.input.Type, tc.want, got)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+	store, err := jobs.Open(filepath.Join(t.TempDir(), "jobs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := func(data commonmeta.Data) (string, error) {
+		return jobs.Hash([]byte(data.ID + " " + data.Type)), nil
+	}
+	list := []commonmeta.Data{
+		{ID: "https://doi.org/10.59350/ok", Type: "Article"},
+		{ID: "https://doi.org/10.59350/failed", Type: "Article"},
+		{ID: "https://doi.org/10.59350/missing", Type: "Article"},
+		{ID: "https://doi.org/10.59350/queued", Type: "Article"},
+	}
+	err = store.Start("crossrefxml", jobs.Options{}, list, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []commonmeta.APIResponse{
+		{DOI: "https://doi.org/10.59350/ok", Status: "submitted", DOIBatchID: "batch-1"},
+		{DOI: "https://doi.org/10.59350/failed", Status: "submitted", DOIBatchID: "batch-1"},
+		{DOI: "https://doi.org/10.59350/missing", Status: "submitted", DOIBatchID: "batch-1"},
+		{DOI: "https://doi.org/10.59350/queued", Status: "submitted", DOIBatchID: "batch-2"},
+	}
+	store.Finish("crossrefxml", jobs.Options{}, list, records, nil)
+
+	changed, _, err := store.Changed("crossrefxml", jobs.Options{}, list, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("Changed(submitted): want 0 records, got %v", len(changed))
+	}
+	if due := store.Due(time.Now().Add(time.Hour), 10); len(due) != 0 {
+		t.Errorf("Due(submitted): want 0 jobs, got %v", len(due))
+	}
+	if got := store.Batches("crossrefxml"); len(got) != 2 {
+		t.Errorf("Batches(): want 2 batches, got %v", got)
+	}
+
+	store.Resolve("crossrefxml", []commonmeta.APIResponse{
+		{DOI: "https://doi.org/10.59350/ok", Status: "registered", DOIBatchID: "batch-1"},
+		{DOI: "https://doi.org/10.59350/failed", Status: "failed", Message: "title is missing", DOIBatchID: "batch-1"},
+		{DOIBatchID: "batch-2", Status: "in_process"},
+	})
+
+	type testCase struct {
+		id       string
+		status   string
+		response string
+		error    string
+	}
+	testCases := []testCase{
+		{id: "crossrefxml https://doi.org/10.59350/failed", status: jobs.StatusFailed, response: "failed", error: "title is missing"},
+		{id: "crossrefxml https://doi.org/10.59350/missing", status: jobs.StatusFailed, response: "failed", error: "no result"},
+		{id: "crossrefxml https://doi.org/10.59350/ok", status: jobs.StatusSucceeded, response: "registered", error: ""},
+		{id: "crossrefxml https://doi.org/10.59350/queued", status: jobs.StatusPending, response: "in_process", error: ""},
+	}
+	got := store.List()
+	if len(got) != len(testCases) {
+		t.Fatalf("List(): want %v jobs, got %v", len(testCases), len(got))
+	}
+	for i, tc := range testCases {
+		if tc.id != got[i].ID || tc.status != got[i].Status || tc.response != got[i].Response || tc.error != got[i].Error {
+			t.Errorf("Resolve(%v): want %v %v %q, got %v %v %v %q", tc.id, tc.status, tc.response, tc.error, got[i].ID, got[i].Status, got[i].Response, got[i].Error)
+		}
+	}
+	if got[2].RegisteredHash != got[2].PayloadHash {
+		t.Errorf("Resolve(%v): want registered hash %v, got %v", got[2].ID, got[2].PayloadHash, got[2].RegisteredHash)
+	}
+	if due := store.Due(time.Now().Add(time.Hour), 10); len(due) != 2 {
+		t.Errorf("Due(resolved): want 2 jobs, got %v", len(due))
+	}
+	if got := store.Batches("crossrefxml"); len(got) != 1 || got[0] != "batch-2" {
+		t.Errorf("Batches(): want [batch-2], got %v", got)
+	}
+}
+
+func TestDepositType(t *testing.T) {
+	t.Parallel()
+	store, err := jobs.Open(filepath.Join(t.TempDir(), "jobs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := jobs.Options{DepositType: "metadata"}
+	resources := jobs.Options{DepositType: "resources"}
+	hash := func(depositType string) jobs.HashFunc {
+		return func(data commonmeta.Data) (string, error) {
+			return jobs.Hash([]byte(depositType + " " + data.ID)), nil
+		}
+	}
+	list := []commonmeta.Data{{ID: "https://doi.org/10.59350/ok", Type: "Article"}}
+	records := []commonmeta.APIResponse{{DOI: "https://doi.org/10.59350/ok", Status: "registered"}}
+	for _, options := range []jobs.Options{metadata, resources} {
+		err = store.Start("crossrefxml", options, list, hash(options.DepositType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Finish("crossrefxml", options, list, records, nil)
+	}
+
+	want := []string{
+		"crossrefxml https://doi.org/10.59350/ok",
+		"crossrefxml resources https://doi.org/10.59350/ok",
+	}
+	got := store.List()
+	if len(got) != len(want) {
+		t.Fatalf("List(): want %v jobs, got %v", len(want), len(got))
+	}
+	for i := range want {
+		if want[i] != got[i].ID {
+			t.Errorf("List(): want %v, got %v", want[i], got[i].ID)
+		}
+	}
+	for _, options := range []jobs.Options{metadata, resources} {
+		changed, _, err := store.Changed("crossrefxml", options, list, hash(options.DepositType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changed) != 0 {
+			t.Errorf("Changed(%v): want 0 records, got %v", options.DepositType, len(changed))
+		}
+	}
+}
+
+func TestSaveConcurrent(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	stores := make([]*jobs.Store, 4)
+	for i := range stores {
+		store, err := jobs.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stores[i] = store
+	}
+
+	var wg sync.WaitGroup
+	for i, store := range stores {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			list := []commonmeta.Data{{ID: fmt.Sprintf("https://doi.org/10.59350/%d", i), Type: "Article"}}
+			err := store.Start("crossrefxml", jobs.Options{}, list, nil)
+			if err == nil {
+				err = store.Save()
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	reopened, err := jobs.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.List(); len(got) != len(stores) {
+		t.Errorf("Save(concurrent): want %v jobs, got %v", len(stores), len(got))
+	}
+}
@@ -14,12 +14,13 @@ import (
 	"slices"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/front-matter/commonmeta/authorutils"
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/dateutils"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/utils"
 )
 
@@ -127,11 +128,11 @@ func Fetch(str string) (commonmeta.Data, error) {
 		id = str
 	} else if identifierType == "DOI" {
 		doi, _ := doiutils.ValidateDOI(str)
-		id = "https://api.rogue-scholar.org/posts/" + doi
+		id = config.GetEndpoints().RogueScholarAPI + "/posts/" + doi
 	} else if identifierType == "UUID" {
-		id = "https://api.rogue-scholar.org/posts/" + str
+		id = config.GetEndpoints().RogueScholarAPI + "/posts/" + str
 	} else {
-		return data, errors.New("invalid ID")
+		return data, fmt.Errorf("%w: invalid ID", commonmeta.ErrValidation)
 	}
 	content, err := Get(id)
 	if err != nil {
@@ -158,17 +159,16 @@ func FetchAll(number int, page int, community string, archived bool) ([]commonme
 // Get retrieves JSON Feed metadata.
 func Get(id string) (Content, error) {
 	var content Content
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
+	client := httputils.Client()
 	resp, err := client.Get(id)
 	if err != nil {
 		return content, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return content, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
-	}
 	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return content, err
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return content, err
@@ -185,9 +185,7 @@ func GetAll(number int, page int, community string, archived bool) ([]Content, e
 	var response Query
 	var content []Content
 
-	client := &http.Client{
-		Timeout: time.Second * 30,
-	}
+	client := httputils.Client()
 	url := QueryURL(number, page, community, archived)
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
@@ -198,8 +196,9 @@ func GetAll(number int, page int, community string, archived bool) ([]Content, e
 		return content, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return content, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return content, err
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -215,7 +214,7 @@ func GetAll(number int, page int, community string, archived bool) ([]Content, e
 
 // QueryURL returns the URL for the Rogue Scholar API query
 func QueryURL(number int, page int, community string, archived bool) string {
-	requestURL := "https://api.rogue-scholar.org/posts?"
+	requestURL := config.GetEndpoints().RogueScholarAPI + "/posts?"
 	values := url.Values{}
 	if !archived {
 		values.Set("flag", "needs_update")
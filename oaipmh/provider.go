@@ -0,0 +1,514 @@
+package oaipmh
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/dateutils"
+	"github.com/front-matter/commonmeta/doiutils"
+)
+
+// Granularity is the datestamp granularity of the data provider.
+const Granularity = "YYYY-MM-DDThh:mm:ssZ"
+
+// DefaultPageSize is the number of records or headers per incomplete list.
+const DefaultPageSize = 100
+
+// datestampFormat is the Go layout for Granularity.
+const datestampFormat = "2006-01-02T15:04:05Z"
+
+// MetadataFormat represents a metadata format disseminated by the data provider.
+type MetadataFormat struct {
+	MetadataPrefix    string `xml:"metadataPrefix"`
+	Schema            string `xml:"schema"`
+	MetadataNamespace string `xml:"metadataNamespace"`
+}
+
+// ProviderFormats are the metadata formats disseminated by the data provider.
+var ProviderFormats = []MetadataFormat{
+	{
+		MetadataPrefix:    "oai_dc",
+		Schema:            "http://www.openarchives.org/OAI/2.0/oai_dc.xsd",
+		MetadataNamespace: "http://www.openarchives.org/OAI/2.0/oai_dc/",
+	},
+	{
+		MetadataPrefix:    "oai_datacite",
+		Schema:            "http://schema.datacite.org/oai/oai-1.1/oai.xsd",
+		MetadataNamespace: "http://schema.datacite.org/oai/oai-1.1/",
+	},
+}
+
+// Set represents an OAI-PMH set.
+type Set struct {
+	Spec string `xml:"setSpec"`
+	Name string `xml:"setName"`
+}
+
+// ProviderOptions configures the data provider. BaseURL defaults to the
+// URL of the request, PageSize to DefaultPageSize.
+type ProviderOptions struct {
+	RepositoryName string
+	BaseURL        string
+	AdminEmail     string
+	PageSize       int
+}
+
+// Provider is an OAI-PMH data provider serving a collection of
+// commonmeta records. It implements the six OAI-PMH verbs.
+type Provider struct {
+	options ProviderOptions
+	records []commonmeta.Data
+	headers []Header
+	index   map[string]int
+	started time.Time
+}
+
+// NewProvider returns a data provider for the records.
+func NewProvider(records []commonmeta.Data, options ProviderOptions) *Provider {
+	if options.PageSize <= 0 {
+		options.PageSize = DefaultPageSize
+	}
+	if options.RepositoryName == "" {
+		options.RepositoryName = "commonmeta"
+	}
+	p := &Provider{
+		options: options,
+		records: records,
+		index:   make(map[string]int, len(records)),
+		started: time.Now().UTC().Truncate(time.Second),
+	}
+	for i, data := range records {
+		header := Header{
+			Identifier: data.ID,
+			Datestamp:  p.datestamp(data),
+		}
+		for _, set := range SetSpecs(data) {
+			if !slices.Contains(header.SetSpec, set.Spec) {
+				header.SetSpec = append(header.SetSpec, set.Spec)
+			}
+		}
+		p.headers = append(p.headers, header)
+		p.index[data.ID] = i
+	}
+	return p
+}
+
+// envelope represents an OAI-PMH response written by the data provider.
+type envelope struct {
+	XMLName             xml.Name             `xml:"OAI-PMH"`
+	Xmlns               string               `xml:"xmlns,attr"`
+	XmlnsXsi            string               `xml:"xmlns:xsi,attr"`
+	SchemaLocation      string               `xml:"xsi:schemaLocation,attr"`
+	ResponseDate        string               `xml:"responseDate"`
+	Request             requestInfo          `xml:"request"`
+	Errors              []Error              `xml:"error,omitempty"`
+	Identify            *identify            `xml:"Identify,omitempty"`
+	ListMetadataFormats *listMetadataFormats `xml:"ListMetadataFormats,omitempty"`
+	ListSets            *listSets            `xml:"ListSets,omitempty"`
+	ListIdentifiers     *listIdentifiers     `xml:"ListIdentifiers,omitempty"`
+	ListRecords         *listRecords         `xml:"ListRecords,omitempty"`
+	GetRecord           *listRecords         `xml:"GetRecord,omitempty"`
+}
+
+type requestInfo struct {
+	BaseURL         string `xml:",chardata"`
+	Verb            string `xml:"verb,attr,omitempty"`
+	Identifier      string `xml:"identifier,attr,omitempty"`
+	MetadataPrefix  string `xml:"metadataPrefix,attr,omitempty"`
+	From            string `xml:"from,attr,omitempty"`
+	Until           string `xml:"until,attr,omitempty"`
+	Set             string `xml:"set,attr,omitempty"`
+	ResumptionToken string `xml:"resumptionToken,attr,omitempty"`
+}
+
+type identify struct {
+	RepositoryName    string   `xml:"repositoryName"`
+	BaseURL           string   `xml:"baseURL"`
+	ProtocolVersion   string   `xml:"protocolVersion"`
+	AdminEmail        []string `xml:"adminEmail,omitempty"`
+	EarliestDatestamp string   `xml:"earliestDatestamp"`
+	DeletedRecord     string   `xml:"deletedRecord"`
+	Granularity       string   `xml:"granularity"`
+}
+
+type listMetadataFormats struct {
+	MetadataFormats []MetadataFormat `xml:"metadataFormat"`
+}
+
+type listSets struct {
+	Sets []Set `xml:"set"`
+}
+
+type listIdentifiers struct {
+	Headers         []Header         `xml:"header"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
+
+type listRecords struct {
+	Records         []record         `xml:"record"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
+
+type record struct {
+	Header   Header   `xml:"header"`
+	Metadata metadata `xml:"metadata"`
+}
+
+// metadata wraps an OAIDC or OAIDataCite record, which provide their own
+// element names.
+type metadata struct {
+	Content any
+}
+
+// arguments lists the legal arguments per verb, required arguments first.
+var arguments = map[string][]string{
+	"Identify":            {},
+	"ListMetadataFormats": {"identifier"},
+	"ListSets":            {"resumptionToken"},
+	"ListIdentifiers":     {"metadataPrefix", "from", "until", "set", "resumptionToken"},
+	"ListRecords":         {"metadataPrefix", "from", "until", "set", "resumptionToken"},
+	"GetRecord":           {"identifier", "metadataPrefix"},
+}
+
+// ServeHTTP answers OAI-PMH requests sent with GET or POST.
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	baseURL := p.options.BaseURL
+	if baseURL == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		baseURL = scheme + "://" + r.Host + r.URL.Path
+	}
+	response := envelope{
+		Xmlns:          "http://www.openarchives.org/OAI/2.0/",
+		XmlnsXsi:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: "http://www.openarchives.org/OAI/2.0/ http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd",
+		ResponseDate:   time.Now().UTC().Format(datestampFormat),
+		Request:        requestInfo{BaseURL: baseURL},
+	}
+
+	if err := r.ParseForm(); err != nil {
+		response.Errors = []Error{{Code: "badArgument", Message: err.Error()}}
+		writeResponse(w, response)
+		return
+	}
+	verb := r.Form.Get("verb")
+	legal, ok := arguments[verb]
+	if !ok || len(r.Form["verb"]) > 1 {
+		response.Errors = []Error{{Code: "badVerb", Message: "Illegal OAI verb"}}
+		writeResponse(w, response)
+		return
+	}
+	for k, v := range r.Form {
+		if k != "verb" && !slices.Contains(legal, k) {
+			response.Errors = append(response.Errors, Error{Code: "badArgument", Message: "Illegal argument " + k})
+		} else if len(v) > 1 {
+			response.Errors = append(response.Errors, Error{Code: "badArgument", Message: "Repeated argument " + k})
+		}
+	}
+	if len(response.Errors) > 0 {
+		writeResponse(w, response)
+		return
+	}
+	response.Request = requestInfo{
+		BaseURL:         baseURL,
+		Verb:            verb,
+		Identifier:      r.Form.Get("identifier"),
+		MetadataPrefix:  r.Form.Get("metadataPrefix"),
+		From:            r.Form.Get("from"),
+		Until:           r.Form.Get("until"),
+		Set:             r.Form.Get("set"),
+		ResumptionToken: r.Form.Get("resumptionToken"),
+	}
+
+	var err *Error
+	switch verb {
+	case "Identify":
+		response.Identify = p.identify(baseURL)
+	case "ListMetadataFormats":
+		response.ListMetadataFormats, err = p.listMetadataFormats(r.Form.Get("identifier"))
+	case "ListSets":
+		response.ListSets, err = p.listSets(r.Form)
+	case "ListIdentifiers":
+		var page []int
+		var token *ResumptionToken
+		page, _, token, err = p.list(r.Form)
+		if err == nil {
+			response.ListIdentifiers = &listIdentifiers{ResumptionToken: token}
+			for _, i := range page {
+				response.ListIdentifiers.Headers = append(response.ListIdentifiers.Headers, p.headers[i])
+			}
+		}
+	case "ListRecords":
+		var page []int
+		var prefix string
+		var token *ResumptionToken
+		page, prefix, token, err = p.list(r.Form)
+		if err == nil {
+			response.ListRecords = &listRecords{ResumptionToken: token}
+			for _, i := range page {
+				rec, e := p.record(i, prefix)
+				if e != nil {
+					err = e
+					break
+				}
+				response.ListRecords.Records = append(response.ListRecords.Records, rec)
+			}
+		}
+	case "GetRecord":
+		response.GetRecord, err = p.getRecord(r.Form.Get("identifier"), r.Form.Get("metadataPrefix"))
+	}
+	if err != nil {
+		response.Errors = []Error{*err}
+		response.Identify = nil
+		response.ListMetadataFormats = nil
+		response.ListSets = nil
+		response.ListIdentifiers = nil
+		response.ListRecords = nil
+		response.GetRecord = nil
+	}
+	writeResponse(w, response)
+}
+
+// writeResponse writes an OAI-PMH response. Protocol errors are reported
+// in the response body with status 200, as required by OAI-PMH.
+func writeResponse(w http.ResponseWriter, response envelope) {
+	body, err := xml.MarshalIndent(response, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
+
+func (p *Provider) identify(baseURL string) *identify {
+	earliest := p.started.Format(datestampFormat)
+	for _, h := range p.headers {
+		if h.Datestamp < earliest {
+			earliest = h.Datestamp
+		}
+	}
+	result := &identify{
+		RepositoryName:    p.options.RepositoryName,
+		BaseURL:           baseURL,
+		ProtocolVersion:   "2.0",
+		EarliestDatestamp: earliest,
+		DeletedRecord:     "no",
+		Granularity:       Granularity,
+	}
+	if p.options.AdminEmail != "" {
+		result.AdminEmail = []string{p.options.AdminEmail}
+	}
+	return result
+}
+
+func (p *Provider) listMetadataFormats(identifier string) (*listMetadataFormats, *Error) {
+	if identifier != "" {
+		if _, ok := p.lookup(identifier); !ok {
+			return nil, &Error{Code: "idDoesNotExist", Message: "No matching identifier " + identifier}
+		}
+	}
+	return &listMetadataFormats{MetadataFormats: ProviderFormats}, nil
+}
+
+func (p *Provider) listSets(form url.Values) (*listSets, *Error) {
+	if form.Get("resumptionToken") != "" {
+		return nil, &Error{Code: "badResumptionToken", Message: "Invalid resumption token"}
+	}
+	names := map[string]string{}
+	for _, data := range p.records {
+		for _, set := range SetSpecs(data) {
+			names[set.Spec] = set.Name
+		}
+	}
+	if len(names) == 0 {
+		return nil, &Error{Code: "noSetHierarchy", Message: "This repository does not support sets"}
+	}
+	result := &listSets{}
+	for spec, name := range names {
+		result.Sets = append(result.Sets, Set{Spec: spec, Name: name})
+	}
+	sort.Slice(result.Sets, func(i, j int) bool { return result.Sets[i].Spec < result.Sets[j].Spec })
+	return result, nil
+}
+
+func (p *Provider) getRecord(identifier string, prefix string) (*listRecords, *Error) {
+	if identifier == "" || prefix == "" {
+		return nil, &Error{Code: "badArgument", Message: "Missing identifier or metadataPrefix"}
+	}
+	i, ok := p.lookup(identifier)
+	if !ok {
+		return nil, &Error{Code: "idDoesNotExist", Message: "No matching identifier " + identifier}
+	}
+	rec, err := p.record(i, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &listRecords{Records: []record{rec}}, nil
+}
+
+// listArguments returns the selective harvesting arguments of a
+// ListIdentifiers or ListRecords request, decoding the resumption token if
+// present, and the cursor into the complete list.
+func (p *Provider) listArguments(form url.Values) (url.Values, int) {
+	token := form.Get("resumptionToken")
+	if token == "" {
+		return form, 0
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, 0
+	}
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return nil, 0
+	}
+	cursor, err := strconv.Atoi(values.Get("cursor"))
+	if err != nil || cursor < 0 {
+		return nil, 0
+	}
+	return values, cursor
+}
+
+// list selects the records of a ListIdentifiers or ListRecords request and
+// returns the indexes of the current page, the metadata prefix and the
+// resumption token.
+func (p *Provider) list(form url.Values) ([]int, string, *ResumptionToken, *Error) {
+	resumed := form.Get("resumptionToken") != ""
+	if resumed && len(form) > 2 {
+		return nil, "", nil, &Error{Code: "badArgument", Message: "resumptionToken is an exclusive argument"}
+	}
+	values, cursor := p.listArguments(form)
+	if values == nil {
+		return nil, "", nil, &Error{Code: "badResumptionToken", Message: "Invalid resumption token"}
+	}
+	prefix := values.Get("metadataPrefix")
+	if prefix == "" {
+		return nil, "", nil, &Error{Code: "badArgument", Message: "Missing metadataPrefix"}
+	}
+	if !slices.ContainsFunc(ProviderFormats, func(f MetadataFormat) bool { return f.MetadataPrefix == prefix }) {
+		return nil, "", nil, &Error{Code: "cannotDisseminateFormat", Message: "Unsupported metadataPrefix " + prefix}
+	}
+	from, err := parseDatestamp(values.Get("from"), false)
+	if err != nil {
+		return nil, "", nil, &Error{Code: "badArgument", Message: "Invalid from date"}
+	}
+	until, err := parseDatestamp(values.Get("until"), true)
+	if err != nil {
+		return nil, "", nil, &Error{Code: "badArgument", Message: "Invalid until date"}
+	}
+	if values.Get("from") != "" && values.Get("until") != "" && len(values.Get("from")) != len(values.Get("until")) {
+		return nil, "", nil, &Error{Code: "badArgument", Message: "from and until must have the same granularity"}
+	}
+	set := values.Get("set")
+
+	var selected []int
+	for i, h := range p.headers {
+		if from != "" && h.Datestamp < from {
+			continue
+		}
+		if until != "" && h.Datestamp > until {
+			continue
+		}
+		if set != "" && !slices.Contains(h.SetSpec, set) {
+			continue
+		}
+		selected = append(selected, i)
+	}
+	if len(selected) == 0 {
+		return nil, "", nil, &Error{Code: "noRecordsMatch", Message: "No records match the request"}
+	}
+	if cursor >= len(selected) {
+		return nil, "", nil, &Error{Code: "badResumptionToken", Message: "Invalid resumption token"}
+	}
+
+	end := min(cursor+p.options.PageSize, len(selected))
+	var token *ResumptionToken
+	if end < len(selected) || resumed {
+		token = &ResumptionToken{CompleteListSize: len(selected), Cursor: cursor}
+		if end < len(selected) {
+			next := url.Values{}
+			for _, k := range []string{"metadataPrefix", "from", "until", "set"} {
+				if v := values.Get(k); v != "" {
+					next.Set(k, v)
+				}
+			}
+			next.Set("cursor", strconv.Itoa(end))
+			token.Token = base64.RawURLEncoding.EncodeToString([]byte(next.Encode()))
+		}
+	}
+	return selected[cursor:end], prefix, token, nil
+}
+
+// record returns the record at index i in the metadata format prefix.
+func (p *Provider) record(i int, prefix string) (record, *Error) {
+	rec := record{Header: p.headers[i]}
+	switch prefix {
+	case "oai_dc":
+		rec.Metadata.Content = ConvertDublinCore(p.records[i])
+	case "oai_datacite":
+		content, err := ConvertDataCite(p.records[i])
+		if err != nil {
+			return rec, &Error{Code: "cannotDisseminateFormat", Message: err.Error()}
+		}
+		rec.Metadata.Content = content
+	default:
+		return rec, &Error{Code: "cannotDisseminateFormat", Message: "Unsupported metadataPrefix " + prefix}
+	}
+	return rec, nil
+}
+
+// lookup finds a record by its identifier, also accepting a DOI in any
+// supported notation.
+func (p *Provider) lookup(identifier string) (int, bool) {
+	if i, ok := p.index[identifier]; ok {
+		return i, true
+	}
+	if doi := doiutils.NormalizeDOI(identifier); doi != "" {
+		i, ok := p.index[doi]
+		return i, ok
+	}
+	return 0, false
+}
+
+// datestamp returns the datestamp of a record, taken from the updated date,
+// falling back to the publication date and the start of the provider.
+func (p *Provider) datestamp(data commonmeta.Data) string {
+	for _, date := range []string{data.Date.Updated, data.Date.Published} {
+		t, err := dateutils.ParseTime(date)
+		if err == nil {
+			return t.UTC().Format(datestampFormat)
+		}
+	}
+	return p.started.Format(datestampFormat)
+}
+
+// parseDatestamp parses a from or until argument, either a date or a
+// datetime in UTC. A date used as until includes the whole day.
+func parseDatestamp(s string, until bool) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if t, err := time.Parse(datestampFormat, s); err == nil {
+		return t.Format(datestampFormat), nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid datestamp %s", commonmeta.ErrValidation, s)
+	}
+	if until {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t.Format(datestampFormat), nil
+}
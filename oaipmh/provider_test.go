@@ -0,0 +1,173 @@
+package oaipmh_test
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/oaipmh"
+)
+
+// newProvider returns a test server for a data provider serving the
+// testdata collection, two records per page.
+func newProvider(t *testing.T) *httptest.Server {
+	t.Helper()
+	records, err := commonmeta.LoadAll(filepath.Join("testdata", "collection.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := oaipmh.NewProvider(records, oaipmh.ProviderOptions{
+		RepositoryName: "Example Collection",
+		AdminEmail:     "info@example.org",
+		PageSize:       2,
+	})
+	return httptest.NewServer(provider)
+}
+
+func get(t *testing.T, url string) oaipmh.Response {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response oaipmh.Response
+	err = xml.Unmarshal(body, &response)
+	if err != nil {
+		t.Fatalf("%v: %s", err, body)
+	}
+	return response
+}
+
+func TestProviderHarvest(t *testing.T) {
+	t.Parallel()
+	ts := newProvider(t)
+	defer ts.Close()
+
+	type testCase struct {
+		prefix string
+		set    string
+		from   string
+		until  string
+		want   []string
+	}
+	testCases := []testCase{
+		{prefix: "oai_dc", want: []string{"https://doi.org/10.5555/oai.1", "https://doi.org/10.5555/oai.2", "https://doi.org/10.5555/oai.3"}},
+		{prefix: "oai_datacite", want: []string{"https://doi.org/10.5555/oai.1", "https://doi.org/10.5555/oai.2", "https://doi.org/10.5555/oai.3"}},
+		{prefix: "oai_dc", set: "container:journalofexamples", want: []string{"https://doi.org/10.5555/oai.1", "https://doi.org/10.5555/oai.3"}},
+		{prefix: "oai_dc", set: "subject:biology", want: []string{"https://doi.org/10.5555/oai.2"}},
+		{prefix: "oai_dc", from: "2025-02-01", want: []string{"https://doi.org/10.5555/oai.2", "https://doi.org/10.5555/oai.3"}},
+		{prefix: "oai_dc", until: "2025-02-10", want: []string{"https://doi.org/10.5555/oai.1", "https://doi.org/10.5555/oai.2"}},
+		{prefix: "oai_dc", set: "subject:chemistry", want: nil},
+	}
+	for _, tc := range testCases {
+		data, err := oaipmh.FetchAll(ts.URL, tc.prefix, tc.set, tc.from, tc.until, 0)
+		if err != nil {
+			t.Fatalf("FetchAll(%v, %v): %v", tc.prefix, tc.set, err)
+		}
+		var got []string
+		for _, d := range data {
+			got = append(got, d.ID)
+		}
+		if strings.Join(tc.want, " ") != strings.Join(got, " ") {
+			t.Errorf("FetchAll(%v, %v, %v, %v): want %v, got %v", tc.prefix, tc.set, tc.from, tc.until, tc.want, got)
+		}
+	}
+}
+
+func TestProviderRecord(t *testing.T) {
+	t.Parallel()
+	ts := newProvider(t)
+	defer ts.Close()
+
+	for _, prefix := range []string{"oai_dc", "oai_datacite"} {
+		data, err := oaipmh.Fetch(ts.URL, "10.5555/oai.1", prefix)
+		if err != nil {
+			t.Fatalf("Fetch(%v): %v", prefix, err)
+		}
+		if data.ID != "https://doi.org/10.5555/oai.1" {
+			t.Errorf("Fetch(%v): want %v, got %v", prefix, "https://doi.org/10.5555/oai.1", data.ID)
+		}
+		if data.Type != "JournalArticle" && prefix == "oai_datacite" {
+			t.Errorf("Fetch(%v): want %v, got %v", prefix, "JournalArticle", data.Type)
+		}
+		if len(data.Titles) == 0 || data.Titles[0].Title != "First article" {
+			t.Errorf("Fetch(%v): want %v, got %v", prefix, "First article", data.Titles)
+		}
+		if len(data.Contributors) == 0 || data.Contributors[0].FamilyName != "Doe" {
+			t.Errorf("Fetch(%v): want %v, got %v", prefix, "Doe", data.Contributors)
+		}
+		if data.Date.Updated != "2025-01-10T08:00:00Z" {
+			t.Errorf("Fetch(%v): want %v, got %v", prefix, "2025-01-10T08:00:00Z", data.Date.Updated)
+		}
+	}
+}
+
+func TestProviderVerbs(t *testing.T) {
+	t.Parallel()
+	ts := newProvider(t)
+	defer ts.Close()
+
+	type testCase struct {
+		query string
+		want  string
+	}
+	testCases := []testCase{
+		{query: "verb=Identify", want: ""},
+		{query: "verb=ListMetadataFormats", want: ""},
+		{query: "verb=ListMetadataFormats&identifier=https://doi.org/10.5555/oai.9", want: "idDoesNotExist"},
+		{query: "verb=ListSets", want: ""},
+		{query: "verb=ListIdentifiers&metadataPrefix=oai_dc", want: ""},
+		{query: "verb=ListIdentifiers", want: "badArgument"},
+		{query: "verb=ListRecords&metadataPrefix=marcxml", want: "cannotDisseminateFormat"},
+		{query: "verb=ListRecords&metadataPrefix=oai_dc&from=yesterday", want: "badArgument"},
+		{query: "verb=ListRecords&resumptionToken=invalid", want: "badResumptionToken"},
+		{query: "verb=ListRecords&metadataPrefix=oai_dc&set=subject:chemistry", want: "noRecordsMatch"},
+		{query: "verb=GetRecord&identifier=https://doi.org/10.5555/oai.1", want: "badArgument"},
+		{query: "verb=GetRecord&identifier=https://doi.org/10.5555/oai.1&metadataPrefix=oai_dc&set=xyz", want: "badArgument"},
+		{query: "verb=Harvest", want: "badVerb"},
+	}
+	for _, tc := range testCases {
+		response := get(t, ts.URL+"?"+tc.query)
+		var got string
+		if response.Error != nil {
+			got = response.Error.Code
+		}
+		if tc.want != got {
+			t.Errorf("%v: want %v, got %v", tc.query, tc.want, got)
+		}
+	}
+}
+
+func TestProviderResumptionToken(t *testing.T) {
+	t.Parallel()
+	ts := newProvider(t)
+	defer ts.Close()
+
+	response := get(t, ts.URL+"?verb=ListRecords&metadataPrefix=oai_dc")
+	token := response.ListRecords.ResumptionToken
+	if len(response.ListRecords.Records) != 2 || token.Token == "" || token.CompleteListSize != 3 {
+		t.Fatalf("ListRecords: want 2 records and a resumption token, got %v, %v", len(response.ListRecords.Records), token)
+	}
+
+	// the resumption token is an exclusive argument
+	response = get(t, ts.URL+"?verb=ListRecords&metadataPrefix=oai_dc&resumptionToken="+token.Token)
+	if response.Error == nil || response.Error.Code != "badArgument" {
+		t.Errorf("ListRecords: want %v, got %v", "badArgument", response.Error)
+	}
+
+	response = get(t, ts.URL+"?verb=ListRecords&resumptionToken="+token.Token)
+	next := response.ListRecords.ResumptionToken
+	if len(response.ListRecords.Records) != 1 || next.Token != "" || next.Cursor != 2 {
+		t.Errorf("ListRecords: want the last record and an empty resumption token, got %v, %v", len(response.ListRecords.Records), next)
+	}
+}
@@ -0,0 +1,699 @@
+// Package oaipmh reads metadata from OAI-PMH repositories and converts it to the commonmeta metadata format.
+package oaipmh
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/front-matter/commonmeta/authorutils"
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/datacite"
+	"github.com/front-matter/commonmeta/dateutils"
+	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
+	"github.com/front-matter/commonmeta/utils"
+)
+
+// MetadataPrefixes are the OAI-PMH metadata formats supported by the reader.
+var MetadataPrefixes = []string{"oai_dc", "oai_datacite", "datacite"}
+
+// DefaultMetadataPrefix is the metadata format every OAI-PMH repository must support.
+const DefaultMetadataPrefix = "oai_dc"
+
+// Response represents an OAI-PMH response.
+type Response struct {
+	XMLName      xml.Name    `xml:"OAI-PMH"`
+	ResponseDate string      `xml:"responseDate"`
+	Error        *Error      `xml:"error"`
+	GetRecord    GetRecord   `xml:"GetRecord"`
+	ListRecords  ListRecords `xml:"ListRecords"`
+}
+
+// Error represents an OAI-PMH protocol error.
+type Error struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+// GetRecord represents the payload of a GetRecord response.
+type GetRecord struct {
+	Record Record `xml:"record"`
+}
+
+// ListRecords represents the payload of a ListRecords response.
+type ListRecords struct {
+	Records         []Record        `xml:"record"`
+	ResumptionToken ResumptionToken `xml:"resumptionToken"`
+}
+
+// ResumptionToken represents the flow control token of an incomplete list.
+type ResumptionToken struct {
+	Token            string `xml:",chardata"`
+	CompleteListSize int    `xml:"completeListSize,attr"`
+	Cursor           int    `xml:"cursor,attr"`
+}
+
+// Record represents an OAI-PMH record.
+type Record struct {
+	Header   Header   `xml:"header"`
+	Metadata Metadata `xml:"metadata"`
+}
+
+// Header represents the header of an OAI-PMH record.
+type Header struct {
+	Status     string   `xml:"status,attr,omitempty"`
+	Identifier string   `xml:"identifier"`
+	Datestamp  string   `xml:"datestamp"`
+	SetSpec    []string `xml:"setSpec"`
+}
+
+// Metadata represents the metadata of an OAI-PMH record, in one of the
+// supported metadata formats.
+type Metadata struct {
+	DublinCore  *DublinCore `xml:"dc"`
+	Resource    *Resource   `xml:"resource"`
+	OAIDataCite *Resource   `xml:"oai_datacite>payload>resource"`
+}
+
+// DublinCore represents the oai_dc metadata format.
+type DublinCore struct {
+	Title       []string `xml:"title"`
+	Creator     []string `xml:"creator"`
+	Subject     []string `xml:"subject"`
+	Description []string `xml:"description"`
+	Publisher   []string `xml:"publisher"`
+	Contributor []string `xml:"contributor"`
+	Date        []string `xml:"date"`
+	Type        []string `xml:"type"`
+	Format      []string `xml:"format"`
+	Identifier  []string `xml:"identifier"`
+	Source      []string `xml:"source"`
+	Language    []string `xml:"language"`
+	Relation    []string `xml:"relation"`
+	Rights      []string `xml:"rights"`
+}
+
+// Resource represents the DataCite metadata kernel, used by the datacite
+// and oai_datacite metadata formats.
+type Resource struct {
+	Identifier           Identifier          `xml:"identifier"`
+	Creators             []Contributor       `xml:"creators>creator"`
+	Titles               []Title             `xml:"titles>title"`
+	Publisher            Publisher           `xml:"publisher"`
+	PublicationYear      string              `xml:"publicationYear"`
+	ResourceType         ResourceType        `xml:"resourceType"`
+	Subjects             []Subject           `xml:"subjects>subject"`
+	Contributors         []Contributor       `xml:"contributors>contributor"`
+	Dates                []Date              `xml:"dates>date"`
+	Language             string              `xml:"language"`
+	AlternateIdentifiers []Identifier        `xml:"alternateIdentifiers>alternateIdentifier"`
+	RelatedIdentifiers   []RelatedIdentifier `xml:"relatedIdentifiers>relatedIdentifier"`
+	Sizes                []string            `xml:"sizes>size"`
+	Formats              []string            `xml:"formats>format"`
+	Version              string              `xml:"version"`
+	RightsList           []Rights            `xml:"rightsList>rights"`
+	Descriptions         []Description       `xml:"descriptions>description"`
+	FundingReferences    []FundingReference  `xml:"fundingReferences>fundingReference"`
+}
+
+// Identifier represents a DataCite identifier or alternate identifier.
+type Identifier struct {
+	Value                   string `xml:",chardata"`
+	IdentifierType          string `xml:"identifierType,attr,omitempty"`
+	AlternateIdentifierType string `xml:"alternateIdentifierType,attr,omitempty"`
+}
+
+// Contributor represents a DataCite creator or contributor.
+type Contributor struct {
+	CreatorName     *Name            `xml:"creatorName,omitempty"`
+	ContributorName *Name            `xml:"contributorName,omitempty"`
+	ContributorType string           `xml:"contributorType,attr,omitempty"`
+	GivenName       string           `xml:"givenName,omitempty"`
+	FamilyName      string           `xml:"familyName,omitempty"`
+	NameIdentifiers []NameIdentifier `xml:"nameIdentifier,omitempty"`
+	Affiliations    []Affiliation    `xml:"affiliation,omitempty"`
+}
+
+// Name represents the name of a DataCite creator or contributor.
+type Name struct {
+	Value    string `xml:",chardata"`
+	NameType string `xml:"nameType,attr,omitempty"`
+}
+
+// NameIdentifier represents a DataCite name identifier.
+type NameIdentifier struct {
+	Value                string `xml:",chardata"`
+	NameIdentifierScheme string `xml:"nameIdentifierScheme,attr,omitempty"`
+	SchemeURI            string `xml:"schemeURI,attr,omitempty"`
+}
+
+// Affiliation represents a DataCite affiliation.
+type Affiliation struct {
+	Value                       string `xml:",chardata"`
+	AffiliationIdentifier       string `xml:"affiliationIdentifier,attr,omitempty"`
+	AffiliationIdentifierScheme string `xml:"affiliationIdentifierScheme,attr,omitempty"`
+	SchemeURI                   string `xml:"schemeURI,attr,omitempty"`
+}
+
+// Title represents a DataCite title.
+type Title struct {
+	Value     string `xml:",chardata"`
+	TitleType string `xml:"titleType,attr,omitempty"`
+	Lang      string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+}
+
+// Publisher represents a DataCite publisher.
+type Publisher struct {
+	Value                     string `xml:",chardata"`
+	PublisherIdentifier       string `xml:"publisherIdentifier,attr,omitempty"`
+	PublisherIdentifierScheme string `xml:"publisherIdentifierScheme,attr,omitempty"`
+	SchemeURI                 string `xml:"schemeURI,attr,omitempty"`
+	Lang                      string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+}
+
+// ResourceType represents a DataCite resource type.
+type ResourceType struct {
+	Value               string `xml:",chardata"`
+	ResourceTypeGeneral string `xml:"resourceTypeGeneral,attr,omitempty"`
+}
+
+// Subject represents a DataCite subject.
+type Subject struct {
+	Value string `xml:",chardata"`
+}
+
+// Date represents a DataCite date.
+type Date struct {
+	Value           string `xml:",chardata"`
+	DateType        string `xml:"dateType,attr,omitempty"`
+	DateInformation string `xml:"dateInformation,attr,omitempty"`
+}
+
+// RelatedIdentifier represents a DataCite related identifier.
+type RelatedIdentifier struct {
+	Value                 string `xml:",chardata"`
+	RelatedIdentifierType string `xml:"relatedIdentifierType,attr,omitempty"`
+	RelationType          string `xml:"relationType,attr,omitempty"`
+	ResourceTypeGeneral   string `xml:"resourceTypeGeneral,attr,omitempty"`
+}
+
+// Rights represents a DataCite rights statement.
+type Rights struct {
+	Value                  string `xml:",chardata"`
+	RightsURI              string `xml:"rightsURI,attr,omitempty"`
+	SchemeURI              string `xml:"schemeURI,attr,omitempty"`
+	RightsIdentifier       string `xml:"rightsIdentifier,attr,omitempty"`
+	RightsIdentifierScheme string `xml:"rightsIdentifierScheme,attr,omitempty"`
+}
+
+// Description represents a DataCite description.
+type Description struct {
+	Value           string `xml:",chardata"`
+	DescriptionType string `xml:"descriptionType,attr,omitempty"`
+	Lang            string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+}
+
+// FundingReference represents a DataCite funding reference.
+type FundingReference struct {
+	FunderName       string            `xml:"funderName,omitempty"`
+	FunderIdentifier *FunderIdentifier `xml:"funderIdentifier,omitempty"`
+	AwardNumber      *AwardNumber      `xml:"awardNumber,omitempty"`
+	AwardTitle       string            `xml:"awardTitle,omitempty"`
+}
+
+// FunderIdentifier represents the identifier of a DataCite funder.
+type FunderIdentifier struct {
+	Value                string `xml:",chardata"`
+	FunderIdentifierType string `xml:"funderIdentifierType,attr,omitempty"`
+}
+
+// AwardNumber represents the number of a DataCite award.
+type AwardNumber struct {
+	Value    string `xml:",chardata"`
+	AwardURI string `xml:"awardURI,attr,omitempty"`
+}
+
+// DCToCMMappings maps Dublin Core types not covered by the DataCite
+// resource types, e.g. the info:eu-repo vocabulary, to Commonmeta types
+var DCToCMMappings = map[string]string{
+	"article":                "JournalArticle",
+	"bachelorThesis":         "Dissertation",
+	"book":                   "Book",
+	"bookPart":               "BookChapter",
+	"conferenceObject":       "ProceedingsArticle",
+	"conferencePaper":        "ProceedingsArticle",
+	"conferencePoster":       "Poster",
+	"dataset":                "Dataset",
+	"doctoralThesis":         "Dissertation",
+	"lecture":                "Presentation",
+	"masterThesis":           "Dissertation",
+	"MovingImage":            "Audiovisual",
+	"preprint":               "Article",
+	"report":                 "Report",
+	"review":                 "Review",
+	"software":               "Software",
+	"StillImage":             "Image",
+	"submittedVersion":       "Article",
+	"technicalDocumentation": "Document",
+	"workingPaper":           "Report",
+	"other":                  "Other",
+}
+
+// Fetch fetches a single record from an OAI-PMH repository and returns Commonmeta metadata.
+func Fetch(host string, identifier string, prefix string) (commonmeta.Data, error) {
+	var data commonmeta.Data
+	record, err := Get(host, identifier, prefix)
+	if err != nil {
+		return data, err
+	}
+	data, err = Read(record)
+	if err != nil {
+		return data, err
+	}
+	return data, nil
+}
+
+// FetchAll fetches a list of records from an OAI-PMH repository and returns Commonmeta metadata.
+func FetchAll(host string, prefix string, set string, from string, until string, number int) ([]commonmeta.Data, error) {
+	var data []commonmeta.Data
+	records, err := GetAll(host, prefix, set, from, until, number)
+	if err != nil {
+		return data, err
+	}
+	data, err = ReadAll(records)
+	return data, err
+}
+
+// Get retrieves a single record from an OAI-PMH repository using the GetRecord verb.
+func Get(host string, identifier string, prefix string) (Record, error) {
+	var record Record
+	if identifier == "" {
+		return record, fmt.Errorf("%w: missing identifier", commonmeta.ErrValidation)
+	}
+	if prefix == "" {
+		prefix = DefaultMetadataPrefix
+	}
+	values := url.Values{}
+	values.Set("verb", "GetRecord")
+	values.Set("identifier", identifier)
+	values.Set("metadataPrefix", prefix)
+	response, err := request(host, values)
+	if err != nil {
+		return record, err
+	}
+	return response.GetRecord.Record, nil
+}
+
+// GetAll retrieves records from an OAI-PMH repository using the ListRecords
+// verb, following resumption tokens until the list is complete or number
+// records have been retrieved. Deleted records are skipped.
+func GetAll(host string, prefix string, set string, from string, until string, number int) ([]Record, error) {
+	var records []Record
+	if prefix == "" {
+		prefix = DefaultMetadataPrefix
+	}
+	values := url.Values{}
+	values.Set("verb", "ListRecords")
+	values.Set("metadataPrefix", prefix)
+	if set != "" {
+		values.Set("set", set)
+	}
+	if from != "" {
+		values.Set("from", from)
+	}
+	if until != "" {
+		values.Set("until", until)
+	}
+	for {
+		response, err := request(host, values)
+		if err != nil {
+			return records, err
+		}
+		for _, record := range response.ListRecords.Records {
+			if record.Header.Status == "deleted" {
+				continue
+			}
+			records = append(records, record)
+			if number > 0 && len(records) >= number {
+				return records, nil
+			}
+		}
+		token := strings.TrimSpace(response.ListRecords.ResumptionToken.Token)
+		if token == "" {
+			return records, nil
+		}
+		// the resumption token is an exclusive argument besides the verb
+		values = url.Values{}
+		values.Set("verb", "ListRecords")
+		values.Set("resumptionToken", token)
+	}
+}
+
+// QueryURL returns the URL for an OAI-PMH request.
+func QueryURL(host string, values url.Values) string {
+	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
+		host = "https://" + host
+	}
+	return host + "?" + values.Encode()
+}
+
+// request sends an OAI-PMH request and decodes the response. An empty
+// result is not an error.
+func request(host string, values url.Values) (Response, error) {
+	var response Response
+	if host == "" {
+		return response, fmt.Errorf("%w: missing OAI-PMH base URL", commonmeta.ErrValidation)
+	}
+	if prefix := values.Get("metadataPrefix"); prefix != "" && !slices.Contains(MetadataPrefixes, prefix) {
+		return response, fmt.Errorf("%w: unsupported metadata prefix %s", commonmeta.ErrValidation, prefix)
+	}
+	client := httputils.Client()
+	resp, err := client.Get(QueryURL(host, values))
+	if err != nil {
+		return response, err
+	}
+	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return response, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return response, err
+	}
+	err = xml.Unmarshal(body, &response)
+	if err != nil {
+		return response, err
+	}
+	if response.Error != nil {
+		message := strings.TrimSpace(response.Error.Message)
+		switch response.Error.Code {
+		case "noRecordsMatch":
+			return response, nil
+		case "idDoesNotExist":
+			return response, fmt.Errorf("%w: %s", commonmeta.ErrNotFound, message)
+		default:
+			return response, fmt.Errorf("%w: %s %s", commonmeta.ErrValidation, response.Error.Code, message)
+		}
+	}
+	return response, nil
+}
+
+// Read reads an OAI-PMH record and converts it into Commonmeta metadata.
+func Read(record Record) (commonmeta.Data, error) {
+	var data commonmeta.Data
+	var err error
+
+	if record.Metadata.OAIDataCite != nil {
+		data, err = ReadResource(*record.Metadata.OAIDataCite)
+	} else if record.Metadata.Resource != nil {
+		data, err = ReadResource(*record.Metadata.Resource)
+	} else if record.Metadata.DublinCore != nil {
+		data, err = ReadDublinCore(*record.Metadata.DublinCore)
+	} else {
+		return data, fmt.Errorf("%w: record %s has no supported metadata", commonmeta.ErrValidation, record.Header.Identifier)
+	}
+	if err != nil {
+		return data, err
+	}
+	if data.ID == "" {
+		data.ID = record.Header.Identifier
+	}
+	if data.Date.Updated == "" {
+		data.Date.Updated = dateutils.ParseDateTime(record.Header.Datestamp)
+	}
+	return data, nil
+}
+
+// ReadAll reads a list of OAI-PMH records and converts them into Commonmeta metadata.
+func ReadAll(records []Record) ([]commonmeta.Data, error) {
+	var data []commonmeta.Data
+	for _, v := range records {
+		d, err := Read(v)
+		if err != nil {
+			return data, err
+		}
+		data = append(data, d)
+	}
+	return data, nil
+}
+
+// ReadResource converts the DataCite metadata kernel into Commonmeta metadata,
+// using the same mapping as the DataCite REST API reader.
+func ReadResource(resource Resource) (commonmeta.Data, error) {
+	content := datacite.Content{
+		Datacite:        &datacite.Datacite{},
+		PublicationYear: resource.PublicationYear,
+	}
+	if strings.EqualFold(resource.Identifier.IdentifierType, "DOI") {
+		content.DOI = strings.TrimSpace(resource.Identifier.Value)
+	}
+	for _, v := range resource.AlternateIdentifiers {
+		content.AlternateIdentifiers = append(content.AlternateIdentifiers, datacite.AlternateIdentifier{
+			AlternateIdentifier:     strings.TrimSpace(v.Value),
+			AlternateIdentifierType: v.AlternateIdentifierType,
+		})
+	}
+	for _, v := range resource.Creators {
+		content.Creators = append(content.Creators, contentContributor(v))
+	}
+	for _, v := range resource.Contributors {
+		content.Contributors = append(content.Contributors, contentContributor(v))
+	}
+	publisher, err := json.Marshal(datacite.Publisher{
+		Name:                      strings.TrimSpace(resource.Publisher.Value),
+		PublisherIdentifier:       resource.Publisher.PublisherIdentifier,
+		PublisherIdentifierScheme: resource.Publisher.PublisherIdentifierScheme,
+		SchemeURI:                 resource.Publisher.SchemeURI,
+		Lang:                      resource.Publisher.Lang,
+	})
+	if err != nil {
+		return commonmeta.Data{}, err
+	}
+	content.Publisher = publisher
+	for _, v := range resource.Titles {
+		content.Titles = append(content.Titles, datacite.Title{
+			Title:     strings.TrimSpace(v.Value),
+			TitleType: v.TitleType,
+			Lang:      v.Lang,
+		})
+	}
+	for _, v := range resource.Subjects {
+		content.Subjects = append(content.Subjects, datacite.Subject{Subject: strings.TrimSpace(v.Value)})
+	}
+	for _, v := range resource.Dates {
+		content.Dates = append(content.Dates, datacite.Date{
+			Date:            strings.TrimSpace(v.Value),
+			DateType:        v.DateType,
+			DateInformation: v.DateInformation,
+		})
+	}
+	content.Language = resource.Language
+	content.Types = datacite.Types{
+		ResourceTypeGeneral: resource.ResourceType.ResourceTypeGeneral,
+		ResourceType:        strings.TrimSpace(resource.ResourceType.Value),
+	}
+	for _, v := range resource.RelatedIdentifiers {
+		content.RelatedIdentifiers = append(content.RelatedIdentifiers, datacite.RelatedIdentifier{
+			RelatedIdentifier:     strings.TrimSpace(v.Value),
+			RelatedIdentifierType: v.RelatedIdentifierType,
+			RelationType:          v.RelationType,
+			ResourceTypeGeneral:   v.ResourceTypeGeneral,
+		})
+	}
+	content.Sizes = resource.Sizes
+	content.Formats = resource.Formats
+	content.Version = resource.Version
+	for _, v := range resource.RightsList {
+		content.RightsList = append(content.RightsList, datacite.Rights{
+			Rights:                 strings.TrimSpace(v.Value),
+			RightsURI:              v.RightsURI,
+			SchemeURI:              v.SchemeURI,
+			RightsIdentifier:       v.RightsIdentifier,
+			RightsIdentifierScheme: v.RightsIdentifierScheme,
+		})
+	}
+	for _, v := range resource.Descriptions {
+		content.Descriptions = append(content.Descriptions, datacite.Description{
+			Description:     strings.TrimSpace(v.Value),
+			DescriptionType: v.DescriptionType,
+			Lang:            v.Lang,
+		})
+	}
+	for _, v := range resource.FundingReferences {
+		fundingReference := datacite.FundingReference{
+			FunderName: strings.TrimSpace(v.FunderName),
+			AwardTitle: strings.TrimSpace(v.AwardTitle),
+		}
+		if v.FunderIdentifier != nil {
+			fundingReference.FunderIdentifier = strings.TrimSpace(v.FunderIdentifier.Value)
+			fundingReference.FunderIdentifierType = v.FunderIdentifier.FunderIdentifierType
+		}
+		if v.AwardNumber != nil {
+			fundingReference.AwardNumber = strings.TrimSpace(v.AwardNumber.Value)
+			fundingReference.AwardURI = v.AwardNumber.AwardURI
+		}
+		content.FundingReferences = append(content.FundingReferences, fundingReference)
+	}
+	return datacite.Read(content, false)
+}
+
+// contentContributor converts a DataCite XML creator or contributor into
+// the representation used by the DataCite REST API.
+func contentContributor(v Contributor) datacite.ContentContributor {
+	name := Name{}
+	if v.CreatorName != nil {
+		name = *v.CreatorName
+	} else if v.ContributorName != nil {
+		name = *v.ContributorName
+	}
+	contributor := datacite.Contributor{
+		Name:            strings.TrimSpace(name.Value),
+		NameType:        name.NameType,
+		GivenName:       strings.TrimSpace(v.GivenName),
+		FamilyName:      strings.TrimSpace(v.FamilyName),
+		ContributorType: v.ContributorType,
+	}
+	for _, n := range v.NameIdentifiers {
+		contributor.NameIdentifiers = append(contributor.NameIdentifiers, datacite.NameIdentifier{
+			NameIdentifier:       strings.TrimSpace(n.Value),
+			NameIdentifierScheme: n.NameIdentifierScheme,
+			SchemeURI:            n.SchemeURI,
+		})
+	}
+	affiliations := []datacite.Affiliation{}
+	for _, a := range v.Affiliations {
+		affiliations = append(affiliations, datacite.Affiliation{
+			Name:                        strings.TrimSpace(a.Value),
+			AffiliationIdentifier:       a.AffiliationIdentifier,
+			AffiliationIdentifierScheme: a.AffiliationIdentifierScheme,
+			SchemeURI:                   a.SchemeURI,
+		})
+	}
+	affiliation, _ := json.Marshal(affiliations)
+	return datacite.ContentContributor{
+		Contributor: &contributor,
+		Affiliation: affiliation,
+	}
+}
+
+// ReadDublinCore converts oai_dc metadata into Commonmeta metadata.
+func ReadDublinCore(dc DublinCore) (commonmeta.Data, error) {
+	var data commonmeta.Data
+
+	for _, v := range dc.Identifier {
+		v = strings.TrimSpace(v)
+		id, identifierType := utils.ValidateID(v)
+		if identifierType == "DOI" {
+			if data.ID == "" {
+				data.ID = doiutils.NormalizeDOI(id)
+			}
+			data.Identifiers = append(data.Identifiers, commonmeta.Identifier{
+				Identifier:     doiutils.NormalizeDOI(id),
+				IdentifierType: "DOI",
+			})
+		} else if identifierType == "URL" && data.URL == "" {
+			data.URL = v
+		}
+	}
+	if data.ID == "" {
+		data.ID = data.URL
+	}
+
+	data.Type = "Other"
+	for _, v := range dc.Type {
+		// strip vocabulary prefixes such as info:eu-repo/semantics/
+		v = strings.TrimSpace(v)
+		v = v[strings.LastIndex(v, "/")+1:]
+		if t := datacite.DCToCMMappings[v]; t != "" {
+			data.Type = t
+			break
+		}
+		if t := DCToCMMappings[v]; t != "" {
+			data.Type = t
+			break
+		}
+	}
+
+	for _, v := range dc.Creator {
+		data.Contributors = append(data.Contributors, dublinCoreContributor(v, "Author"))
+	}
+	for _, v := range dc.Contributor {
+		data.Contributors = append(data.Contributors, dublinCoreContributor(v, "Other"))
+	}
+
+	for _, v := range dc.Title {
+		data.Titles = append(data.Titles, commonmeta.Title{Title: strings.TrimSpace(v)})
+	}
+	for _, v := range dc.Description {
+		data.Descriptions = append(data.Descriptions, commonmeta.Description{
+			Description: utils.Sanitize(strings.TrimSpace(v)),
+			Type:        "Abstract",
+		})
+	}
+	for _, v := range dc.Subject {
+		data.Subjects = append(data.Subjects, commonmeta.Subject{Subject: strings.TrimSpace(v)})
+	}
+	if len(dc.Publisher) > 0 {
+		data.Publisher = commonmeta.Publisher{Name: strings.TrimSpace(dc.Publisher[0])}
+	}
+	if len(dc.Date) > 0 {
+		data.Date.Published = dateutils.ParseDate(strings.TrimSpace(dc.Date[0]))
+	}
+	if len(dc.Language) > 0 {
+		data.Language = strings.TrimSpace(dc.Language[0])
+	}
+	for _, v := range dc.Rights {
+		v = strings.TrimSpace(v)
+		if utils.ValidateURL(v) == "" {
+			continue
+		}
+		url, _ := utils.NormalizeCCUrl(v)
+		if url == "" {
+			url = v
+		}
+		data.License = commonmeta.License{
+			ID:  utils.URLToSPDX(url),
+			URL: url,
+		}
+		break
+	}
+	for _, v := range dc.Relation {
+		v = strings.TrimSpace(v)
+		id, identifierType := utils.ValidateID(v)
+		if identifierType == "DOI" {
+			data.Relations = append(data.Relations, commonmeta.Relation{
+				ID:   doiutils.NormalizeDOI(id),
+				Type: "References",
+			})
+		} else if identifierType == "URL" {
+			data.Relations = append(data.Relations, commonmeta.Relation{
+				ID:   v,
+				Type: "References",
+			})
+		}
+	}
+	return data, nil
+}
+
+// dublinCoreContributor converts a Dublin Core creator or contributor name
+// into a Commonmeta contributor.
+func dublinCoreContributor(name string, role string) commonmeta.Contributor {
+	givenName, familyName, name := authorutils.ParseName(strings.TrimSpace(name))
+	contributor := commonmeta.Contributor{
+		Type:             "Person",
+		GivenName:        givenName,
+		FamilyName:       familyName,
+		ContributorRoles: []string{role},
+	}
+	if name != "" {
+		contributor.Type = "Organization"
+		contributor.Name = name
+	}
+	return contributor
+}
@@ -0,0 +1,185 @@
+package oaipmh_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/oaipmh"
+)
+
+// newRepository returns a stand-in OAI-PMH repository serving the testdata files.
+func newRepository(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		var filename string
+		switch {
+		case query.Get("verb") == "GetRecord" && query.Get("identifier") == "oai:repo.example.org:201":
+			filename = "getrecord-oai_datacite.xml"
+		case query.Get("verb") == "GetRecord":
+			filename = "error-iddoesnotexist.xml"
+		case query.Get("resumptionToken") == "page-2":
+			filename = "listrecords-2.xml"
+		case query.Get("verb") == "ListRecords" && query.Get("set") == "xyz" && query.Get("from") == "2025-01-01":
+			filename = "listrecords-1.xml"
+		default:
+			http.Error(w, "unexpected request "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		body, err := os.ReadFile(filepath.Join("testdata", filename))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write(body)
+	}))
+}
+
+func TestFetchAll(t *testing.T) {
+	t.Parallel()
+	ts := newRepository(t)
+	defer ts.Close()
+
+	type testCase struct {
+		number int
+		want   []string
+	}
+	testCases := []testCase{
+		{number: 0, want: []string{"https://doi.org/10.5555/repo.101", "https://repo.example.org/records/103"}},
+		{number: 1, want: []string{"https://doi.org/10.5555/repo.101"}},
+	}
+	for _, tc := range testCases {
+		data, err := oaipmh.FetchAll(ts.URL, "oai_dc", "xyz", "2025-01-01", "", tc.number)
+		if err != nil {
+			t.Fatalf("FetchAll(%v): %v", tc.number, err)
+		}
+		var got []string
+		for _, d := range data {
+			got = append(got, d.ID)
+		}
+		if len(tc.want) != len(got) {
+			t.Fatalf("FetchAll(%v): want %v, got %v", tc.number, tc.want, got)
+		}
+		for i := range tc.want {
+			if tc.want[i] != got[i] {
+				t.Errorf("FetchAll(%v): want %v, got %v", tc.number, tc.want, got)
+			}
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+	ts := newRepository(t)
+	defer ts.Close()
+
+	data, err := oaipmh.Fetch(ts.URL, "oai:repo.example.org:201", "oai_datacite")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if data.ID != "https://doi.org/10.5072/example-full" {
+		t.Errorf("Fetch: want %v, got %v", "https://doi.org/10.5072/example-full", data.ID)
+	}
+	if data.Type != "Software" {
+		t.Errorf("Fetch: want %v, got %v", "Software", data.Type)
+	}
+	if len(data.Titles) == 0 || data.Titles[0].Title != "Full DataCite XML Example" {
+		t.Errorf("Fetch: want %v, got %v", "Full DataCite XML Example", data.Titles)
+	}
+	if len(data.Contributors) == 0 || data.Contributors[0].ID != "https://orcid.org/0000-0001-5000-0007" {
+		t.Errorf("Fetch: want %v, got %v", "https://orcid.org/0000-0001-5000-0007", data.Contributors)
+	}
+
+	_, err = oaipmh.Fetch(ts.URL, "oai:repo.example.org:999", "oai_datacite")
+	if !errors.Is(err, commonmeta.ErrNotFound) {
+		t.Errorf("Fetch: want %v, got %v", commonmeta.ErrNotFound, err)
+	}
+	_, err = oaipmh.Fetch(ts.URL, "oai:repo.example.org:201", "marcxml")
+	if !errors.Is(err, commonmeta.ErrValidation) {
+		t.Errorf("Fetch: want %v, got %v", commonmeta.ErrValidation, err)
+	}
+}
+
+func TestReadDublinCore(t *testing.T) {
+	t.Parallel()
+	body, err := os.ReadFile(filepath.Join("testdata", "listrecords-1.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response oaipmh.Response
+	err = xml.Unmarshal(body, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := oaipmh.Read(response.ListRecords.Records[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		field string
+		want  string
+		got   string
+	}
+	testCases := []testCase{
+		{field: "type", want: "JournalArticle", got: data.Type},
+		{field: "url", want: "https://repo.example.org/records/101", got: data.URL},
+		{field: "published", want: "2025-02-10", got: data.Date.Published},
+		{field: "updated", want: "2025-02-14T09:30:00Z", got: data.Date.Updated},
+		{field: "familyName", want: "Fenner", got: data.Contributors[0].FamilyName},
+		{field: "organization", want: "Example Research Group", got: data.Contributors[1].Name},
+		{field: "publisher", want: "Example Repository", got: data.Publisher.Name},
+		{field: "description", want: "An overview of <i>repository</i> metadata.", got: data.Descriptions[0].Description},
+		{field: "license", want: "CC-BY-4.0", got: data.License.ID},
+		{field: "relation", want: "https://doi.org/10.5555/repo.100", got: data.Relations[0].ID},
+	}
+	for _, tc := range testCases {
+		if tc.want != tc.got {
+			t.Errorf("Read(%v): want %v, got %v", tc.field, tc.want, tc.got)
+		}
+	}
+}
+
+func TestReadResource(t *testing.T) {
+	t.Parallel()
+	body, err := os.ReadFile(filepath.Join("..", "testdata", "datacitexml", "datacite-example-full-v4.4.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resource oaipmh.Resource
+	err = xml.Unmarshal(body, &resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := oaipmh.ReadResource(resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		field string
+		want  string
+		got   string
+	}
+	testCases := []testCase{
+		{field: "id", want: "https://doi.org/10.5072/example-full", got: data.ID},
+		{field: "type", want: "Software", got: data.Type},
+		{field: "publisher", want: "DataCite", got: data.Publisher.Name},
+		{field: "published", want: "2014", got: data.Date.Published},
+		{field: "updated", want: "2021-01-26", got: data.Date.Updated},
+		{field: "version", want: "4.2", got: data.Version},
+		{field: "language", want: "en-US", got: data.Language},
+	}
+	for _, tc := range testCases {
+		if tc.want != tc.got {
+			t.Errorf("ReadResource(%v): want %v, got %v", tc.field, tc.want, tc.got)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/datacite"
+	"github.com/front-matter/commonmeta/utils"
+)
+
+// DataCiteSchemaVersion is the DataCite metadata schema version written in oai_datacite records.
+const DataCiteSchemaVersion = "4.5"
+
+// OAIDC represents an oai_dc record written by the data provider.
+type OAIDC struct {
+	XMLName        xml.Name `xml:"oai_dc:dc"`
+	XmlnsOAIDC     string   `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string   `xml:"xmlns:dc,attr"`
+	XmlnsXsi       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	Title          []string `xml:"dc:title,omitempty"`
+	Creator        []string `xml:"dc:creator,omitempty"`
+	Subject        []string `xml:"dc:subject,omitempty"`
+	Description    []string `xml:"dc:description,omitempty"`
+	Publisher      []string `xml:"dc:publisher,omitempty"`
+	Contributor    []string `xml:"dc:contributor,omitempty"`
+	Date           []string `xml:"dc:date,omitempty"`
+	Type           []string `xml:"dc:type,omitempty"`
+	Identifier     []string `xml:"dc:identifier,omitempty"`
+	Source         []string `xml:"dc:source,omitempty"`
+	Language       []string `xml:"dc:language,omitempty"`
+	Relation       []string `xml:"dc:relation,omitempty"`
+	Rights         []string `xml:"dc:rights,omitempty"`
+}
+
+// OAIDataCite represents an oai_datacite record written by the data provider.
+type OAIDataCite struct {
+	XMLName        xml.Name `xml:"oai_datacite"`
+	Xmlns          string   `xml:"xmlns,attr"`
+	XmlnsXsi       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	SchemaVersion  string   `xml:"schemaVersion"`
+	Payload        struct {
+		Resource Resource `xml:"http://datacite.org/schema/kernel-4 resource"`
+	} `xml:"payload"`
+}
+
+// ConvertDublinCore converts Commonmeta metadata to the oai_dc metadata format.
+func ConvertDublinCore(data commonmeta.Data) OAIDC {
+	dc := OAIDC{
+		XmlnsOAIDC:     "http://www.openarchives.org/OAI/2.0/oai_dc/",
+		XmlnsDC:        "http://purl.org/dc/elements/1.1/",
+		XmlnsXsi:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: "http://www.openarchives.org/OAI/2.0/oai_dc/ http://www.openarchives.org/OAI/2.0/oai_dc.xsd",
+	}
+	for _, v := range data.Titles {
+		dc.Title = append(dc.Title, v.Title)
+	}
+	for _, v := range data.Contributors {
+		name := v.Name
+		if name == "" {
+			name = strings.Trim(v.FamilyName+", "+v.GivenName, ", ")
+		}
+		if len(v.ContributorRoles) == 0 || slices.Contains(v.ContributorRoles, "Author") {
+			dc.Creator = append(dc.Creator, name)
+		} else {
+			dc.Contributor = append(dc.Contributor, name)
+		}
+	}
+	for _, v := range data.Subjects {
+		dc.Subject = append(dc.Subject, v.Subject)
+	}
+	for _, v := range data.Descriptions {
+		dc.Description = append(dc.Description, v.Description)
+	}
+	if data.Publisher.Name != "" {
+		dc.Publisher = append(dc.Publisher, data.Publisher.Name)
+	}
+	if data.Date.Published != "" {
+		dc.Date = append(dc.Date, data.Date.Published)
+	}
+	if t := datacite.CMToDCMappings[data.Type]; t != "" {
+		dc.Type = append(dc.Type, t)
+	}
+	dc.Identifier = append(dc.Identifier, data.ID)
+	if data.URL != "" && data.URL != data.ID {
+		dc.Identifier = append(dc.Identifier, data.URL)
+	}
+	if data.Container.Title != "" {
+		dc.Source = append(dc.Source, data.Container.Title)
+	}
+	if data.Language != "" {
+		dc.Language = append(dc.Language, data.Language)
+	}
+	for _, v := range data.Relations {
+		dc.Relation = append(dc.Relation, v.ID)
+	}
+	if data.License.URL != "" {
+		dc.Rights = append(dc.Rights, data.License.URL)
+	}
+	return dc
+}
+
+// ConvertDataCite converts Commonmeta metadata to the oai_datacite metadata
+// format, using the same mapping as the DataCite writer.
+func ConvertDataCite(data commonmeta.Data) (OAIDataCite, error) {
+	record := OAIDataCite{
+		Xmlns:          "http://schema.datacite.org/oai/oai-1.1/",
+		XmlnsXsi:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: "http://schema.datacite.org/oai/oai-1.1/ http://schema.datacite.org/oai/oai-1.1/oai.xsd",
+		SchemaVersion:  DataCiteSchemaVersion,
+	}
+	content, err := datacite.Convert(data)
+	if err != nil {
+		return record, err
+	}
+
+	resource := Resource{
+		Identifier: Identifier{Value: content.DOI, IdentifierType: "DOI"},
+		Publisher:  Publisher{Value: content.Publisher.Name},
+		ResourceType: ResourceType{
+			Value:               content.Types.ResourceType,
+			ResourceTypeGeneral: content.Types.ResourceTypeGeneral,
+		},
+		Language: content.Language,
+		Version:  content.Version,
+		Sizes:    content.Sizes,
+		Formats:  content.Formats,
+	}
+	if content.DOI == "" {
+		resource.Identifier = Identifier{Value: data.ID, IdentifierType: "URL"}
+	}
+	if content.PublicationYear > 0 {
+		resource.PublicationYear = strconv.Itoa(content.PublicationYear)
+	}
+	for _, v := range content.Creators {
+		c := resourceContributor(v)
+		c.CreatorName = c.ContributorName
+		c.ContributorName = nil
+		resource.Creators = append(resource.Creators, c)
+	}
+	for _, v := range content.Contributors {
+		c := resourceContributor(v)
+		c.ContributorType = v.ContributorType
+		resource.Contributors = append(resource.Contributors, c)
+	}
+	for _, v := range content.Titles {
+		resource.Titles = append(resource.Titles, Title{Value: v.Title, TitleType: v.TitleType, Lang: v.Lang})
+	}
+	for _, v := range content.Subjects {
+		resource.Subjects = append(resource.Subjects, Subject{Value: v.Subject})
+	}
+	for _, v := range content.Dates {
+		resource.Dates = append(resource.Dates, Date{Value: v.Date, DateType: v.DateType, DateInformation: v.DateInformation})
+	}
+	for _, v := range content.Identifiers {
+		resource.AlternateIdentifiers = append(resource.AlternateIdentifiers, Identifier{
+			Value:                   v.Identifier,
+			AlternateIdentifierType: v.IdentifierType,
+		})
+	}
+	for _, v := range content.RelatedIdentifiers {
+		if v.RelatedIdentifier == "" {
+			continue
+		}
+		resource.RelatedIdentifiers = append(resource.RelatedIdentifiers, RelatedIdentifier{
+			Value:                 v.RelatedIdentifier,
+			RelatedIdentifierType: v.RelatedIdentifierType,
+			RelationType:          v.RelationType,
+			ResourceTypeGeneral:   v.ResourceTypeGeneral,
+		})
+	}
+	for _, v := range content.RightsList {
+		resource.RightsList = append(resource.RightsList, Rights{
+			Value:                  v.Rights,
+			RightsURI:              v.RightsURI,
+			SchemeURI:              v.SchemeURI,
+			RightsIdentifier:       v.RightsIdentifier,
+			RightsIdentifierScheme: v.RightsIdentifierScheme,
+		})
+	}
+	for _, v := range content.Descriptions {
+		resource.Descriptions = append(resource.Descriptions, Description{
+			Value:           v.Description,
+			DescriptionType: v.DescriptionType,
+			Lang:            v.Lang,
+		})
+	}
+	for _, v := range content.FundingReferences {
+		fundingReference := FundingReference{
+			FunderName: v.FunderName,
+			AwardTitle: v.AwardTitle,
+		}
+		if v.FunderIdentifier != "" {
+			fundingReference.FunderIdentifier = &FunderIdentifier{Value: v.FunderIdentifier, FunderIdentifierType: v.FunderIdentifierType}
+		}
+		if v.AwardNumber != "" {
+			fundingReference.AwardNumber = &AwardNumber{Value: v.AwardNumber, AwardURI: v.AwardURI}
+		}
+		resource.FundingReferences = append(resource.FundingReferences, fundingReference)
+	}
+	record.Payload.Resource = resource
+	return record, nil
+}
+
+// resourceContributor converts a DataCite contributor into its XML
+// representation, using contributorName for the name.
+func resourceContributor(v datacite.Contributor) Contributor {
+	name := v.Name
+	if v.FamilyName != "" {
+		name = strings.Trim(v.FamilyName+", "+v.GivenName, ", ")
+	}
+	c := Contributor{
+		ContributorName: &Name{Value: name, NameType: v.NameType},
+		GivenName:       v.GivenName,
+		FamilyName:      v.FamilyName,
+	}
+	for _, n := range v.NameIdentifiers {
+		c.NameIdentifiers = append(c.NameIdentifiers, NameIdentifier{
+			Value:                n.NameIdentifier,
+			NameIdentifierScheme: n.NameIdentifierScheme,
+			SchemeURI:            n.SchemeURI,
+		})
+	}
+	for _, a := range v.Affiliation {
+		c.Affiliations = append(c.Affiliations, Affiliation{Value: a})
+	}
+	return c
+}
+
+// SetSpecs returns the OAI-PMH sets of a record, derived from its subjects
+// and its container.
+func SetSpecs(data commonmeta.Data) []Set {
+	var sets []Set
+	for _, v := range data.Subjects {
+		if slug := utils.StringToSlug(v.Subject); slug != "" {
+			sets = append(sets, Set{Spec: "subject:" + slug, Name: v.Subject})
+		}
+	}
+	if slug := utils.StringToSlug(data.Container.Title); slug != "" {
+		sets = append(sets, Set{Spec: "container:" + slug, Name: data.Container.Title})
+	}
+	return sets
+}
+
+// MarshalXML writes the DataCite metadata kernel in schema order. It omits
+// empty wrapper elements such as subjects, which encoding/xml would write
+// for empty slices.
+func (r Resource) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(r.Identifier, element("identifier")); err != nil {
+		return err
+	}
+	if err := encodeList(e, "creators", "creator", r.Creators); err != nil {
+		return err
+	}
+	if err := encodeList(e, "titles", "title", r.Titles); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(r.Publisher, element("publisher")); err != nil {
+		return err
+	}
+	if err := encodeString(e, "publicationYear", r.PublicationYear); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(r.ResourceType, element("resourceType")); err != nil {
+		return err
+	}
+	if err := encodeList(e, "subjects", "subject", r.Subjects); err != nil {
+		return err
+	}
+	if err := encodeList(e, "contributors", "contributor", r.Contributors); err != nil {
+		return err
+	}
+	if err := encodeList(e, "dates", "date", r.Dates); err != nil {
+		return err
+	}
+	if err := encodeString(e, "language", r.Language); err != nil {
+		return err
+	}
+	if err := encodeList(e, "alternateIdentifiers", "alternateIdentifier", r.AlternateIdentifiers); err != nil {
+		return err
+	}
+	if err := encodeList(e, "relatedIdentifiers", "relatedIdentifier", r.RelatedIdentifiers); err != nil {
+		return err
+	}
+	if err := encodeList(e, "sizes", "size", r.Sizes); err != nil {
+		return err
+	}
+	if err := encodeList(e, "formats", "format", r.Formats); err != nil {
+		return err
+	}
+	if err := encodeString(e, "version", r.Version); err != nil {
+		return err
+	}
+	if err := encodeList(e, "rightsList", "rights", r.RightsList); err != nil {
+		return err
+	}
+	if err := encodeList(e, "descriptions", "description", r.Descriptions); err != nil {
+		return err
+	}
+	if err := encodeList(e, "fundingReferences", "fundingReference", r.FundingReferences); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// element returns a start element in the default namespace.
+func element(name string) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: name}}
+}
+
+// encodeString writes a simple element unless the value is empty.
+func encodeString(e *xml.Encoder, name string, value string) error {
+	if value == "" {
+		return nil
+	}
+	return e.EncodeElement(value, element(name))
+}
+
+// encodeList writes a wrapper element with one child per item, unless
+// there are no items.
+func encodeList[T any](e *xml.Encoder, parent string, child string, items []T) error {
+	if len(items) == 0 {
+		return nil
+	}
+	start := element(parent)
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := e.EncodeElement(item, element(child)); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
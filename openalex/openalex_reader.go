@@ -4,7 +4,6 @@ package openalex
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"net/url"
 	"path"
 	"slices"
@@ -13,13 +12,13 @@ import (
 	"sync"
 
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/spdx"
 	"github.com/front-matter/commonmeta/utils"
 )
 
-const openAlexBaseURL = "https://api.openalex.org"
-
 // Reader struct to hold any configuration for the Openalex reader
 type Reader struct {
 	Email string // Email for polite pool of OpenAlex API
@@ -203,7 +202,7 @@ func (r *Reader) QueryURL(number int, page int, publisher string, type_ string,
 		"retraction",
 	}
 
-	u, _ := url.Parse("https://api.openalex.org")
+	u, _ := url.Parse(config.GetEndpoints().OpenAlexAPI)
 	u.Path = path.Join(u.Path, "works")
 	values := u.Query()
 	if number <= 0 {
@@ -277,7 +276,7 @@ func (r *Reader) QueryURL(number int, page int, publisher string, type_ string,
 
 // APIURL constructs a URL for accessing the OpenAlex API with a specific ID
 func (r *Reader) APIURL(id string, idType string) string {
-	u, _ := url.Parse(openAlexBaseURL)
+	u, _ := url.Parse(config.GetEndpoints().OpenAlexAPI)
 	var query = url.Values{}
 	if r.Email != "" {
 		query.Add("mailto", r.Email)
@@ -304,13 +303,14 @@ func (r *Reader) GetAll(number int, page int, publisher string, type_ string, sa
 		Results []Work `json:"results"`
 	}
 	url := r.QueryURL(number, page, publisher, type_, sample, ids, year, orcid, ror, hasORCID, hasROR, hasReferences, hasRelation, hasAbstract, hasAward, hasLicense, hasArchive)
-	resp, err := http.Get(url)
+	resp, err := httputils.Client().Get(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAlex API returned status %d", resp.StatusCode)
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return nil, err
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, err
@@ -325,14 +325,15 @@ func (r *Reader) Get(pid string) (*Work, error) {
 		return nil, fmt.Errorf("invalid identifier: %s", pid)
 	}
 	url := r.APIURL(id, idType)
-	resp, err := http.Get(url)
+	resp, err := httputils.Client().Get(url)
 	if err != nil {
 		fmt.Println(err, url)
 		return nil, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAlex API returned status %d", resp.StatusCode)
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return nil, err
 	}
 
 	// For MAG, PMID, PMCID we get a list response
@@ -344,7 +345,7 @@ func (r *Reader) Get(pid string) (*Work, error) {
 			return nil, err
 		}
 		if len(response.Results) == 0 {
-			return nil, fmt.Errorf("no results found for %s", pid)
+			return nil, fmt.Errorf("%w: no results found for %s", commonmeta.ErrNotFound, pid)
 		}
 		work := response.Results[0]
 		return &work, nil
@@ -446,7 +447,7 @@ func (r *Reader) GetFunders(ids []string) ([]Funder, error) {
 		batch := ids[i:end]
 		idsString := strings.Join(batch, "|")
 
-		u, err := url.Parse(openAlexBaseURL)
+		u, err := url.Parse(config.GetEndpoints().OpenAlexAPI)
 		if err != nil {
 			return nil, err
 		}
@@ -459,14 +460,15 @@ func (r *Reader) GetFunders(ids []string) ([]Funder, error) {
 		}
 		u.RawQuery = query.Encode()
 
-		resp, err := http.Get(u.String())
+		resp, err := httputils.Client().Get(u.String())
 		if err != nil {
 			return nil, err
 		}
 		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("OpenAlex API returned status %d", resp.StatusCode)
+		err = httputils.CheckResponse(resp)
+		if err != nil {
+			return nil, err
 		}
 
 		var response struct {
@@ -488,7 +490,7 @@ func (r *Reader) GetSource(sourceID string) (*Source, error) {
 		return nil, fmt.Errorf("invalid OpenAlex source ID: %s", sourceID)
 	}
 
-	u, err := url.Parse(openAlexBaseURL)
+	u, err := url.Parse(config.GetEndpoints().OpenAlexAPI)
 	if err != nil {
 		return nil, err
 	}
@@ -500,14 +502,15 @@ func (r *Reader) GetSource(sourceID string) (*Source, error) {
 	}
 	u.RawQuery = query.Encode()
 
-	resp, err := http.Get(u.String())
+	resp, err := httputils.Client().Get(u.String())
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAlex API returned status %d", resp.StatusCode)
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return nil, err
 	}
 
 	var source Source
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/httputils"
 )
 
 // UpdateLegacyRecord updates a record in Rogue Scholar legacy database.
@@ -37,13 +38,15 @@ func UpdateLegacyRecord(record commonmeta.APIResponse, legacyKey string, field s
 		"Authorization": {"Bearer " + legacyKey},
 		"Prefer":        {"return=minimal"},
 	}
-	client := &http.Client{
-		Timeout: time.Second * 30,
-	}
+	client := httputils.Client()
 	resp, err := client.Do(req)
-	if resp.StatusCode != 204 {
+	if err != nil {
 		return record, err
 	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 204 {
+		return record, nil
+	}
 	record.Status = "updated_legacy"
 	return record, nil
 }
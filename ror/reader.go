@@ -6,16 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"slices"
 	"strings"
-	"time"
 
 	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/fileutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/utils"
 	"github.com/front-matter/commonmeta/vocabularies"
 	"github.com/parquet-go/parquet-go"
@@ -162,25 +162,24 @@ func Get(str string) (ROR, error) {
 
 	id, type_ := utils.ValidateID(str)
 	if !slices.Contains(commonmeta.OrganizationTypes, type_) {
-		return ror, errors.New("not a supported organization id")
+		return ror, fmt.Errorf("%w: not a supported organization id", commonmeta.ErrValidation)
 	}
 	if type_ == "ROR" {
-		url_ = "https://api.ror.org/v2/organizations/" + id
+		url_ = config.GetEndpoints().RORAPI + "/v2/organizations/" + id
 	} else {
-		url_ = "https://api.ror.org/v2/organizations?query=" + url.QueryEscape(id)
+		url_ = config.GetEndpoints().RORAPI + "/v2/organizations?query=" + url.QueryEscape(id)
 	}
 
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
+	client := httputils.Client()
 	resp, err := client.Get(url_)
 	if err != nil {
 		return ror, err
 	}
-	if resp.StatusCode >= 400 {
-		return ror, errors.New(resp.Status)
-	}
 	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return ror, err
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return ror, err
@@ -194,7 +193,7 @@ func Get(str string) (ROR, error) {
 		}
 	}
 	if err != nil {
-		return ror, errors.New("error unmarshalling response")
+		return ror, fmt.Errorf("error unmarshalling response: %w", err)
 	}
 	return ror, err
 }
@@ -219,18 +218,17 @@ func MatchOrganization(name string) (ROR, error) {
 	var content Content
 	var data ROR
 
-	url := "https://api.ror.org/v2/organizations?affiliation=" + url.QueryEscape(name)
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
+	url := config.GetEndpoints().RORAPI + "/v2/organizations?affiliation=" + url.QueryEscape(name)
+	client := httputils.Client()
 	resp, err := client.Get(url)
 	if err != nil {
 		return data, err
 	}
-	if resp.StatusCode >= 400 {
-		return data, errors.New(resp.Status)
-	}
 	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return data, err
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return data, err
@@ -238,7 +236,7 @@ func MatchOrganization(name string) (ROR, error) {
 	err = json.Unmarshal(body, &content)
 	if err != nil {
 		fmt.Println(err)
-		return data, errors.New("error unmarshalling response")
+		return data, fmt.Errorf("error unmarshalling response: %w", err)
 	}
 
 	// fmt.Println("Number of results:", content.NumberOfResults)
@@ -261,7 +259,7 @@ func Search(id string) (ROR, error) {
 
 	pid, type_ := utils.ValidateID(id)
 	if !slices.Contains(commonmeta.OrganizationTypes, type_) {
-		return ror, errors.New("not a supported organization id")
+		return ror, fmt.Errorf("%w: not a supported organization id", commonmeta.ErrValidation)
 	}
 
 	list, err := LoadBuiltin()
@@ -288,7 +286,7 @@ func Search(id string) (ROR, error) {
 		})
 	}
 	if idx == -1 {
-		return ror, errors.New("no organization found")
+		return ror, fmt.Errorf("%w: no organization found for %s", commonmeta.ErrNotFound, id)
 	}
 
 	ror = list[idx]
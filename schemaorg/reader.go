@@ -4,12 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"os"
 	"path"
 	"slices"
 	"strings"
-	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/front-matter/commonmeta/commonmeta"
@@ -17,6 +15,7 @@ import (
 	"github.com/front-matter/commonmeta/datacite"
 	"github.com/front-matter/commonmeta/dateutils"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/utils"
 	"github.com/samber/lo"
 )
@@ -194,16 +193,15 @@ func Get(url string) (Content, error) {
 	var content Content
 	var err error
 
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
+	client := httputils.Client()
 	resp, err := client.Get(url)
 	if err != nil {
 		return content, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return content, fmt.Errorf("HTTP status code: %d", resp.StatusCode)
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return content, err
 	}
 
 	// Load the HTML document
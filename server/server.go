@@ -0,0 +1,418 @@
+// Package server provides a HTTP server for DOI content negotiation and
+// metadata conversion, so that commonmeta can run as a small service.
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/crossref"
+	"github.com/front-matter/commonmeta/crossrefxml"
+	"github.com/front-matter/commonmeta/csl"
+	"github.com/front-matter/commonmeta/datacite"
+	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/inveniordm"
+	"github.com/front-matter/commonmeta/oaipmh"
+	"github.com/front-matter/commonmeta/schemaorg"
+	"github.com/front-matter/commonmeta/utils"
+)
+
+// MediaTypes maps the media types supported by content negotiation to
+// commonmeta formats.
+var MediaTypes = map[string]string{
+	"application/vnd.commonmeta+json":         "commonmeta",
+	"application/vnd.citationstyles.csl+json": "csl",
+	"application/vnd.datacite.datacite+json":  "datacite",
+	UnixrefMediaType:                          "crossrefxml",
+	"application/ld+json":                     "schemaorg",
+	"text/x-bibliography":                     "bibliography",
+	"application/vnd.inveniordm.v1+json":      "inveniordm",
+	"application/json":                        "commonmeta",
+}
+
+// DefaultMediaType is returned when the Accept header is missing or */*.
+const DefaultMediaType = "application/vnd.commonmeta+json"
+
+// UnixrefMediaType is the media type of the UNIXREF registered with
+// Crossref, which is only available for Crossref DOIs.
+const UnixrefMediaType = "application/vnd.crossref.unixref+xml"
+
+// DepositMediaType is the media type of the Crossref XML deposit returned
+// by POST /convert?to=crossrefxml.
+const DepositMediaType = "application/xml"
+
+// Options configures the server. With Match, readers match affiliations
+// and funders to ROR. The Crossref depositor, email and registrant are used
+// for Crossref XML output. With Webhooks, signed webhook calls for
+// published items run the steps of the Pipeline with Workers concurrent
+// jobs, and finished jobs are kept for JobRetention. Items from InvenioRDM
+// are fetched from InvenioRDMHost. OAIRecords are served by an OAI-PMH
+// data provider at /oai, with Email as admin email.
+type Options struct {
+	Match      bool
+	Depositor  string
+	Email      string
+	Registrant string
+
+	Webhooks       bool
+	WebhookSecret  string
+	Pipeline       []Step
+	Workers        int
+	JobRetention   time.Duration
+	InvenioRDMHost string
+
+	OAIRecords []commonmeta.Data
+}
+
+// Server serves DOI content negotiation and metadata conversion.
+type Server struct {
+	options Options
+	mux     *http.ServeMux
+}
+
+// New returns a server with the given options.
+func New(options Options) *Server {
+	s := &Server{options: options, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /convert", s.handleConvert)
+	if options.Webhooks {
+		wh := newWebhooks(options)
+		s.mux.HandleFunc("POST /webhooks/{source}", wh.handleWebhook)
+		s.mux.HandleFunc("GET /webhooks/jobs/{id}", wh.handleJob)
+	}
+	if options.OAIRecords != nil {
+		provider := oaipmh.NewProvider(options.OAIRecords, oaipmh.ProviderOptions{AdminEmail: options.Email})
+		s.mux.Handle("GET /oai", provider)
+		s.mux.Handle("POST /oai", provider)
+	}
+	s.mux.HandleFunc("GET /{id...}", s.handleGet)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleGet fetches the metadata for a DOI and returns the representation
+// chosen by the Accept header.
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		writeError(w, http.StatusNotFound, errors.New("please provide a DOI"))
+		return
+	}
+	mediaType, to, ok := Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		writeError(w, http.StatusNotAcceptable, fmt.Errorf("unsupported media type %s", r.Header.Get("Accept")))
+		return
+	}
+	if to == "crossrefxml" {
+		s.writeUnixref(w, id)
+		return
+	}
+	data, err := s.Fetch(id)
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+	s.write(w, data, mediaType, to)
+}
+
+// writeUnixref writes the UNIXREF of a Crossref DOI as registered with
+// Crossref.
+func (s *Server) writeUnixref(w http.ResponseWriter, id string) {
+	doi, ok := doiutils.ValidateDOI(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: invalid DOI %s", commonmeta.ErrNotFound, id))
+		return
+	}
+	if utils.FindFromFormatByID(doi) != "crossref" {
+		writeError(w, http.StatusNotAcceptable, fmt.Errorf("UNIXREF is only available for Crossref DOIs: %s", doi))
+		return
+	}
+	output, err := crossrefxml.GetUnixref(doi)
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", UnixrefMediaType)
+	w.Header().Set("Vary", "Accept")
+	w.Write(output)
+}
+
+// handleConvert converts metadata in the request body from one format to
+// another. The from format is detected if missing, the to format is chosen
+// by the Accept header if missing.
+func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request) {
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	mediaType := ""
+	if to == "" {
+		var ok bool
+		mediaType, to, ok = Negotiate(r.Header.Get("Accept"))
+		if !ok {
+			writeError(w, http.StatusNotAcceptable, fmt.Errorf("unsupported media type %s", r.Header.Get("Accept")))
+			return
+		}
+		if mediaType == UnixrefMediaType {
+			writeError(w, http.StatusNotAcceptable, errors.New("UNIXREF is only available for registered Crossref DOIs, use to=crossrefxml for a Crossref deposit"))
+			return
+		}
+	} else {
+		mediaType = MediaType(to)
+		if mediaType == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported format %s", to))
+			return
+		}
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	data, err := s.Decode(body, from)
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+	s.write(w, data, mediaType, to)
+}
+
+// write writes commonmeta metadata in a format.
+func (s *Server) write(w http.ResponseWriter, data commonmeta.Data, mediaType string, to string) {
+	output, err := s.Encode(data, to)
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+	if to == "bibliography" {
+		mediaType += "; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Vary", "Accept")
+	w.Write(output)
+}
+
+// Fetch fetches the metadata for a DOI from Crossref or DataCite.
+func (s *Server) Fetch(id string) (commonmeta.Data, error) {
+	doi, ok := doiutils.ValidateDOI(id)
+	if !ok {
+		return commonmeta.Data{}, fmt.Errorf("%w: invalid DOI %s", commonmeta.ErrNotFound, id)
+	}
+	switch utils.FindFromFormatByID(doi) {
+	case "crossref":
+		return crossref.Fetch(doi, s.options.Match)
+	case "datacite":
+		return datacite.Fetch(doi, s.options.Match)
+	}
+	return commonmeta.Data{}, fmt.Errorf("%w: DOI %s is not registered with Crossref or DataCite", commonmeta.ErrNotFound, doi)
+}
+
+// Decode reads metadata in a format. Supported formats are commonmeta,
+// crossref, crossrefxml, datacite, inveniordm, csl and schemaorg. If from
+// is empty, the format is detected from the content.
+func (s *Server) Decode(body []byte, from string) (commonmeta.Data, error) {
+	var data commonmeta.Data
+	var err error
+	if from == "" {
+		from = detectFormat(body)
+	}
+	switch from {
+	case "commonmeta":
+		err = json.Unmarshal(body, &data)
+	case "crossref":
+		// accept Crossref JSON with or without the message envelope
+		var response struct {
+			Message *crossref.Content `json:"message"`
+		}
+		var content crossref.Content
+		if err = json.Unmarshal(body, &response); err == nil && response.Message != nil {
+			content = *response.Message
+		} else {
+			err = json.Unmarshal(body, &content)
+		}
+		if err == nil {
+			data, err = crossref.Read(content, s.options.Match)
+		}
+	case "crossrefxml":
+		var query crossrefxml.Query
+		if err = xml.Unmarshal(body, &query); err == nil {
+			data, err = crossrefxml.Read(query)
+		}
+	case "datacite":
+		// accept DataCite JSON with or without the JSON:API envelope
+		var response struct {
+			Data datacite.Data `json:"data"`
+		}
+		var content datacite.Content
+		if err = json.Unmarshal(body, &response); err == nil && response.Data.Attributes.DOI != "" {
+			content = response.Data.Attributes
+		} else {
+			err = json.Unmarshal(body, &content)
+		}
+		if err == nil {
+			data, err = datacite.Read(content, s.options.Match)
+		}
+	case "inveniordm":
+		var content inveniordm.Content
+		if err = json.Unmarshal(body, &content); err == nil {
+			data, err = inveniordm.Read(content, s.options.Match)
+		}
+	case "csl":
+		var content csl.Content
+		if err = json.Unmarshal(body, &content); err == nil {
+			data, err = csl.Read(content)
+		}
+	case "schemaorg":
+		var content schemaorg.Content
+		if err = json.Unmarshal(body, &content); err == nil {
+			data, err = schemaorg.Read(content)
+		}
+	default:
+		return data, fmt.Errorf("%w: unsupported format %s", commonmeta.ErrValidation, from)
+	}
+	if err != nil {
+		return data, fmt.Errorf("%w: %w", commonmeta.ErrValidation, err)
+	}
+	return data, nil
+}
+
+// Encode writes commonmeta metadata in a format.
+func (s *Server) Encode(data commonmeta.Data, to string) ([]byte, error) {
+	switch to {
+	case "commonmeta":
+		return commonmeta.Write(data)
+	case "csl":
+		return csl.Write(data)
+	case "datacite":
+		return datacite.Write(data)
+	case "crossrefxml":
+		account := crossrefxml.Account{
+			Depositor:  s.options.Depositor,
+			Email:      s.options.Email,
+			Registrant: s.options.Registrant,
+		}
+		return crossrefxml.Write(data, account)
+	case "schemaorg":
+		return schemaorg.Write(data)
+	case "inveniordm":
+		return inveniordm.Write(data, "")
+	case "bibliography":
+		return []byte(csl.Bibliography(data)), nil
+	}
+	return nil, fmt.Errorf("%w: unsupported format %s", commonmeta.ErrValidation, to)
+}
+
+// Negotiate chooses the media type and format with the highest quality
+// from an Accept header. It returns false if no media type is supported.
+func Negotiate(accept string) (string, string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return DefaultMediaType, MediaTypes[DefaultMediaType], true
+	}
+	type candidate struct {
+		mediaType string
+		q         float64
+		order     int
+	}
+	var candidates []candidate
+	for i, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		if mediaType == "*/*" || mediaType == "application/*" {
+			mediaType = DefaultMediaType
+		}
+		if _, ok := MediaTypes[mediaType]; ok {
+			candidates = append(candidates, candidate{mediaType, q, i})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", "", false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	mediaType := candidates[0].mediaType
+	return mediaType, MediaTypes[mediaType], true
+}
+
+// MediaType returns the media type of a format, or an empty string if the
+// format is not supported. Crossref XML is a deposit, not UNIXREF.
+func MediaType(format string) string {
+	if format == "commonmeta" {
+		return DefaultMediaType
+	} else if format == "crossrefxml" {
+		return DepositMediaType
+	}
+	for mediaType, f := range MediaTypes {
+		if f == format {
+			return mediaType
+		}
+	}
+	return ""
+}
+
+// detectFormat detects the format of metadata from its content.
+func detectFormat(body []byte) string {
+	s := strings.TrimSpace(string(body))
+	if strings.HasPrefix(s, "<") {
+		return "crossrefxml"
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return ""
+	}
+	switch {
+	case fields["@context"] != nil:
+		return "schemaorg"
+	case fields["data"] != nil, fields["creators"] != nil && fields["types"] != nil:
+		return "datacite"
+	case fields["metadata"] != nil && fields["pids"] != nil:
+		return "inveniordm"
+	case fields["message"] != nil, fields["member"] != nil:
+		return "crossref"
+	case fields["DOI"] != nil, fields["container-title"] != nil:
+		return "csl"
+	}
+	return "commonmeta"
+}
+
+// statusCode returns the HTTP status code for an error.
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, commonmeta.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, commonmeta.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, commonmeta.ErrRateLimited):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// writeError writes an error as JSON.
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
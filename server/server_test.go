@@ -0,0 +1,161 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
+	"github.com/front-matter/commonmeta/oaipmh"
+	"github.com/front-matter/commonmeta/server"
+)
+
+func TestNegotiate(t *testing.T) {
+	t.Parallel()
+	type testCase struct {
+		input string
+		want  string
+		ok    bool
+	}
+	testCases := []testCase{
+		{input: "", want: "commonmeta", ok: true},
+		{input: "*/*", want: "commonmeta", ok: true},
+		{input: "application/vnd.citationstyles.csl+json", want: "csl", ok: true},
+		{input: "text/x-bibliography; style=apa", want: "bibliography", ok: true},
+		{input: "application/ld+json;q=0.5, application/vnd.datacite.datacite+json", want: "datacite", ok: true},
+		{input: "application/vnd.crossref.unixref+xml;q=0.9, text/html", want: "crossrefxml", ok: true},
+		{input: "text/html", want: "", ok: false},
+		{input: "application/ld+json;q=0", want: "", ok: false},
+	}
+	for _, tc := range testCases {
+		_, got, ok := server.Negotiate(tc.input)
+		if tc.want != got || tc.ok != ok {
+			t.Errorf("Negotiate(%v): want %v %v, got %v %v", tc.input, tc.want, tc.ok, got, ok)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	input, err := os.ReadFile(filepath.Join("..", "testdata", "commonmeta", "commonmeta.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(server.New(server.Options{}))
+	defer srv.Close()
+
+	type testCase struct {
+		query       string
+		accept      string
+		status      int
+		contentType string
+		contains    string
+	}
+	testCases := []testCase{
+		{query: "from=commonmeta&to=csl", status: http.StatusOK, contentType: "application/vnd.citationstyles.csl+json", contains: `"DOI":"10.7554/elife.01567"`},
+		{query: "from=commonmeta", accept: "text/x-bibliography", status: http.StatusOK, contentType: "text/x-bibliography; charset=utf-8", contains: "Sankar, M., "},
+		{query: "", accept: "application/ld+json", status: http.StatusOK, contentType: "application/ld+json", contains: `"@context"`},
+		{query: "from=commonmeta&to=unknown", status: http.StatusBadRequest, contentType: "application/json", contains: "unsupported format"},
+		{query: "from=commonmeta", accept: "text/html", status: http.StatusNotAcceptable, contentType: "application/json", contains: "unsupported media type"},
+		{query: "from=bibtex&to=csl", status: http.StatusUnprocessableEntity, contentType: "application/json", contains: "unsupported format"},
+		{query: "from=commonmeta&to=crossrefxml", status: http.StatusOK, contentType: "application/xml", contains: "<doi_batch"},
+		{query: "from=commonmeta", accept: "application/vnd.crossref.unixref+xml", status: http.StatusNotAcceptable, contentType: "application/json", contains: "UNIXREF"},
+	}
+	for _, tc := range testCases {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/convert?"+tc.query, bytes.NewReader(input))
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		resp.Body.Close()
+		if tc.status != resp.StatusCode || tc.contentType != resp.Header.Get("Content-Type") || !strings.Contains(body.String(), tc.contains) {
+			t.Errorf("Convert(%v, %v): want %v %v %v, got %v %v %v", tc.query, tc.accept, tc.status, tc.contentType, tc.contains, resp.StatusCode, resp.Header.Get("Content-Type"), body.String())
+		}
+	}
+}
+
+func TestGetInvalidDOI(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(server.New(server.Options{}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/not-a-doi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got map[string]string
+	json.NewDecoder(resp.Body).Decode(&got)
+	if resp.StatusCode != http.StatusNotFound || got["error"] == "" {
+		t.Errorf("Get(not-a-doi): want 404 with error, got %v %v", resp.StatusCode, got)
+	}
+}
+
+func TestGetUnixref(t *testing.T) {
+	unixref := `<doi_records><doi_record><crossref><journal/></crossref></doi_record></doi_records>`
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/works/10.59350/test/transform/application/vnd.crossref.unixref+xml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(unixref))
+	}))
+	defer api.Close()
+	config.SetEndpoints(config.Endpoints{CrossrefAPI: api.URL})
+	defer config.ResetEndpoints()
+	srv := httptest.NewServer(server.New(server.Options{}))
+	defer srv.Close()
+
+	type testCase struct {
+		id     string
+		status int
+		want   string
+	}
+	testCases := []testCase{
+		{id: "10.59350/test", status: http.StatusOK, want: unixref},
+		{id: "10.83132/test", status: http.StatusNotAcceptable, want: "only available for Crossref DOIs"},
+	}
+	for _, tc := range testCases {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/"+tc.id, nil)
+		req.Header.Set("Accept", "application/vnd.crossref.unixref+xml")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		resp.Body.Close()
+		if tc.status != resp.StatusCode || !strings.Contains(body.String(), tc.want) {
+			t.Errorf("Get(%v): want %v %v, got %v %v", tc.id, tc.status, tc.want, resp.StatusCode, body.String())
+		}
+		if tc.status == http.StatusOK && resp.Header.Get("Content-Type") != "application/vnd.crossref.unixref+xml" {
+			t.Errorf("Get(%v): want UNIXREF, got %v", tc.id, resp.Header.Get("Content-Type"))
+		}
+	}
+}
+
+func TestOAI(t *testing.T) {
+	t.Parallel()
+	records := []commonmeta.Data{{ID: "https://doi.org/10.5555/oai.1", Type: "JournalArticle"}}
+	srv := httptest.NewServer(server.New(server.Options{OAIRecords: records}))
+	defer srv.Close()
+
+	data, err := oaipmh.FetchAll(srv.URL+"/oai", "oai_dc", "", "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || data[0].ID != records[0].ID {
+		t.Errorf("ListRecords: want %v, got %v", records[0].ID, data)
+	}
+}
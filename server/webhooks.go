@@ -0,0 +1,384 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/inveniordm"
+	"github.com/front-matter/commonmeta/jsonfeed"
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+)
+
+// Webhook job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Webhook sources
+var WebhookSources = []string{"ghost", "inveniordm", "jsonfeed"}
+
+// MaxSignatureAge is the maximum difference between the signed timestamp
+// of a webhook call and the time it is received, to reject replayed calls.
+const MaxSignatureAge = 5 * time.Minute
+
+// DefaultJobRetention is how long finished jobs are kept for polling.
+const DefaultJobRetention = 24 * time.Hour
+
+// Step is a step of the registration pipeline run for a published item,
+// e.g. registering the DOI with Crossref. Run returns a status message.
+type Step struct {
+	Name string
+	Run  func(data commonmeta.Data) (string, error)
+}
+
+// StepResult is the result of a step of the registration pipeline.
+type StepResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Job is a webhook call queued for registration.
+type Job struct {
+	ID      string       `json:"id"`
+	Source  string       `json:"source"`
+	Item    string       `json:"item"`
+	Status  string       `json:"status"`
+	Error   string       `json:"error,omitempty"`
+	Steps   []StepResult `json:"steps,omitempty"`
+	Created time.Time    `json:"created"`
+	Updated time.Time    `json:"updated"`
+}
+
+// webhooks queues webhook calls and runs the registration pipeline for
+// them with a pool of workers. Queued holds the id of the queued job per
+// source and item, finished jobs are removed after the job retention.
+type webhooks struct {
+	options Options
+	client  *inveniordm.InvenioRDMClient
+	queue   chan string
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	queued  map[string]string
+}
+
+// newWebhooks starts the workers of the webhook queue.
+func newWebhooks(options Options) *webhooks {
+	wh := &webhooks{
+		options: options,
+		client:  inveniordm.NewClient(rate.NewLimiter(rate.Every(100*time.Millisecond), 100), options.InvenioRDMHost),
+		queue:   make(chan string, 1000),
+		jobs:    make(map[string]*Job),
+		queued:  make(map[string]string),
+	}
+	if wh.options.JobRetention <= 0 {
+		wh.options.JobRetention = DefaultJobRetention
+	}
+	for range max(1, options.Workers) {
+		go wh.work()
+	}
+	return wh
+}
+
+// handleWebhook verifies the signature of a webhook call, extracts the id
+// of the published item and queues a job for it. A call for an item that
+// is already queued returns the queued job, as the item is fetched only
+// when the job runs.
+func (wh *webhooks) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	source := r.PathValue("source")
+	body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !VerifySignature(source, wh.options.WebhookSecret, body, r.Header) {
+		writeError(w, http.StatusUnauthorized, errors.New("invalid signature"))
+		return
+	}
+	item, err := WebhookItem(source, body)
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	now := time.Now().UTC()
+	key := source + " " + item
+	wh.mu.Lock()
+	wh.prune(now)
+	if id, ok := wh.queued[key]; ok {
+		queued := copyJob(wh.jobs[id])
+		wh.mu.Unlock()
+		w.Header().Set("Location", "/webhooks/jobs/"+id)
+		writeJSON(w, http.StatusOK, queued)
+		return
+	}
+	job := &Job{
+		ID:      uuid.NewString(),
+		Source:  source,
+		Item:    item,
+		Status:  JobQueued,
+		Created: now,
+		Updated: now,
+	}
+	wh.jobs[job.ID] = job
+	wh.queued[key] = job.ID
+	wh.mu.Unlock()
+	select {
+	case wh.queue <- job.ID:
+	default:
+		wh.finish(job.ID, errors.New("webhook queue is full"))
+		writeError(w, http.StatusServiceUnavailable, errors.New("webhook queue is full"))
+		return
+	}
+
+	queued, _ := wh.job(job.ID)
+	w.Header().Set("Location", "/webhooks/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, queued)
+}
+
+// handleJob returns the status of a job.
+func (wh *webhooks) handleJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := wh.job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: job %s", commonmeta.ErrNotFound, r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// job returns a copy of a job.
+func (wh *webhooks) job(id string) (Job, bool) {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+	job, ok := wh.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return copyJob(job), true
+}
+
+// copyJob returns a copy of a job, wh.mu must be held.
+func copyJob(job *Job) Job {
+	j := *job
+	j.Steps = append([]StepResult(nil), job.Steps...)
+	return j
+}
+
+// prune removes the jobs finished before the job retention, wh.mu must be
+// held.
+func (wh *webhooks) prune(now time.Time) {
+	for id, job := range wh.jobs {
+		if (job.Status == JobSucceeded || job.Status == JobFailed) && now.Sub(job.Updated) > wh.options.JobRetention {
+			delete(wh.jobs, id)
+		}
+	}
+}
+
+// work runs the registration pipeline for queued jobs.
+func (wh *webhooks) work() {
+	for id := range wh.queue {
+		job, _ := wh.job(id)
+		wh.update(id, func(j *Job) { j.Status = JobRunning })
+		data, err := wh.fetch(job.Source, job.Item)
+		if err != nil {
+			wh.finish(id, err)
+			continue
+		}
+		for _, step := range wh.options.Pipeline {
+			message, err := step.Run(data)
+			result := StepResult{Name: step.Name, Status: JobSucceeded, Message: message}
+			if err != nil {
+				result.Status = JobFailed
+				result.Error = err.Error()
+			}
+			wh.update(id, func(j *Job) { j.Steps = append(j.Steps, result) })
+			if err != nil {
+				break
+			}
+		}
+		wh.finish(id, nil)
+	}
+}
+
+// fetch fetches the published item of a job.
+func (wh *webhooks) fetch(source string, item string) (commonmeta.Data, error) {
+	if source == "inveniordm" {
+		return inveniordm.Fetch(item, wh.options.Match, wh.client)
+	}
+	return jsonfeed.Fetch(item)
+}
+
+// update changes a job. Jobs no longer queued are removed from the queued
+// jobs.
+func (wh *webhooks) update(id string, fn func(j *Job)) {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+	if job, ok := wh.jobs[id]; ok {
+		fn(job)
+		job.Updated = time.Now().UTC()
+		key := job.Source + " " + job.Item
+		if job.Status != JobQueued && wh.queued[key] == id {
+			delete(wh.queued, key)
+		}
+	}
+}
+
+// finish sets the final status of a job: failed if err is not nil or a
+// step failed, otherwise succeeded.
+func (wh *webhooks) finish(id string, err error) {
+	wh.update(id, func(j *Job) {
+		j.Status = JobSucceeded
+		if err != nil {
+			j.Status = JobFailed
+			j.Error = err.Error()
+		}
+		for _, step := range j.Steps {
+			if step.Status == JobFailed {
+				j.Status = JobFailed
+			}
+		}
+	})
+}
+
+// VerifySignature verifies the HMAC-SHA256 signature of a webhook call,
+// signing the body followed by the timestamp of the call. Ghost sends the
+// X-Ghost-Signature header in the format sha256=<hex>, t=<timestamp> with
+// the timestamp in milliseconds. Other sources send the X-Signature-256
+// header in the format sha256=<hex>, and the X-Signature-Timestamp header
+// with the Unix time in seconds. Calls are rejected if no secret is
+// configured, or the timestamp is missing or differs from the current time
+// by more than MaxSignatureAge.
+func VerifySignature(source string, secret string, body []byte, header http.Header) bool {
+	if secret == "" {
+		return false
+	}
+	var signature, timestamp string
+	var signed time.Time
+	if source == "ghost" {
+		for _, part := range strings.Split(header.Get("X-Ghost-Signature"), ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch k {
+			case "sha256":
+				signature = v
+			case "t":
+				timestamp = v
+			}
+		}
+		ms, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return false
+		}
+		signed = time.UnixMilli(ms)
+	} else {
+		signature = strings.TrimPrefix(header.Get("X-Signature-256"), "sha256=")
+		timestamp = header.Get("X-Signature-Timestamp")
+		sec, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return false
+		}
+		signed = time.Unix(sec, 0)
+	}
+	if age := time.Since(signed); age > MaxSignatureAge || age < -MaxSignatureAge {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil || len(got) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	mac.Write([]byte(timestamp))
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// Sign returns the signature of a webhook call from a source other than
+// Ghost at a time, for the X-Signature-256 header, and the timestamp for
+// the X-Signature-Timestamp header.
+func Sign(secret string, body []byte, t time.Time) (string, string) {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	mac.Write([]byte(timestamp))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil)), timestamp
+}
+
+// WebhookItem returns the id of the published item in a webhook call:
+// the DOI or UUID of a Ghost post, the id of an InvenioRDM record, or the
+// id of a JSON Feed item.
+func WebhookItem(source string, body []byte) (string, error) {
+	var item string
+	switch source {
+	case "ghost":
+		var payload struct {
+			Post struct {
+				Current struct {
+					UUID         string `json:"uuid"`
+					CanonicalURL string `json:"canonical_url"`
+				} `json:"current"`
+			} `json:"post"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return "", fmt.Errorf("%w: %w", commonmeta.ErrValidation, err)
+		}
+		item = payload.Post.Current.UUID
+		if strings.HasPrefix(payload.Post.Current.CanonicalURL, "https://doi.org/") {
+			item = payload.Post.Current.CanonicalURL
+		}
+	case "inveniordm":
+		var payload struct {
+			ID     string `json:"id"`
+			Record struct {
+				ID string `json:"id"`
+			} `json:"record"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return "", fmt.Errorf("%w: %w", commonmeta.ErrValidation, err)
+		}
+		item = payload.Record.ID
+		if item == "" {
+			item = payload.ID
+		}
+	case "jsonfeed":
+		var payload struct {
+			ID   string `json:"id"`
+			DOI  string `json:"doi"`
+			GUID string `json:"guid"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return "", fmt.Errorf("%w: %w", commonmeta.ErrValidation, err)
+		}
+		item = payload.DOI
+		if item == "" {
+			item = payload.ID
+		}
+	default:
+		return "", fmt.Errorf("%w: unsupported webhook source %s", commonmeta.ErrNotFound, source)
+	}
+	if item == "" {
+		return "", fmt.Errorf("%w: missing item id", commonmeta.ErrValidation)
+	}
+	return item, nil
+}
+
+// writeJSON writes a value as JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
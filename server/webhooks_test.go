@@ -0,0 +1,269 @@
+package server_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/front-matter/commonmeta/commonmeta"
+	"github.com/front-matter/commonmeta/config"
+	"github.com/front-matter/commonmeta/server"
+)
+
+func TestVerifySignature(t *testing.T) {
+	t.Parallel()
+	body := []byte(`{"id":"https://doi.org/10.59350/es0dc-vhb02"}`)
+	now := time.Now()
+	ghostSignature := func(t time.Time) string {
+		timestamp := strconv.FormatInt(t.UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		mac.Write([]byte(timestamp))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil)) + ", t=" + timestamp
+	}
+	signed := func(secret string, t time.Time) http.Header {
+		signature, timestamp := server.Sign(secret, body, t)
+		return http.Header{"X-Signature-256": {signature}, "X-Signature-Timestamp": {timestamp}}
+	}
+	unsigned, _ := server.Sign("secret", body, now)
+
+	type testCase struct {
+		source string
+		secret string
+		header http.Header
+		want   bool
+	}
+	testCases := []testCase{
+		{source: "jsonfeed", secret: "secret", header: signed("secret", now), want: true},
+		{source: "jsonfeed", secret: "secret", header: signed("other", now), want: false},
+		{source: "jsonfeed", secret: "", header: signed("", now), want: false},
+		{source: "jsonfeed", secret: "secret", header: http.Header{"X-Signature-256": {unsigned}}, want: false},
+		{source: "jsonfeed", secret: "secret", header: signed("secret", now.Add(-10*time.Minute)), want: false},
+		{source: "inveniordm", secret: "secret", header: signed("secret", now.Add(time.Minute)), want: true},
+		{source: "inveniordm", secret: "secret", header: http.Header{}, want: false},
+		{source: "ghost", secret: "secret", header: http.Header{"X-Ghost-Signature": {ghostSignature(now)}}, want: true},
+		{source: "ghost", secret: "secret", header: http.Header{"X-Ghost-Signature": {ghostSignature(now.Add(-10 * time.Minute))}}, want: false},
+		{source: "ghost", secret: "secret", header: signed("secret", now), want: false},
+	}
+	for _, tc := range testCases {
+		got := server.VerifySignature(tc.source, tc.secret, body, tc.header)
+		if tc.want != got {
+			t.Errorf("VerifySignature(%v, %v): want %v, got %v", tc.source, tc.header, tc.want, got)
+		}
+	}
+}
+
+func TestWebhookItem(t *testing.T) {
+	t.Parallel()
+	type testCase struct {
+		source string
+		input  string
+		want   string
+		err    error
+	}
+	testCases := []testCase{
+		{source: "ghost", input: `{"post":{"current":{"uuid":"3d02cf64-c600-4eb1-91b4-02f5bade5691","canonical_url":null}}}`, want: "3d02cf64-c600-4eb1-91b4-02f5bade5691"},
+		{source: "ghost", input: `{"post":{"current":{"uuid":"3d02cf64-c600-4eb1-91b4-02f5bade5691","canonical_url":"https://doi.org/10.59350/es0dc-vhb02"}}}`, want: "https://doi.org/10.59350/es0dc-vhb02"},
+		{source: "inveniordm", input: `{"record":{"id":"fh8y2-aef76"}}`, want: "fh8y2-aef76"},
+		{source: "inveniordm", input: `{"id":"fh8y2-aef76"}`, want: "fh8y2-aef76"},
+		{source: "jsonfeed", input: `{"id":"3d02cf64-c600-4eb1-91b4-02f5bade5691","doi":"https://doi.org/10.59350/es0dc-vhb02"}`, want: "https://doi.org/10.59350/es0dc-vhb02"},
+		{source: "jsonfeed", input: `{}`, err: commonmeta.ErrValidation},
+		{source: "wordpress", input: `{}`, err: commonmeta.ErrNotFound},
+	}
+	for _, tc := range testCases {
+		got, err := server.WebhookItem(tc.source, []byte(tc.input))
+		if tc.want != got || !errors.Is(err, tc.err) {
+			t.Errorf("WebhookItem(%v, %v): want %v %v, got %v %v", tc.source, tc.input, tc.want, tc.err, got, err)
+		}
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	item, err := os.ReadFile(filepath.Join("..", "jsonfeed", "testdata", "3d02cf64-c600-4eb1-91b4-02f5bade5691.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(item)
+	}))
+	defer api.Close()
+	config.SetEndpoints(config.Endpoints{RogueScholarAPI: api.URL})
+	defer config.ResetEndpoints()
+
+	registered := make(chan string, 1)
+	srv := httptest.NewServer(server.New(server.Options{
+		Webhooks:      true,
+		WebhookSecret: "secret",
+		Pipeline: []server.Step{
+			{Name: "register", Run: func(data commonmeta.Data) (string, error) {
+				registered <- data.ID
+				return "submitted", nil
+			}},
+			{Name: "notify", Run: func(data commonmeta.Data) (string, error) {
+				return "", errors.New("notification failed")
+			}},
+		},
+	}))
+	defer srv.Close()
+
+	body := []byte(`{"id":"https://doi.org/10.59350/es0dc-vhb02"}`)
+	resp, err := http.Post(srv.URL+"/webhooks/jsonfeed", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Webhook(unsigned): want %v, got %v", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	resp, err = postWebhook(srv.URL, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job server.Job
+	json.NewDecoder(resp.Body).Decode(&job)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || job.ID == "" || resp.Header.Get("Location") != "/webhooks/jobs/"+job.ID {
+		t.Fatalf("Webhook(signed): want %v with job, got %v %v", http.StatusAccepted, resp.StatusCode, job)
+	}
+
+	location := resp.Header.Get("Location")
+
+	select {
+	case id := <-registered:
+		if id != "https://doi.org/10.59350/es0dc-vhb02" {
+			t.Errorf("Webhook(signed): want https://doi.org/10.59350/es0dc-vhb02 registered, got %v", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Webhook(signed): pipeline not run")
+	}
+	for range 50 {
+		resp, err = http.Get(srv.URL + location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewDecoder(resp.Body).Decode(&job)
+		resp.Body.Close()
+		if job.Status != server.JobQueued && job.Status != server.JobRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if job.Status != server.JobFailed || len(job.Steps) != 2 || job.Steps[0].Message != "submitted" || job.Steps[1].Error != "notification failed" {
+		t.Errorf("Job(%v): want failed with 2 steps, got %v %v", job.ID, job.Status, job.Steps)
+	}
+
+	resp, err = http.Get(srv.URL + "/webhooks/jobs/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Job(unknown): want %v, got %v", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+// postWebhook posts a signed jsonfeed webhook call.
+func postWebhook(url string, body []byte) (*http.Response, error) {
+	req, _ := http.NewRequest(http.MethodPost, url+"/webhooks/jsonfeed", bytes.NewReader(body))
+	signature, timestamp := server.Sign("secret", body, time.Now())
+	req.Header.Set("X-Signature-256", signature)
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	return http.DefaultClient.Do(req)
+}
+
+func TestWebhookJobs(t *testing.T) {
+	item, err := os.ReadFile(filepath.Join("..", "jsonfeed", "testdata", "3d02cf64-c600-4eb1-91b4-02f5bade5691.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(item)
+	}))
+	defer api.Close()
+	config.SetEndpoints(config.Endpoints{RogueScholarAPI: api.URL})
+	defer config.ResetEndpoints()
+
+	// the pipeline waits until a job is released
+	running := make(chan bool)
+	release := make(chan bool)
+	srv := httptest.NewServer(server.New(server.Options{
+		Webhooks:      true,
+		WebhookSecret: "secret",
+		Workers:       1,
+		JobRetention:  time.Nanosecond,
+		Pipeline: []server.Step{
+			{Name: "register", Run: func(data commonmeta.Data) (string, error) {
+				running <- true
+				<-release
+				return "submitted", nil
+			}},
+		},
+	}))
+	defer srv.Close()
+
+	post := func(body string) (int, server.Job) {
+		resp, err := postWebhook(srv.URL, []byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var job server.Job
+		json.NewDecoder(resp.Body).Decode(&job)
+		return resp.StatusCode, job
+	}
+	get := func(id string) (int, server.Job) {
+		resp, err := http.Get(srv.URL + "/webhooks/jobs/" + id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var job server.Job
+		json.NewDecoder(resp.Body).Decode(&job)
+		return resp.StatusCode, job
+	}
+
+	_, first := post(`{"id":"https://doi.org/10.59350/es0dc-vhb02"}`)
+	<-running
+	// a call for a queued item returns the queued job
+	code, second := post(`{"id":"https://doi.org/10.59350/es0dc-vhb02"}`)
+	if code != http.StatusAccepted || second.ID == first.ID {
+		t.Errorf("Webhook(running item): want %v with a new job, got %v %v", http.StatusAccepted, code, second.ID)
+	}
+	code, third := post(`{"id":"https://doi.org/10.59350/es0dc-vhb02"}`)
+	if code != http.StatusOK || third.ID != second.ID {
+		t.Errorf("Webhook(queued item): want %v with job %v, got %v %v", http.StatusOK, second.ID, code, third.ID)
+	}
+	release <- true
+	<-running
+	release <- true
+
+	// finished jobs are removed after the job retention
+	for range 50 {
+		if _, job := get(second.ID); job.Status == server.JobSucceeded {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, other := post(`{"id":"https://doi.org/10.59350/other"}`)
+	<-running
+	release <- true
+	if code, _ := get(first.ID); code != http.StatusNotFound {
+		t.Errorf("Job(%v): want %v after the job retention, got %v", first.ID, http.StatusNotFound, code)
+	}
+	if code, _ := get(other.ID); code != http.StatusOK {
+		t.Errorf("Job(%v): want %v, got %v", other.ID, http.StatusOK, code)
+	}
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Change is a field that differs between two JSON documents. Path is the
+// field path, e.g. metadata.creators[0].name. Old is missing for added
+// fields, New for removed fields.
+type Change struct {
+	Path string `json:"path"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// DiffJSON returns the field-level changes from old to new, ordered by path.
+// An empty old document is treated as missing, so that all fields of new
+// are reported as added.
+func DiffJSON(old []byte, new []byte) ([]Change, error) {
+	var o, n any
+	if len(old) > 0 {
+		if err := json.Unmarshal(old, &o); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(new, &n); err != nil {
+		return nil, err
+	}
+	var changes []Change
+	diffValues("", o, n, &changes)
+	return changes, nil
+}
+
+// diffValues appends the changes between two decoded JSON values.
+func diffValues(path string, old any, new any, changes *[]Change) {
+	switch n := new.(type) {
+	case map[string]any:
+		o, ok := old.(map[string]any)
+		if !ok && old != nil {
+			break
+		}
+		keys := make([]string, 0, len(o)+len(n))
+		for k := range o {
+			keys = append(keys, k)
+		}
+		for k := range n {
+			if _, ok := o[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			diffValues(p, o[k], n[k], changes)
+		}
+		return
+	case []any:
+		o, ok := old.([]any)
+		if !ok && old != nil {
+			break
+		}
+		for i := range max(len(o), len(n)) {
+			var ov, nv any
+			if i < len(o) {
+				ov = o[i]
+			}
+			if i < len(n) {
+				nv = n[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), ov, nv, changes)
+		}
+		return
+	}
+	if _, ok := old.(map[string]any); ok && new == nil {
+		// removed object, report its fields
+		diffValues(path, old, map[string]any{}, changes)
+		return
+	}
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Path: path, Old: old, New: new})
+	}
+}
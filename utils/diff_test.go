@@ -0,0 +1,34 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/front-matter/commonmeta/utils"
+)
+
+func TestDiffJSON(t *testing.T) {
+	t.Parallel()
+	type testCase struct {
+		old  string
+		new  string
+		want []utils.Change
+	}
+	testCases := []testCase{
+		{old: `{"title":"A"}`, new: `{"title":"A"}`, want: nil},
+		{old: `{"title":"A"}`, new: `{"title":"B"}`, want: []utils.Change{{Path: "title", Old: "A", New: "B"}}},
+		{old: ``, new: `{"title":"A","version":1}`, want: []utils.Change{{Path: "title", New: "A"}, {Path: "version", New: float64(1)}}},
+		{old: `{"metadata":{"creators":[{"name":"X"},{"name":"Y"}]}}`, new: `{"metadata":{"creators":[{"name":"X"}]}}`, want: []utils.Change{{Path: "metadata.creators[1].name", Old: "Y"}}},
+		{old: `{"subjects":["a"]}`, new: `{"subjects":["a","b"]}`, want: []utils.Change{{Path: "subjects[1]", New: "b"}}},
+		{old: `{"language":"en"}`, new: `{}`, want: []utils.Change{{Path: "language", Old: "en"}}},
+	}
+	for _, tc := range testCases {
+		got, err := utils.DiffJSON([]byte(tc.old), []byte(tc.new))
+		if err != nil {
+			t.Errorf("DiffJSON(%v, %v): %v", tc.old, tc.new, err)
+		}
+		if !reflect.DeepEqual(tc.want, got) {
+			t.Errorf("DiffJSON(%v, %v): want %v, got %v", tc.old, tc.new, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package utils
+
+import "sync"
+
+// ParallelMap calls fn for each item with at most workers goroutines and
+// returns the results in the order of the items. With less than two
+// workers the items are processed sequentially.
+func ParallelMap[T any, R any](items []T, workers int, fn func(T) R) []R {
+	results := make([]R, len(items))
+	if workers < 2 {
+		for i, item := range items {
+			results[i] = fn(item)
+		}
+		return results
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = fn(items[i])
+			}
+		}()
+	}
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
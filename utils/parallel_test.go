@@ -0,0 +1,64 @@
+package utils_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/front-matter/commonmeta/utils"
+)
+
+func TestParallelMap(t *testing.T) {
+	t.Parallel()
+	type testCase struct {
+		items   int
+		workers int
+	}
+	testCases := []testCase{
+		{items: 0, workers: 4},
+		{items: 10, workers: 0},
+		{items: 10, workers: 1},
+		{items: 10, workers: 4},
+		{items: 3, workers: 10},
+	}
+	for _, tc := range testCases {
+		items := make([]int, tc.items)
+		for i := range items {
+			items[i] = i
+		}
+		var running, maxRunning int32
+		got := utils.ParallelMap(items, tc.workers, func(i int) int {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return i * i
+		})
+		for i, v := range got {
+			if v != i*i {
+				t.Errorf("ParallelMap(%v, %v): want %v at %v, got %v", tc.items, tc.workers, i*i, i, v)
+			}
+		}
+		if len(got) != tc.items {
+			t.Errorf("ParallelMap(%v, %v): want %v results, got %v", tc.items, tc.workers, tc.items, len(got))
+		}
+		if limit := int32(max(tc.workers, 1)); maxRunning > limit {
+			t.Errorf("ParallelMap(%v, %v): want at most %v workers, got %v", tc.items, tc.workers, limit, maxRunning)
+		}
+	}
+}
+
+func ExampleParallelMap() {
+	s := utils.ParallelMap([]string{"a", "b", "c"}, 2, func(s string) string {
+		return s + s
+	})
+	fmt.Println(s)
+	// Output:
+	// [aa bb cc]
+}
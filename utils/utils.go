@@ -5,18 +5,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"regexp"
 	"slices"
 	"strconv"
 	"strings"
-	"time"
 	"unicode"
 
 	iso639_3 "github.com/barbashov/iso639-3"
+	"github.com/front-matter/commonmeta/config"
 	"github.com/front-matter/commonmeta/crockford"
 	"github.com/front-matter/commonmeta/doiutils"
+	"github.com/front-matter/commonmeta/httputils"
 	"github.com/front-matter/commonmeta/spdx"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/pkosilo/iso7064"
@@ -589,18 +589,17 @@ func ValidatePMCID(pmcid string) (string, bool) {
 // GetROR
 func GetROR(ror string) (ROR, error) {
 	var content ROR
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
-	url := "https://api.ror.org/organizations/" + ror
+	client := httputils.Client()
+	url := config.GetEndpoints().RORAPI + "/organizations/" + ror
 	resp, err := client.Get(url)
 	if err != nil {
 		return content, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return content, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
-	}
 	defer resp.Body.Close()
+	err = httputils.CheckResponse(resp)
+	if err != nil {
+		return content, err
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return content, err